@@ -70,6 +70,10 @@ func MustUnmarshalBinaryLengthPrefixed(bz []byte, ptr interface{}) {
 	gcdc.MustUnmarshalBinaryLengthPrefixed(bz, ptr)
 }
 
+func UnmarshalBinaryLengthPrefixedWithLimits(bz []byte, ptr interface{}, limits DecodeLimits) error {
+	return gcdc.UnmarshalBinaryLengthPrefixedWithLimits(bz, ptr, limits)
+}
+
 func UnmarshalBinaryBare(bz []byte, ptr interface{}) error {
 	return gcdc.UnmarshalBinaryBare(bz, ptr)
 }
@@ -78,6 +82,18 @@ func MustUnmarshalBinaryBare(bz []byte, ptr interface{}) {
 	gcdc.MustUnmarshalBinaryBare(bz, ptr)
 }
 
+func UnmarshalBinaryBareWithLimits(bz []byte, ptr interface{}, limits DecodeLimits) error {
+	return gcdc.UnmarshalBinaryBareWithLimits(bz, ptr, limits)
+}
+
+func UnmarshalBinaryBareStrict(bz []byte, ptr interface{}) error {
+	return gcdc.UnmarshalBinaryBareStrict(bz, ptr)
+}
+
+func UnmarshalBinaryLengthPrefixedStrict(bz []byte, ptr interface{}) error {
+	return gcdc.UnmarshalBinaryLengthPrefixedStrict(bz, ptr)
+}
+
 func MarshalJSON(o interface{}) ([]byte, error) {
 	return gcdc.MarshalJSON(o)
 }
@@ -86,6 +102,10 @@ func UnmarshalJSON(bz []byte, ptr interface{}) error {
 	return gcdc.UnmarshalJSON(bz, ptr)
 }
 
+func UnmarshalJSONWithLimits(bz []byte, ptr interface{}, limits DecodeLimits) error {
+	return gcdc.UnmarshalJSONWithLimits(bz, ptr, limits)
+}
+
 func MarshalJSONIndent(o interface{}, prefix, indent string) ([]byte, error) {
 	return gcdc.MarshalJSONIndent(o, prefix, indent)
 }
@@ -107,6 +127,9 @@ const (
 	//Typ3_Interface  = Typ3(7)
 )
 
+// Field numbers are in [1, maxFieldNum], as in Proto3.
+const maxFieldNum = 1<<29 - 1
+
 func (typ Typ3) String() string {
 	switch typ {
 	case Typ3Varint:
@@ -130,6 +153,170 @@ func (typ Typ3) String() string {
 	}
 }
 
+//----------------------------------------
+// UnknownFields
+
+// UnknownFields holds the encoded fields of a struct that are not known to
+// the struct, e.g. fields added by a newer version of the struct.  To preserve
+// them when decoding and re-encoding a struct, add a field of type
+// UnknownFields to it:
+//
+//	type MyStruct struct {
+//		A       string
+//		Unknown amino.UnknownFields
+//	}
+//
+// Unknown fields are then kept in field number order on decoding, and written
+// back in between the known fields on encoding.  The UnknownFields field
+// itself has no field number, and is not encoded in JSON.
+type UnknownFields []byte
+
+//----------------------------------------
+// UnknownConcrete
+
+// UnknownConcrete holds a value of a concrete type that is not registered,
+// e.g. one added by a newer version of a program, so that it can be decoded
+// and encoded again as is.  Interface values of unknown concrete types decode
+// to an UnknownConcrete if the interface was registered with
+// AllowUnknownConcrete and UnknownConcrete implements it (e.g. interface{}).
+// Otherwise, decoding fails with an error of kind ErrKindUnknownPrefix.
+//
+// An UnknownConcrete decoded from binary can only be encoded to binary, and
+// one decoded from JSON only to JSON.
+type UnknownConcrete struct {
+	// Binary
+	Disamb    DisambBytes // Disambiguation bytes, if HasDisamb.
+	HasDisamb bool
+	Prefix    PrefixBytes
+	Value     []byte // Encoding of the value, after the prefix bytes.
+
+	// JSON
+	Name      string // Type name.
+	JSONValue []byte // Encoding of the value.
+}
+
+// Returns the binary encoding of uc, including the prefix bytes.
+func (uc UnknownConcrete) binaryBytes() ([]byte, error) {
+	if uc.Prefix[0] == 0x00 {
+		return nil, errors.Errorf("cannot encode amino.UnknownConcrete %v to binary", uc.Name)
+	}
+	var bz = make([]byte, 0, 1+DisfixBytesLen+len(uc.Value))
+	if uc.HasDisamb {
+		bz = append(append(bz, 0x00), uc.Disamb[:]...)
+	}
+	bz = append(bz, uc.Prefix[:]...)
+	return append(bz, uc.Value...), nil
+}
+
+// Returns the length of the binary encoding of uc, including the prefix bytes.
+func (uc UnknownConcrete) binarySize() (int, error) {
+	if uc.Prefix[0] == 0x00 {
+		return 0, errors.Errorf("cannot encode amino.UnknownConcrete %v to binary", uc.Name)
+	}
+	var n = PrefixBytesLen + len(uc.Value)
+	if uc.HasDisamb {
+		n += 1 + DisambBytesLen
+	}
+	return n, nil
+}
+
+//----------------------------------------
+// Raw
+
+// Raw holds the binary encoding of a struct or interface field, to defer
+// decoding it, e.g. to only decode the header of a message and pass on its
+// payload:
+//
+//	type Envelope struct {
+//		Header  Header
+//		Payload amino.Raw // Payload Msg
+//	}
+//
+// In binary, a Raw field is encoded as a []byte field holding the encoding
+// of the value, which is the same as the encoding of a field of the value's
+// type, so the bytes are kept as is on decoding, and written back as is on
+// encoding.  A Raw decoded from an empty field is empty but not nil, so that
+// the field is written back, while a nil Raw is omitted like an absent field.
+// This doesn't work for fields that are encoded as repeated fields instead,
+// i.e. lists and maps.  In JSON, Raw is encoded as a []byte, see RawJSON
+// instead.
+type Raw []byte
+
+// Decode decodes raw into ptr, as if it were the field that raw was decoded
+// from, so an empty raw decodes to the default value.  Like
+// UnmarshalBinaryBare, it fails if not all of raw is consumed.
+func (raw Raw) Decode(cdc *Codec, ptr interface{}) error {
+	rv := reflect.ValueOf(ptr)
+	if rv.Kind() != reflect.Ptr {
+		return ErrNoPointer
+	}
+	rv = rv.Elem()
+	rt := rv.Type()
+	if len(raw) == 0 {
+		rv.Set(defaultValue(rt))
+		return nil
+	}
+	info, err := cdc.getTypeInfoWlock(rt)
+	if err != nil {
+		return err
+	}
+	var ds = newDecodeState(cdc.getDecodeLimits())
+	ds.root, ds.input = rt, raw
+	n, err := cdc.decodeReflectBinary(raw, info, rv, FieldOptions{}, true, ds)
+	if err != nil {
+		return err
+	}
+	if n != len(raw) {
+		err = fmt.Errorf("unmarshal to %v didn't read all bytes. Expected to read %v, only read %v",
+			info.Type, len(raw), n)
+		return ds.binaryError(err, rt, n)
+	}
+	return nil
+}
+
+// RawJSON holds the JSON encoding of a field, to defer decoding it, like Raw
+// does for binary.  It is kept and written back as is, and encoded as null if
+// empty.  In binary, RawJSON is encoded as a []byte.
+type RawJSON []byte
+
+// MarshalJSON returns raw, or null if raw is empty.
+func (raw RawJSON) MarshalJSON() ([]byte, error) {
+	if len(raw) == 0 {
+		return []byte("null"), nil
+	}
+	if !json.Valid(raw) {
+		return nil, errors.Errorf("invalid amino.RawJSON %s", []byte(raw))
+	}
+	return raw, nil
+}
+
+// UnmarshalJSON sets *raw to a copy of bz.
+func (raw *RawJSON) UnmarshalJSON(bz []byte) error {
+	*raw = append((*raw)[:0], bz...)
+	return nil
+}
+
+// Decode decodes raw into ptr, as if it were the field that raw was decoded
+// from.  An empty raw decodes like null.
+func (raw RawJSON) Decode(cdc *Codec, ptr interface{}) error {
+	if len(raw) == 0 {
+		raw = RawJSON("null")
+	}
+	rv := reflect.ValueOf(ptr)
+	if rv.Kind() != reflect.Ptr {
+		return ErrNoPointer
+	}
+	rv = rv.Elem()
+	rt := rv.Type()
+	info, err := cdc.getTypeInfoWlock(rt)
+	if err != nil {
+		return err
+	}
+	var ds = newDecodeState(cdc.getDecodeLimits())
+	ds.root = rt
+	return cdc.decodeReflectJSON(raw, info, rv, FieldOptions{}, ds)
+}
+
 //----------------------------------------
 // *Codec methods
 
@@ -141,41 +328,68 @@ func (typ Typ3) String() string {
 // before encoding.  MarshalBinaryLengthPrefixed will panic if o is a nil-pointer,
 // or if o is invalid.
 func (cdc *Codec) MarshalBinaryLengthPrefixed(o interface{}) ([]byte, error) {
+	return cdc.AppendBinaryLengthPrefixed(nil, o)
+}
 
-	// Write the bytes here.
-	var buf = new(bytes.Buffer)
-
-	// Write the bz without length-prefixing.
-	bz, err := cdc.MarshalBinaryBare(o)
-	if err != nil {
-		return nil, err
-	}
-
-	// Write uvarint(len(bz)).
-	err = EncodeUvarint(buf, uint64(len(bz)))
-	if err != nil {
-		return nil, err
-	}
-
-	// Write bz.
-	_, err = buf.Write(bz)
+// AppendBinaryLengthPrefixed appends the bytes as would be returned from
+// MarshalBinaryLengthPrefixed to dst, and returns the extended slice.  See
+// AppendBinaryBare.
+func (cdc *Codec) AppendBinaryLengthPrefixed(dst []byte, o interface{}) ([]byte, error) {
+	buf := getBuffer()
+	_, err := cdc.encodeBinaryBare(buf, o)
 	if err != nil {
-		return nil, err
+		putBuffer(buf)
+		return dst, err
 	}
 
-	return buf.Bytes(), nil
+	// Append uvarint(len(bz)), then bz.
+	var lbuf [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(lbuf[:], uint64(buf.Len()))
+	dst = append(dst, lbuf[:n]...)
+	dst = append(dst, buf.Bytes()...)
+	putBuffer(buf)
+	return dst, nil
 }
 
 // MarshalBinaryLengthPrefixedWriter writes the bytes as would be returned from
-// MarshalBinaryLengthPrefixed to the writer w.
+// MarshalBinaryLengthPrefixed to the writer w, and returns the number of bytes
+// written.  The length is computed first (see SizeBinaryBare), so that the
+// encoding is written straight to w, in many small writes.  Use e.g. a
+// bufio.Writer for sockets and files.
 func (cdc *Codec) MarshalBinaryLengthPrefixedWriter(w io.Writer, o interface{}) (n int64, err error) {
-	var bz, _n = []byte(nil), int(0)
-	bz, err = cdc.MarshalBinaryLengthPrefixed(o)
+	var rv, _, isNilPtr = derefPointers(reflect.ValueOf(o))
+	if isNilPtr {
+		panic("MarshalBinaryLengthPrefixedWriter cannot marshal a nil pointer directly. Try wrapping in a struct?")
+	}
+	var info *TypeInfo
+	info, err = cdc.getTypeInfoWlock(rv.Type())
+	if err != nil {
+		return 0, err
+	}
+	var size int
+	var es = newEncodeState()
+	size, err = cdc.sizeBinaryBare(info, rv, es)
 	if err != nil {
 		return 0, err
 	}
-	_n, err = w.Write(bz) // TODO: handle overflow in 32-bit systems.
-	n = int64(_n)
+	var cw = &countingWriter{w: w}
+	err = EncodeUvarint(cw, uint64(size))
+	if err != nil {
+		return cw.n, err
+	}
+	err = cdc.writeBinaryBare(cw, info, rv, es)
+	return cw.n, err
+}
+
+// Counts the bytes written to w.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (cw *countingWriter) Write(p []byte) (n int, err error) {
+	n, err = cw.w.Write(p)
+	cw.n += int64(n)
 	return
 }
 
@@ -192,53 +406,138 @@ func (cdc *Codec) MustMarshalBinaryLengthPrefixed(o interface{}) []byte {
 // MarshalBinaryBare doesn't prefix the byte-length of the encoding,
 // so the caller must handle framing.
 func (cdc *Codec) MarshalBinaryBare(o interface{}) ([]byte, error) {
+	buf := getBuffer()
+	info, err := cdc.encodeBinaryBare(buf, o)
+	if err != nil {
+		putBuffer(buf)
+		return nil, err
+	}
+	// The empty encoding of non-struct values (written as field 1, see
+	// encodeBinaryBare) is []byte{} like for proto.Marshal, and nil for
+	// structs.
+	var bz []byte
+	if buf.Len() > 0 || !isStructOrRepeatedStruct(info) {
+		bz = append([]byte{}, buf.Bytes()...)
+	}
+	putBuffer(buf)
+	return bz, nil
+}
+
+// AppendBinaryBare appends the bytes as would be returned from
+// MarshalBinaryBare to dst, and returns the extended slice.  Like append, it
+// only allocates if dst is too small, so reusing dst (e.g. dst[:0]) for many
+// values saves allocations.  On error, dst is returned unchanged.
+func (cdc *Codec) AppendBinaryBare(dst []byte, o interface{}) ([]byte, error) {
+	buf := getBuffer()
+	_, err := cdc.encodeBinaryBare(buf, o)
+	if err != nil {
+		putBuffer(buf)
+		return dst, err
+	}
+	dst = append(dst, buf.Bytes()...)
+	putBuffer(buf)
+	return dst, nil
+}
+
+// SizeBinaryBare returns the length of the bytes as would be returned from
+// MarshalBinaryBare, without encoding o.
+func (cdc *Codec) SizeBinaryBare(o interface{}) (int, error) {
 
 	// Dereference value if pointer.
 	var rv, _, isNilPtr = derefPointers(reflect.ValueOf(o))
 	if isNilPtr {
-		// NOTE: You can still do so by calling
-		// `.MarshalBinaryLengthPrefixed(struct{ *SomeType })` or so on.
-		panic("MarshalBinaryBare cannot marshal a nil pointer directly. Try wrapping in a struct?")
+		panic("SizeBinaryBare cannot size a nil pointer directly. Try wrapping in a struct?")
 	}
 
-	// Encode Amino:binary bytes.
-	var bz []byte
-	buf := new(bytes.Buffer)
 	rt := rv.Type()
 	info, err := cdc.getTypeInfoWlock(rt)
 	if err != nil {
-		return nil, err
+		return 0, err
 	}
-	// in the case of of a repeated struct (e.g. type Alias []SomeStruct),
-	// we do not need to prepend with `(field_number << 3) | wire_type` as this
-	// would need to be done for each struct and not only for the first.
+	return cdc.sizeBinaryBare(info, rv, nil)
+}
+
+// Returns the length of what writeBinaryBare writes, and records what
+// writeBinaryBare needs in es, unless es is nil.
+func (cdc *Codec) sizeBinaryBare(info *TypeInfo, rv reflect.Value, es *encodeState) (n int, err error) {
+	if info.isTopLevelRegistered() {
+		n += PrefixBytesLen
+	}
+	var _n int
 	if rv.Kind() != reflect.Struct && !isStructOrRepeatedStruct(info) {
-		writeEmpty := false
 		typ3 := typeToTyp3(info.Type, FieldOptions{})
 		bare := typ3 != Typ3ByteLength
-		if err := cdc.writeFieldIfNotEmpty(buf, 1, info, FieldOptions{}, FieldOptions{}, rv, writeEmpty, bare); err != nil {
-			return nil, err
-		}
-		bz = buf.Bytes()
+		_n, err = cdc.sizeFieldIfNotEmpty(fieldKeySize(1, typ3), info, FieldOptions{}, rv, false, bare, es)
 	} else {
-		err = cdc.encodeReflectBinary(buf, info, rv, FieldOptions{BinFieldNum: 1}, true)
-		if err != nil {
-			return nil, err
-		}
-		bz = buf.Bytes()
+		_n, err = cdc.sizeReflectBinary(info, rv, FieldOptions{BinFieldNum: 1}, true, es)
 	}
-	// If registered concrete, prepend prefix bytes.
-	if info.Registered {
+	if err != nil {
+		return 0, err
+	}
+	return n + _n, nil
+}
+
+// SizeBinaryLengthPrefixed returns the length of the bytes as would be
+// returned from MarshalBinaryLengthPrefixed, without encoding o.
+func (cdc *Codec) SizeBinaryLengthPrefixed(o interface{}) (int, error) {
+	n, err := cdc.SizeBinaryBare(o)
+	if err != nil {
+		return 0, err
+	}
+	return UvarintSize(uint64(n)) + n, nil
+}
+
+// Writes the bytes as would be returned from MarshalBinaryBare to w, and
+// returns the type info of o.
+func (cdc *Codec) encodeBinaryBare(w io.Writer, o interface{}) (info *TypeInfo, err error) {
+
+	// Dereference value if pointer.
+	var rv, _, isNilPtr = derefPointers(reflect.ValueOf(o))
+	if isNilPtr {
+		// NOTE: You can still do so by calling
+		// `.MarshalBinaryLengthPrefixed(struct{ *SomeType })` or so on.
+		panic("MarshalBinaryBare cannot marshal a nil pointer directly. Try wrapping in a struct?")
+	}
+
+	// Encode Amino:binary bytes, sizing the value first.
+	rt := rv.Type()
+	info, err = cdc.getTypeInfoWlock(rt)
+	if err != nil {
+		return
+	}
+	var es = newEncodeState()
+	_, err = cdc.sizeBinaryBare(info, rv, es)
+	if err != nil {
+		return
+	}
+	err = cdc.writeBinaryBare(w, info, rv, es)
+	return
+}
+
+// CONTRACT: rv was sized with es, see sizeBinaryBare.
+func (cdc *Codec) writeBinaryBare(w io.Writer, info *TypeInfo, rv reflect.Value, es *encodeState) (err error) {
+	// If registered concrete, write prefix bytes first.
+	if info.isTopLevelRegistered() {
 		// TODO: https://github.com/tendermint/go-amino/issues/267
 		//return MarshalBinaryBare(RegisteredAny{
 		//	AminoPreOrDisfix: info.Prefix.Bytes(),
 		//	Value: bz,
 		//})
-		pb := info.Prefix.Bytes()
-		bz = append(pb, bz...)
+		if _, err = w.Write(info.Prefix.Bytes()); err != nil {
+			return
+		}
 	}
-
-	return bz, nil
+	// in the case of of a repeated struct (e.g. type Alias []SomeStruct),
+	// we do not need to prepend with `(field_number << 3) | wire_type` as this
+	// would need to be done for each struct and not only for the first.
+	if rv.Kind() != reflect.Struct && !isStructOrRepeatedStruct(info) {
+		typ3 := typeToTyp3(info.Type, FieldOptions{})
+		bare := typ3 != Typ3ByteLength
+		err = cdc.writeFieldIfNotEmpty(w, fieldKey(1, typ3), FieldOptions{}, bare, es)
+		return
+	}
+	err = cdc.encodeReflectBinary(w, info, rv, FieldOptions{BinFieldNum: 1}, true, es)
+	return
 }
 
 //type RegisteredAny struct {
@@ -259,31 +558,50 @@ func (cdc *Codec) MustMarshalBinaryBare(o interface{}) []byte {
 // UnmarshalBinaryLengthPrefixed will panic if ptr is a nil-pointer.
 // Returns an error if not all of bz is consumed.
 func (cdc *Codec) UnmarshalBinaryLengthPrefixed(bz []byte, ptr interface{}) error {
+	return cdc.UnmarshalBinaryLengthPrefixedWithLimits(bz, ptr, cdc.getDecodeLimits())
+}
+
+// Like UnmarshalBinaryLengthPrefixed, but with the given limits instead of
+// those of the codec.
+func (cdc *Codec) UnmarshalBinaryLengthPrefixedWithLimits(bz []byte, ptr interface{}, limits DecodeLimits) error {
+	n, err := checkLengthPrefix(bz)
+	if err != nil {
+		return err
+	}
+	var ds = newDecodeState(limits)
+	ds.input = bz
+	bz = bz[n:]
+
+	// Decode.
+	return cdc.unmarshalBinaryBare(bz, ptr, ds)
+}
+
+// Checks that the byte-length prefix of bz matches the rest of bz, and
+// returns the length of the prefix.
+func checkLengthPrefix(bz []byte) (n int, err error) {
 	if len(bz) == 0 {
-		return errors.New("UnmarshalBinaryLengthPrefixed cannot decode empty bytes")
+		return 0, errors.New("UnmarshalBinaryLengthPrefixed cannot decode empty bytes")
 	}
 
 	// Read byte-length prefix.
 	u64, n := binary.Uvarint(bz)
 	if n < 0 {
-		return errors.Errorf("Error reading msg byte-length prefix: got code %v", n)
+		return 0, errors.Errorf("Error reading msg byte-length prefix: got code %v", n)
 	}
 	if u64 > uint64(len(bz)-n) {
-		return errors.Errorf("Not enough bytes to read in UnmarshalBinaryLengthPrefixed, want %v more bytes but only have %v",
+		return 0, errors.Errorf("Not enough bytes to read in UnmarshalBinaryLengthPrefixed, want %v more bytes but only have %v",
 			u64, len(bz)-n)
 	} else if u64 < uint64(len(bz)-n) {
-		return errors.Errorf("Bytes left over in UnmarshalBinaryLengthPrefixed, should read %v more bytes but have %v",
+		return 0, errors.Errorf("Bytes left over in UnmarshalBinaryLengthPrefixed, should read %v more bytes but have %v",
 			u64, len(bz)-n)
 	}
-	bz = bz[n:]
-
-	// Decode.
-	return cdc.UnmarshalBinaryBare(bz, ptr)
+	return n, nil
 }
 
 // Like UnmarshalBinaryBare, but will first read the byte-length prefix.
 // UnmarshalBinaryLengthPrefixedReader will panic if ptr is a nil-pointer.
 // If maxSize is 0, there is no limit (not recommended).
+// To read a stream of many values, use a Decoder instead.
 func (cdc *Codec) UnmarshalBinaryLengthPrefixedReader(r io.Reader, ptr interface{}, maxSize int64) (n int64, err error) {
 	if maxSize < 0 {
 		panic("maxSize cannot be negative.")
@@ -325,15 +643,20 @@ func (cdc *Codec) UnmarshalBinaryLengthPrefixedReader(r io.Reader, ptr interface
 	}
 
 	// Read that many bytes.
+	var ds = newDecodeState(cdc.getDecodeLimits())
+	if err = ds.allocate(l); err != nil {
+		return
+	}
 	var bz = make([]byte, l, l)
 	_, err = io.ReadFull(r, bz)
 	if err != nil {
 		return
 	}
+	ds.input, ds.base = bz, int(n)
 	n += l
 
 	// Decode.
-	err = cdc.UnmarshalBinaryBare(bz, ptr)
+	err = cdc.unmarshalBinaryBare(bz, ptr, ds)
 	return
 }
 
@@ -346,7 +669,18 @@ func (cdc *Codec) MustUnmarshalBinaryLengthPrefixed(bz []byte, ptr interface{})
 }
 
 // UnmarshalBinaryBare will panic if ptr is a nil-pointer.
+// Malformed input results in a *DecodeError.
 func (cdc *Codec) UnmarshalBinaryBare(bz []byte, ptr interface{}) error {
+	return cdc.UnmarshalBinaryBareWithLimits(bz, ptr, cdc.getDecodeLimits())
+}
+
+// Like UnmarshalBinaryBare, but with the given limits instead of those of the
+// codec.
+func (cdc *Codec) UnmarshalBinaryBareWithLimits(bz []byte, ptr interface{}, limits DecodeLimits) error {
+	return cdc.unmarshalBinaryBare(bz, ptr, newDecodeState(limits))
+}
+
+func (cdc *Codec) unmarshalBinaryBare(bz []byte, ptr interface{}, ds *decodeState) error {
 
 	rv := reflect.ValueOf(ptr)
 	if rv.Kind() != reflect.Ptr {
@@ -358,76 +692,190 @@ func (cdc *Codec) UnmarshalBinaryBare(bz []byte, ptr interface{}) error {
 	if err != nil {
 		return err
 	}
+	ds.root = rt
+	if ds.input == nil {
+		ds.input = bz
+	}
 
 	// If registered concrete, consume and verify prefix bytes.
-	if info.Registered {
+	if info.isTopLevelRegistered() {
 		// TODO: https://github.com/tendermint/go-amino/issues/267
-		pb := info.Prefix.Bytes()
-		if len(bz) < 4 {
-			return fmt.Errorf("unmarshalBinaryBare expected to read prefix bytes %X (since it is registered concrete) but got %X", pb, bz)
-		} else if !bytes.Equal(bz[:4], pb) {
-			return fmt.Errorf("unmarshalBinaryBare expected to read prefix bytes %X (since it is registered concrete) but got %X", pb, bz[:4])
+		var n int
+		if n, err = ds.consumePrefix(bz, info); err != nil {
+			return err
 		}
-		bz = bz[4:]
+		bz = bz[n:]
 	}
 	// Only add length prefix if we have another typ3 then Typ3ByteLength.
 	// Default is non-length prefixed:
 	bare := true
-	var nWrap int
-	isKnownType := (info.Type.Kind() != reflect.Map) && (info.Type.Kind() != reflect.Func)
+	isKnownType := info.Type.Kind() != reflect.Func
+	var key []byte // Of field 1, if any.
 	if !isStructOrRepeatedStruct(info) &&
 		!isPointerToStructOrToRepeatedStruct(rv, rt) &&
 		len(bz) > 0 &&
 		(rv.Kind() != reflect.Interface) &&
 		isKnownType {
+		if err = ds.checkUvarint(bz); err != nil {
+			return err
+		}
+		key = bz
 		fnum, typ, nFnumTyp3, err := decodeFieldNumberAndTyp3(bz)
 		if err != nil {
-			return errors.Wrap(err, "could not decode field number and type")
+			err = errors.Wrap(err, "could not decode field number and type")
+			return ds.binaryError(err, rt, ds.offset(bz))
 		}
 		if fnum != 1 {
-			return fmt.Errorf("expected field number: 1; got: %v", fnum)
+			err = kindErrorf(ErrKindFieldOrder, "expected field number: 1; got: %v", fnum)
+			return ds.binaryError(err, rt, ds.offset(bz))
 		}
 		typWanted := typeToTyp3(info.Type, FieldOptions{})
 		if typ != typWanted {
-			return fmt.Errorf("expected field type %v for # %v of %v, got %v",
+			err = kindErrorf(ErrKindWrongTyp3, "expected field type %v for # %v of %v, got %v",
 				typWanted, fnum, info.Type, typ)
+			return ds.binaryError(err, rt, ds.offset(bz))
 		}
 
-		slide(&bz, &nWrap, nFnumTyp3)
+		slide(&bz, nil, nFnumTyp3)
 		bare = typeToTyp3(info.Type, FieldOptions{}) != Typ3ByteLength
 	}
 
 	// Decode contents into rv.
-	n, err := cdc.decodeReflectBinary(bz, info, rv, FieldOptions{BinFieldNum: 1}, bare)
+	n, err := cdc.decodeReflectBinary(bz, info, rv, FieldOptions{BinFieldNum: 1}, bare, ds)
 	if err != nil {
-		return fmt.Errorf(
-			"unmarshal to %v failed after %d bytes (%v): %X",
-			info.Type,
-			n+nWrap,
-			err,
-			bz,
-		)
+		return err
+	}
+	if key != nil && n == 1 && bz[0] == 0x00 {
+		// An empty value is not written, see encodeBinaryBare.
+		if err = ds.nonCanonical(key); err != nil {
+			return err
+		}
 	}
 	if n != len(bz) {
-		return fmt.Errorf(
-			"unmarshal to %v didn't read all bytes. Expected to read %v, only read %v: %X",
-			info.Type,
-			len(bz),
-			n+nWrap,
-			bz,
-		)
+		err = fmt.Errorf("unmarshal to %v didn't read all bytes. Expected to read %v, only read %v",
+			info.Type, len(bz), n)
+		return ds.binaryError(err, rt, ds.offset(bz)+n)
 	}
 
 	return nil
 }
 
+// UnmarshalBinaryBareAny decodes bz, as returned from MarshalBinaryBare for
+// a value of any registered concrete type, which is found by the prefix bytes
+// (or disambiguation and prefix bytes) that bz starts with.  The value is
+// returned as a pointer if the concrete type was registered as one.
+func (cdc *Codec) UnmarshalBinaryBareAny(bz []byte) (interface{}, error) {
+	return cdc.unmarshalBinaryBareAny(bz, newDecodeState(cdc.getDecodeLimits()))
+}
+
+// Like UnmarshalBinaryBareAny, but will first decode the byte-length prefix.
+func (cdc *Codec) UnmarshalBinaryLengthPrefixedAny(bz []byte) (interface{}, error) {
+	n, err := checkLengthPrefix(bz)
+	if err != nil {
+		return nil, err
+	}
+	var ds = newDecodeState(cdc.getDecodeLimits())
+	ds.input = bz
+	return cdc.unmarshalBinaryBareAny(bz[n:], ds)
+}
+
+func (cdc *Codec) unmarshalBinaryBareAny(bz []byte, ds *decodeState) (interface{}, error) {
+	if ds.input == nil {
+		ds.input = bz
+	}
+
+	// Get concrete type info from disfix/prefix.
+	disamb, hasDisamb, prefix, _, n, err := DecodeDisambPrefixBytes(bz)
+	if err != nil {
+		return nil, ds.binaryError(err, nil, ds.offset(bz))
+	}
+	var cinfo *TypeInfo
+	if hasDisamb {
+		cinfo, err = cdc.getTypeInfoFromDisfixRlock(toDisfix(disamb, prefix))
+	} else {
+		cinfo, err = cdc.getConcreteInfoFromPrefixRlock(prefix)
+	}
+	if err == nil && !cinfo.isTopLevelRegistered() {
+		err = kindErrorf(ErrKindUnknownPrefix, "%v (*%v) is not a top-level concrete type", cinfo.Name, cinfo.Type)
+	}
+	if err != nil {
+		return nil, ds.binaryError(err, nil, ds.offset(bz))
+	}
+
+	// Decode, starting with the prefix bytes.
+	var crv, irvSet = constructConcreteType(cinfo)
+	err = cdc.unmarshalBinaryBare(bz[n-PrefixBytesLen:], crv.Addr().Interface(), ds)
+	if err != nil {
+		return nil, err
+	}
+	return irvSet.Interface(), nil
+}
+
+// NonCanonicalErr is returned by the strict decoding functions when the input
+// is not the canonical (MarshalBinaryBare) encoding of the decoded value.
+type NonCanonicalErr struct {
+	Offset int // Offset in the input of the first non-canonical byte.
+}
+
+func (err NonCanonicalErr) Error() string {
+	return fmt.Sprintf("non-canonical amino encoding at byte offset %v", err.Offset)
+}
+
+// UnmarshalBinaryBareStrict is like UnmarshalBinaryBare, but only accepts the
+// canonical encoding, i.e. bz must equal what MarshalBinaryBare returns for the
+// decoded value.  Non-minimal varints, explicitly written default values,
+// out-of-order or unknown fields are all rejected with a NonCanonicalErr while
+// decoding, without encoding the value again.  This makes the encoding
+// non-malleable, as needed when hashing or signing.  ptr is only set if bz is
+// decoded successfully.
+//
+// Structs with an UnknownFields field keep the unknown fields, which are
+// accepted as they are written again.  Repr types (see MarshalAmino) are only
+// checked for the canonical encoding of the repr value.
+func (cdc *Codec) UnmarshalBinaryBareStrict(bz []byte, ptr interface{}) error {
+	var ds = newDecodeState(cdc.getDecodeLimits())
+	ds.strict = true
+	return cdc.unmarshalBinaryStrict(bz, ptr, ds)
+}
+
+// UnmarshalBinaryLengthPrefixedStrict is like UnmarshalBinaryLengthPrefixed,
+// but only accepts the canonical encoding, see UnmarshalBinaryBareStrict.  The
+// byte-length prefix must be a minimal uvarint.
+func (cdc *Codec) UnmarshalBinaryLengthPrefixedStrict(bz []byte, ptr interface{}) error {
+	n, err := checkLengthPrefix(bz)
+	if err != nil {
+		return err
+	}
+	var ds = newDecodeState(cdc.getDecodeLimits())
+	ds.input, ds.strict = bz, true
+	if err = ds.checkUvarint(bz); err != nil {
+		return err
+	}
+	return cdc.unmarshalBinaryStrict(bz[n:], ptr, ds)
+}
+
+// Decodes bz into a new value, which is only stored in ptr on success.
+func (cdc *Codec) unmarshalBinaryStrict(bz []byte, ptr interface{}, ds *decodeState) error {
+	rv := reflect.ValueOf(ptr)
+	if rv.Kind() != reflect.Ptr {
+		return ErrNoPointer
+	}
+	nrv := reflect.New(rv.Type().Elem())
+	err := cdc.unmarshalBinaryBare(bz, nrv.Interface(), ds)
+	if err != nil {
+		return err
+	}
+	rv.Elem().Set(nrv.Elem())
+	return nil
+}
+
 func isStructOrRepeatedStruct(info *TypeInfo) bool {
 	if info.Type.Kind() == reflect.Struct {
 		return true
 	}
-	isRepeatedStructAr := info.Type.Kind() == reflect.Array && info.Type.Elem().Kind() == reflect.Struct
-	isRepeatedStructSl := info.Type.Kind() == reflect.Slice && info.Type.Elem().Kind() == reflect.Struct
-	return isRepeatedStructAr || isRepeatedStructSl
+	// Lists of structs (or of other ByteLength elements) and maps, which are
+	// encoded as repeated key/value entry structs.
+	return isUnpackedList(info.Type, FieldOptions{})
 }
 
 func isPointerToStructOrToRepeatedStruct(rv reflect.Value, rt reflect.Type) bool {
@@ -445,12 +893,9 @@ func isPointerToStructOrToRepeatedStruct(rv reflect.Value, rt reflect.Type) bool
 		if rt.Kind() == reflect.Struct {
 			return true
 		}
-		return rt.Kind() == reflect.Slice && rt.Elem().Kind() == reflect.Struct ||
-			rt.Kind() == reflect.Array && rt.Elem().Kind() == reflect.Struct
+		return isUnpackedList(rt, FieldOptions{})
 	}
-	isRepeatedStructSl := isPtr && drv.Kind() == reflect.Slice && drv.Elem().Kind() == reflect.Struct
-	isRepeatedStructAr := isPtr && drv.Kind() == reflect.Array && drv.Elem().Kind() == reflect.Struct
-	return isRepeatedStructAr || isRepeatedStructSl
+	return isPtr && isUnpackedList(drv.Type(), FieldOptions{})
 }
 
 func derefType(rt reflect.Type) (drt reflect.Type) {
@@ -482,7 +927,7 @@ func (cdc *Codec) MarshalJSON(o interface{}) ([]byte, error) {
 	}
 
 	// Write the disfix wrapper if it is a registered concrete type.
-	if info.Registered {
+	if info.isTopLevelRegistered() {
 		err = writeStr(w, _fmt(`{"type":"%s","value":`, info.Name))
 		if err != nil {
 			return nil, err
@@ -495,7 +940,7 @@ func (cdc *Codec) MarshalJSON(o interface{}) ([]byte, error) {
 	}
 
 	// disfix wrapper continued...
-	if info.Registered {
+	if info.isTopLevelRegistered() {
 		err = writeStr(w, `}`)
 		if err != nil {
 			return nil, err
@@ -514,6 +959,11 @@ func (cdc *Codec) MustMarshalJSON(o interface{}) []byte {
 }
 
 func (cdc *Codec) UnmarshalJSON(bz []byte, ptr interface{}) error {
+	return cdc.UnmarshalJSONWithLimits(bz, ptr, cdc.getDecodeLimits())
+}
+
+// Like UnmarshalJSON, but with the given limits instead of those of the codec.
+func (cdc *Codec) UnmarshalJSONWithLimits(bz []byte, ptr interface{}, limits DecodeLimits) error {
 	if len(bz) == 0 {
 		return errors.New("cannot decode empty bytes")
 	}
@@ -528,20 +978,48 @@ func (cdc *Codec) UnmarshalJSON(bz []byte, ptr interface{}) error {
 	if err != nil {
 		return err
 	}
+	var ds = newDecodeState(limits)
+	ds.root = rt
 	// If registered concrete, consume and verify type wrapper.
-	if info.Registered {
+	if info.isTopLevelRegistered() {
 		// Consume type wrapper info.
 		name, data, err := decodeInterfaceJSON(bz)
 		if err != nil {
-			return err
+			return ds.jsonError(err, rt)
 		}
 		// Check name against info.
 		if name != info.Name {
-			return errors.Errorf("wanted to decode %v but found %v", info.Name, name)
+			err = kindErrorf(ErrKindUnknownPrefix, "wanted to decode %v but found %v", info.Name, name)
+			return ds.jsonError(err, rt)
 		}
 		bz = data
 	}
-	return cdc.decodeReflectJSON(bz, info, rv, FieldOptions{})
+	return cdc.decodeReflectJSON(bz, info, rv, FieldOptions{}, ds)
+}
+
+// UnmarshalJSONAny decodes bz, as returned from MarshalJSON for a value of
+// any registered concrete type, which is found by the type name of bz.  The
+// value is returned as a pointer if the concrete type was registered as one.
+func (cdc *Codec) UnmarshalJSONAny(bz []byte) (interface{}, error) {
+	var ds = newDecodeState(cdc.getDecodeLimits())
+	name, data, err := decodeInterfaceJSON(bz)
+	if err != nil {
+		return nil, ds.jsonError(err, nil)
+	}
+	cinfo, err := cdc.getTypeInfoFromNameRlock(name)
+	if err == nil && !cinfo.isTopLevelRegistered() {
+		err = kindErrorf(ErrKindUnknownPrefix, "%v (*%v) is not a top-level concrete type", cinfo.Name, cinfo.Type)
+	}
+	if err != nil {
+		return nil, ds.jsonError(err, nil)
+	}
+	ds.root = cinfo.Type
+	var crv, irvSet = constructConcreteType(cinfo)
+	err = cdc.decodeReflectJSON(data, cinfo, crv, FieldOptions{}, ds)
+	if err != nil {
+		return nil, err
+	}
+	return irvSet.Interface(), nil
 }
 
 // MustUnmarshalJSON panics if an error occurs. Besides tha behaves exactly like UnmarshalJSON.
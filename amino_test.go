@@ -2,6 +2,10 @@ package amino_test
 
 import (
 	"bytes"
+	"errors"
+	"reflect"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
@@ -124,6 +128,112 @@ func TestUnmarshalBinaryReaderTooLong(t *testing.T) {
 	assert.NotNil(t, err)
 }
 
+// Records the writes, and fails once more than limit bytes are written.
+type recordingWriter struct {
+	bytes.Buffer
+	limit    int
+	maxWrite int
+}
+
+func (w *recordingWriter) Write(p []byte) (int, error) {
+	if w.Len()+len(p) > w.limit {
+		return 0, errors.New("limit exceeded")
+	}
+	if len(p) > w.maxWrite {
+		w.maxWrite = len(p)
+	}
+	return w.Buffer.Write(p)
+}
+
+func TestMarshalBinaryLengthPrefixedWriter(t *testing.T) {
+	var cdc = amino.NewCodec()
+
+	type Inner struct {
+		A string
+		B string
+	}
+	type Outer struct {
+		Inners []Inner
+		Ptr    *Inner
+	}
+	long := strings.Repeat("x", 100)
+	o := Outer{Inners: []Inner{{long, long}, {long, ""}}, Ptr: &Inner{B: long}}
+	bz, err := cdc.MarshalBinaryLengthPrefixed(o)
+	assert.Nil(t, err)
+
+	// The value is written straight to w, not buffered as a whole.
+	w := &recordingWriter{limit: len(bz)}
+	n, err := cdc.MarshalBinaryLengthPrefixedWriter(w, o)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(len(bz)), n)
+	assert.Equal(t, bz, w.Bytes())
+	assert.True(t, w.maxWrite <= len(long), "max write %v", w.maxWrite)
+
+	// Write errors are returned, with the number of bytes written until then.
+	w = &recordingWriter{limit: 150}
+	n, err = cdc.MarshalBinaryLengthPrefixedWriter(w, o)
+	assert.NotNil(t, err)
+	assert.Equal(t, int64(w.Len()), n)
+	assert.Equal(t, bz[:n], w.Bytes())
+}
+
+// A repr type that counts the calls to MarshalAmino.
+type countedRepr struct {
+	N        int64
+	Children []countedRepr
+}
+
+type countedReprRepr struct {
+	N        int64
+	Children []countedRepr
+}
+
+var countedReprCalls int
+
+func (c countedRepr) MarshalAmino() (countedReprRepr, error) {
+	countedReprCalls++
+	return countedReprRepr(c), nil
+}
+
+func (c *countedRepr) UnmarshalAmino(repr countedReprRepr) error {
+	*c = countedRepr(repr)
+	return nil
+}
+
+func newCountedRepr(depth int) countedRepr {
+	c := countedRepr{N: int64(depth)}
+	if depth > 1 {
+		c.Children = []countedRepr{newCountedRepr(depth - 1), newCountedRepr(depth - 1)}
+	}
+	return c
+}
+
+func TestMarshalAminoCalledOnce(t *testing.T) {
+	var cdc = amino.NewCodec()
+
+	type Outer struct {
+		Root   countedRepr
+		ByName map[string]countedRepr
+	}
+	o := Outer{Root: newCountedRepr(4), ByName: map[string]countedRepr{"a": newCountedRepr(2)}}
+	const count = 15 + 3
+
+	// Each value is sized once, before it is written.
+	countedReprCalls = 0
+	bz, err := cdc.MarshalBinaryBare(o)
+	assert.Nil(t, err)
+	assert.Equal(t, count, countedReprCalls)
+
+	countedReprCalls = 0
+	_, err = cdc.MarshalBinaryLengthPrefixedWriter(new(bytes.Buffer), o)
+	assert.Nil(t, err)
+	assert.Equal(t, count, countedReprCalls)
+
+	var o2 Outer
+	assert.Nil(t, cdc.UnmarshalBinaryBare(bz, &o2))
+	assert.Equal(t, o, o2)
+}
+
 func TestUnmarshalBinaryBufferedWritesReads(t *testing.T) {
 	var cdc = amino.NewCodec()
 	var buf = bytes.NewBuffer(nil)
@@ -153,3 +263,539 @@ func TestUnmarshalBinaryBufferedWritesReads(t *testing.T) {
 	_, err = cdc.UnmarshalBinaryLengthPrefixedReader(buf, &s2, 0)
 	assert.NotNil(t, err)
 }
+
+type rawMsg interface{}
+
+type rawPayload struct {
+	A string
+	B int64
+}
+
+type rawEnvelope struct {
+	Header  string
+	Payload rawPayload
+	Msg     rawMsg
+}
+
+type rawLazyEnvelope struct {
+	Header  string
+	Payload amino.Raw
+	Msg     amino.Raw
+}
+
+type rawLazyJSONEnvelope struct {
+	Header  string
+	Payload amino.RawJSON
+	Msg     amino.RawJSON
+}
+
+func TestRaw(t *testing.T) {
+	var cdc = amino.NewCodec()
+	cdc.RegisterInterface((*rawMsg)(nil), nil)
+	cdc.RegisterConcrete(rawPayload{}, "amino_test/rawPayload", nil)
+
+	for _, e := range []rawEnvelope{
+		{"foo", rawPayload{"bar", 1}, rawPayload{"baz", 2}},
+		{"foo", rawPayload{}, nil},
+		{},
+	} {
+		// Binary
+		bz, err := cdc.MarshalBinaryBare(e)
+		assert.Nil(t, err)
+		var lazy rawLazyEnvelope
+		assert.Nil(t, cdc.UnmarshalBinaryBare(bz, &lazy))
+		assert.Equal(t, e.Header, lazy.Header)
+		bz2, err := cdc.MarshalBinaryBare(lazy)
+		assert.Nil(t, err)
+		assert.Equal(t, bz, bz2)
+
+		var e2 rawEnvelope
+		e2.Header = lazy.Header
+		assert.Nil(t, lazy.Payload.Decode(cdc, &e2.Payload))
+		assert.Nil(t, lazy.Msg.Decode(cdc, &e2.Msg))
+		assert.Equal(t, e, e2)
+
+		// JSON
+		bz, err = cdc.MarshalJSON(e)
+		assert.Nil(t, err)
+		var lazyJSON rawLazyJSONEnvelope
+		assert.Nil(t, cdc.UnmarshalJSON(bz, &lazyJSON))
+		assert.Equal(t, e.Header, lazyJSON.Header)
+		bz2, err = cdc.MarshalJSON(lazyJSON)
+		assert.Nil(t, err)
+		assert.Equal(t, string(bz), string(bz2))
+
+		e2 = rawEnvelope{Header: lazyJSON.Header}
+		assert.Nil(t, lazyJSON.Payload.Decode(cdc, &e2.Payload))
+		assert.Nil(t, lazyJSON.Msg.Decode(cdc, &e2.Msg))
+		assert.Equal(t, e, e2)
+	}
+
+	// Trailing bytes are an error.
+	bz, err := cdc.MarshalBinaryBare(rawEnvelope{Payload: rawPayload{"bar", 1}})
+	assert.Nil(t, err)
+	var lazy rawLazyEnvelope
+	assert.Nil(t, cdc.UnmarshalBinaryBare(bz, &lazy))
+	var p rawPayload
+	assert.NotNil(t, append(lazy.Payload, 0x00).Decode(cdc, &p))
+
+	// An empty field is kept, unlike an absent one.
+	for _, bz := range [][]byte{{0x12, 0x00}, {}} {
+		lazy = rawLazyEnvelope{}
+		assert.Nil(t, cdc.UnmarshalBinaryBare(bz, &lazy))
+		assert.Equal(t, len(bz) == 0, lazy.Payload == nil)
+		bz2, err := cdc.MarshalBinaryBare(lazy)
+		assert.Nil(t, err)
+		assert.Equal(t, bz, append([]byte{}, bz2...))
+		assert.Nil(t, cdc.UnmarshalBinaryBareStrict(bz, &lazy))
+	}
+
+	// Invalid JSON is not written.
+	_, err = cdc.MarshalJSON(rawLazyJSONEnvelope{Payload: amino.RawJSON("{")})
+	assert.NotNil(t, err)
+}
+
+type anyStruct struct{ A string }
+
+type anyPtrStruct struct{ B int64 }
+
+type anyBytes [4]byte
+
+func TestUnmarshalAny(t *testing.T) {
+	var cdc = amino.NewCodec()
+	cdc.RegisterConcrete(anyStruct{}, "amino_test/anyStruct", nil)
+	cdc.RegisterConcrete(&anyPtrStruct{}, "amino_test/anyPtrStruct", nil)
+	cdc.RegisterConcrete(anyBytes{}, "amino_test/anyBytes", nil)
+
+	for _, o := range []interface{}{
+		anyStruct{"a"},
+		anyStruct{},
+		&anyPtrStruct{7},
+		anyBytes{1, 2, 3, 4},
+	} {
+		bz, err := cdc.MarshalBinaryBare(o)
+		assert.Nil(t, err)
+		got, err := cdc.UnmarshalBinaryBareAny(bz)
+		assert.Nil(t, err)
+		assert.Equal(t, o, got)
+
+		bz, err = cdc.MarshalBinaryLengthPrefixed(o)
+		assert.Nil(t, err)
+		got, err = cdc.UnmarshalBinaryLengthPrefixedAny(bz)
+		assert.Nil(t, err)
+		assert.Equal(t, o, got)
+
+		bz, err = cdc.MarshalJSON(o)
+		assert.Nil(t, err)
+		got, err = cdc.UnmarshalJSONAny(bz)
+		assert.Nil(t, err)
+		assert.Equal(t, o, got)
+	}
+
+	// With disambiguation bytes.
+	var bz = cdc.MustMarshalBinaryBare(anyStruct{"a"})
+	var disamb, _ = amino.NameToDisfix("amino_test/anyStruct")
+	got, err := cdc.UnmarshalBinaryBareAny(append(append([]byte{0x00}, disamb[:]...), bz...))
+	assert.Nil(t, err)
+	assert.Equal(t, anyStruct{"a"}, got)
+
+	// Unregistered prefix bytes and names.
+	_, err = cdc.UnmarshalBinaryBareAny([]byte{0x01, 0x02, 0x03, 0x04})
+	if assert.IsType(t, &amino.DecodeError{}, err) {
+		assert.Equal(t, amino.ErrKindUnknownPrefix, err.(*amino.DecodeError).Kind)
+	}
+	_, err = cdc.UnmarshalBinaryBareAny(nil)
+	assert.NotNil(t, err)
+	_, err = cdc.UnmarshalJSONAny([]byte(`{"type":"amino_test/nope","value":{}}`))
+	assert.NotNil(t, err)
+	_, err = cdc.UnmarshalJSONAny([]byte(`{"A":"a"}`))
+	assert.NotNil(t, err)
+
+	// Sealed codecs look up the prefix bytes without locking.
+	cdc.Seal()
+	got, err = cdc.UnmarshalBinaryBareAny(bz)
+	assert.Nil(t, err)
+	assert.Equal(t, anyStruct{"a"}, got)
+}
+
+type unknownMsg interface{}
+
+type unknownMsgA struct{ A string }
+
+type unknownMsgB struct {
+	B []byte
+	N int64
+}
+
+type unknownEnvelope struct {
+	Msg    unknownMsg
+	Msgs   []unknownMsg
+	Height int64
+}
+
+func TestUnknownConcrete(t *testing.T) {
+	var newCodec = func(iopts *amino.InterfaceOptions, withB bool) *amino.Codec {
+		var cdc = amino.NewCodec()
+		cdc.RegisterInterface((*unknownMsg)(nil), iopts)
+		cdc.RegisterConcrete(unknownMsgA{}, "amino_test/unknownMsgA", nil)
+		if withB {
+			cdc.RegisterConcrete(&unknownMsgB{}, "amino_test/unknownMsgB", nil)
+		}
+		return cdc
+	}
+	var envelope = unknownEnvelope{
+		Msg:    &unknownMsgB{[]byte("b"), 7},
+		Msgs:   []unknownMsg{unknownMsgA{"a"}, &unknownMsgB{N: 8}, nil},
+		Height: 42,
+	}
+
+	for _, disamb := range []bool{false, true} {
+		var iopts = &amino.InterfaceOptions{AllowUnknownConcrete: true, AlwaysDisambiguate: disamb}
+		var cdc = newCodec(iopts, true)
+		var old = newCodec(iopts, false)
+
+		// Binary is preserved byte for byte.
+		bz, err := cdc.MarshalBinaryBare(envelope)
+		assert.Nil(t, err)
+		var got unknownEnvelope
+		assert.Nil(t, old.UnmarshalBinaryBare(bz, &got))
+		if assert.IsType(t, amino.UnknownConcrete{}, got.Msg) {
+			assert.Equal(t, disamb, got.Msg.(amino.UnknownConcrete).HasDisamb)
+		}
+		assert.Equal(t, unknownMsgA{"a"}, got.Msgs[0])
+		assert.IsType(t, amino.UnknownConcrete{}, got.Msgs[1])
+		assert.Equal(t, int64(42), got.Height)
+		bz2, err := old.MarshalBinaryBare(got)
+		assert.Nil(t, err)
+		assert.Equal(t, bz, bz2)
+		_, err = old.MarshalJSON(got)
+		assert.NotNil(t, err)
+
+		// JSON is preserved byte for byte.
+		jsonBz, err := cdc.MarshalJSON(envelope)
+		assert.Nil(t, err)
+		got = unknownEnvelope{}
+		assert.Nil(t, old.UnmarshalJSON(jsonBz, &got))
+		if assert.IsType(t, amino.UnknownConcrete{}, got.Msg) {
+			assert.Equal(t, "amino_test/unknownMsgB", got.Msg.(amino.UnknownConcrete).Name)
+		}
+		jsonBz2, err := old.MarshalJSON(got)
+		assert.Nil(t, err)
+		assert.Equal(t, string(jsonBz), string(jsonBz2))
+		_, err = old.MarshalBinaryBare(got)
+		assert.NotNil(t, err)
+
+		// The other way around, the original values are decoded.
+		got = unknownEnvelope{}
+		assert.Nil(t, cdc.UnmarshalBinaryBare(bz2, &got))
+		assert.Equal(t, envelope, got)
+	}
+
+	// Without AllowUnknownConcrete, decoding fails.
+	var bz = newCodec(nil, true).MustMarshalBinaryBare(envelope)
+	var err = newCodec(nil, false).UnmarshalBinaryBare(bz, new(unknownEnvelope))
+	if assert.IsType(t, &amino.DecodeError{}, err) {
+		assert.Equal(t, amino.ErrKindUnknownPrefix, err.(*amino.DecodeError).Kind)
+	}
+
+	// Names are escaped when encoded again.
+	var name = `amino_test/"unknown\\Msg"`
+	var jsonBz = []byte(`{"Msg":{"type":` + strconv.Quote(name) + `,"value":{}},"Msgs":null,"Height":"0"}`)
+	var old = newCodec(&amino.InterfaceOptions{AllowUnknownConcrete: true}, false)
+	var got unknownEnvelope
+	assert.Nil(t, old.UnmarshalJSON(jsonBz, &got))
+	if assert.IsType(t, amino.UnknownConcrete{}, got.Msg) {
+		assert.Equal(t, name, got.Msg.(amino.UnknownConcrete).Name)
+	}
+	jsonBz2, err := old.MarshalJSON(got)
+	assert.Nil(t, err)
+	assert.Equal(t, string(jsonBz), string(jsonBz2))
+
+	// Unknown types of interfaces that UnknownConcrete doesn't implement are
+	// rejected as without AllowUnknownConcrete.
+	var stringerCdc = amino.NewCodec()
+	stringerCdc.RegisterInterface((*unknownStringer)(nil), &amino.InterfaceOptions{AllowUnknownConcrete: true})
+	bz = []byte{0x0a, 0x04, 0x01, 0x02, 0x03, 0x04}
+	err = stringerCdc.UnmarshalBinaryBare(bz, new(unknownStringerEnvelope))
+	if assert.IsType(t, &amino.DecodeError{}, err) {
+		assert.Equal(t, amino.ErrKindUnknownPrefix, err.(*amino.DecodeError).Kind)
+	}
+	err = stringerCdc.UnmarshalJSON([]byte(`{"S":{"type":"amino_test/unknown","value":{}}}`), new(unknownStringerEnvelope))
+	assert.NotNil(t, err)
+}
+
+type unknownStringer interface{ String() string }
+
+type unknownStringerEnvelope struct {
+	S unknownStringer
+}
+
+type allowMsg interface{ AllowMsg() }
+
+type allowMsgA struct{ A string }
+
+func (allowMsgA) AllowMsg() {}
+
+type allowMsgB struct{ B int64 }
+
+func (*allowMsgB) AllowMsg() {}
+
+type allowOther struct{ A string }
+
+type allowEnvelope struct {
+	Msg  allowMsg
+	Msgs []allowMsg `amino:"allow=amino_test/allowMsgA"`
+}
+
+type allowAny interface{}
+
+// Like allowEnvelope, but accepts any concrete type.
+type allowAnyEnvelope struct {
+	Msg  allowAny
+	Msgs []allowAny
+}
+
+func TestConcreteAllowed(t *testing.T) {
+	var newCodec = func(iopts *amino.InterfaceOptions) *amino.Codec {
+		var cdc = amino.NewCodec()
+		cdc.RegisterInterface((*allowMsg)(nil), iopts)
+		cdc.RegisterInterface((*allowAny)(nil), nil)
+		cdc.RegisterConcrete(allowMsgA{}, "amino_test/allowMsgA", nil)
+		cdc.RegisterConcrete(&allowMsgB{}, "amino_test/allowMsgB", nil)
+		cdc.RegisterConcrete(allowOther{}, "amino_test/allowOther", nil)
+		return cdc
+	}
+	var encode = func(cdc *amino.Codec, o allowAnyEnvelope) ([]byte, []byte) {
+		bz, err := cdc.MarshalBinaryBare(o)
+		assert.Nil(t, err)
+		jsonBz, err := cdc.MarshalJSON(o)
+		assert.Nil(t, err)
+		return bz, jsonBz
+	}
+
+	cases := []struct {
+		iopts   *amino.InterfaceOptions
+		o       allowAnyEnvelope
+		allowed bool
+	}{
+		{nil, allowAnyEnvelope{allowMsgA{"a"}, []allowAny{allowMsgA{"b"}}}, true},
+		{nil, allowAnyEnvelope{&allowMsgB{1}, nil}, true},
+		// Doesn't implement allowMsg.
+		{nil, allowAnyEnvelope{allowOther{"a"}, nil}, false},
+		{nil, allowAnyEnvelope{nil, []allowAny{allowOther{"a"}}}, false},
+		// Not allowed by the field.
+		{nil, allowAnyEnvelope{nil, []allowAny{allowMsgA{"a"}, &allowMsgB{1}}}, false},
+		// Not allowed by the interface.
+		{&amino.InterfaceOptions{Allow: []string{"amino_test/allowMsgA"}},
+			allowAnyEnvelope{allowMsgA{"a"}, []allowAny{allowMsgA{"b"}}}, true},
+		{&amino.InterfaceOptions{Allow: []string{"amino_test/allowMsgA"}},
+			allowAnyEnvelope{&allowMsgB{1}, nil}, false},
+		{&amino.InterfaceOptions{Allow: []string{"amino_test/allowMsgB"}},
+			allowAnyEnvelope{nil, []allowAny{allowMsgA{"a"}}}, false},
+	}
+	for i, tc := range cases {
+		var cdc = newCodec(tc.iopts)
+		bz, jsonBz := encode(cdc, tc.o)
+
+		var got allowEnvelope
+		var err = cdc.UnmarshalBinaryBare(bz, &got)
+		var jsonErr = cdc.UnmarshalJSON(jsonBz, new(allowEnvelope))
+		if tc.allowed {
+			assert.Nil(t, err, "case %v", i)
+			assert.Nil(t, jsonErr, "case %v", i)
+			continue
+		}
+		for _, err := range []error{err, jsonErr} {
+			if assert.IsType(t, &amino.DecodeError{}, err, "case %v", i) {
+				assert.Equal(t, amino.ErrKindNotAllowed, err.(*amino.DecodeError).Kind, "case %v", i)
+			}
+		}
+	}
+
+	// The same with disambiguation bytes.
+	var cdc = amino.NewCodec()
+	cdc.RegisterInterface((*allowMsg)(nil), &amino.InterfaceOptions{AlwaysDisambiguate: true})
+	cdc.RegisterConcrete(allowOther{}, "amino_test/allowOther", nil)
+	cdc.RegisterInterface((*allowAny)(nil), &amino.InterfaceOptions{AlwaysDisambiguate: true})
+	bz := cdc.MustMarshalBinaryBare(allowAnyEnvelope{Msg: allowOther{"a"}})
+	var err = cdc.UnmarshalBinaryBare(bz, new(allowEnvelope))
+	if assert.IsType(t, &amino.DecodeError{}, err) {
+		assert.Equal(t, amino.ErrKindNotAllowed, err.(*amino.DecodeError).Kind)
+	}
+
+	// Queries check the same.
+	cdc = newCodec(nil)
+	bz, jsonBz := encode(cdc, allowAnyEnvelope{allowOther{"a"}, nil})
+	var rootType = reflect.TypeOf(allowEnvelope{})
+	assert.NotNil(t, cdc.QueryBinary(bz, rootType, "Msg.A", new(string)))
+	assert.NotNil(t, cdc.QueryJSON(jsonBz, rootType, "Msg.A", new(string)))
+
+	// Invalid tags.
+	assert.Panics(t, func() {
+		newCodec(nil).MustMarshalBinaryBare(struct {
+			Msg allowMsg `amino:"allow="`
+		}{})
+	})
+}
+
+type reuseMsg interface{}
+
+type reuseA struct{ A int64 }
+
+type reuseB struct{ B string }
+
+type reuseC struct {
+	B int64
+	A string `json:",omitempty"`
+}
+
+type reuseEnvelope struct {
+	Msg  reuseMsg
+	PMsg *reuseMsg
+	PP   **reuseMsg
+}
+
+func TestDecodeNonNilInterface(t *testing.T) {
+	var cdc = amino.NewCodec()
+	cdc.RegisterInterface((*reuseMsg)(nil), nil)
+	cdc.RegisterConcrete(&reuseA{}, "reuse/A", nil)
+	cdc.RegisterConcrete(reuseB{}, "reuse/B", nil)
+	cdc.RegisterConcrete(&reuseC{}, "reuse/C", nil)
+
+	var msg, pmsg reuseMsg = &reuseA{1}, reuseB{"b"}
+	var ppmsg = &pmsg
+	var env = reuseEnvelope{Msg: msg, PMsg: &pmsg, PP: &ppmsg}
+
+	var codecs = []struct {
+		name                string
+		marshal             func(interface{}) ([]byte, error)
+		unmarshal           func([]byte, interface{}) error
+		unmarshalWithLimits func([]byte, interface{}, amino.DecodeLimits) error
+	}{
+		{"binary", cdc.MarshalBinaryBare, cdc.UnmarshalBinaryBare, cdc.UnmarshalBinaryBareWithLimits},
+		{"json", cdc.MarshalJSON, cdc.UnmarshalJSON, cdc.UnmarshalJSONWithLimits},
+	}
+	for _, c := range codecs {
+		bz, err := c.marshal(env)
+		assert.NoError(t, err, c.name)
+
+		// Pointer-to-interface fields round-trip.
+		var env2 reuseEnvelope
+		err = c.unmarshal(bz, &env2)
+		assert.NoError(t, err, c.name)
+		assert.Equal(t, env, env2, c.name)
+
+		// A value of the decoded concrete type is decoded into.
+		var a = &reuseA{2}
+		var env3 = reuseEnvelope{Msg: a}
+		err = c.unmarshal(bz, &env3)
+		assert.NoError(t, err, c.name)
+		assert.True(t, env3.Msg.(*reuseA) == a, c.name)
+		assert.Equal(t, int64(1), a.A, c.name)
+
+		// Values of other types are replaced.
+		var env4 = reuseEnvelope{Msg: reuseB{"c"}}
+		err = c.unmarshal(bz, &env4)
+		assert.NoError(t, err, c.name)
+		assert.Equal(t, env, env4, c.name)
+
+		// As are values of fields that are absent.
+		bz, err = c.marshal(reuseEnvelope{Msg: &reuseA{3}})
+		assert.NoError(t, err, c.name)
+		var env5 = reuseEnvelope{Msg: a, PMsg: &pmsg}
+		err = c.unmarshal(bz, &env5)
+		assert.NoError(t, err, c.name)
+		assert.Equal(t, reuseEnvelope{Msg: &reuseA{3}}, env5, c.name)
+		assert.True(t, env5.Msg.(*reuseA) == a, c.name)
+
+		// The value pointed to is overwritten as a whole, so fields that are
+		// absent are reset.
+		bz, err = c.marshal(reuseEnvelope{Msg: &reuseC{B: 1}})
+		assert.NoError(t, err, c.name)
+		var rc = &reuseC{B: 2, A: "a"}
+		var env6 = reuseEnvelope{Msg: rc}
+		err = c.unmarshal(bz, &env6)
+		assert.NoError(t, err, c.name)
+		assert.True(t, env6.Msg.(*reuseC) == rc, c.name)
+		assert.Equal(t, reuseC{B: 1}, *rc, c.name)
+
+		// And only if decoding succeeds.
+		bz, err = c.marshal(reuseEnvelope{Msg: &reuseC{B: 3, A: "long"}})
+		assert.NoError(t, err, c.name)
+		err = c.unmarshalWithLimits(bz, &env6, amino.DecodeLimits{MaxStringLen: 3})
+		assert.Equal(t, amino.LimitExceededErr{Limit: "MaxStringLen", Max: 3}, err, c.name)
+		assert.True(t, env6.Msg.(*reuseC) == rc, c.name)
+		assert.Equal(t, reuseC{B: 1}, *rc, c.name)
+
+		// The same goes for the top-level value.
+		bz, err = c.marshal(reuseB{"d"})
+		assert.NoError(t, err, c.name)
+		var top reuseMsg = a
+		err = c.unmarshal(bz, &top)
+		assert.NoError(t, err, c.name)
+		assert.Equal(t, reuseB{"d"}, top, c.name)
+	}
+}
+
+func TestRegisterInterfacePointer(t *testing.T) {
+	var cdc = amino.NewCodec()
+	assert.Panics(t, func() {
+		cdc.RegisterConcrete((*reuseMsg)(nil), "reuse/Msg", nil)
+	}, "the interface is not registered")
+	cdc.RegisterInterface((*reuseMsg)(nil), nil)
+	cdc.RegisterConcrete(reuseB{}, "reuse/B", nil)
+	cdc.RegisterConcrete((*reuseMsg)(nil), "reuse/Msg", nil)
+	assert.Panics(t, func() {
+		cdc.RegisterConcrete((*reuseMsg)(nil), "reuse/Msg2", nil)
+	}, "already registered")
+
+	var inner reuseMsg = reuseB{"b"}
+	var env = reuseEnvelope{Msg: &inner}
+	var codecs = []struct {
+		name      string
+		marshal   func(interface{}) ([]byte, error)
+		unmarshal func([]byte, interface{}) error
+	}{
+		{"binary", cdc.MarshalBinaryBare, cdc.UnmarshalBinaryBare},
+		{"json", cdc.MarshalJSON, cdc.UnmarshalJSON},
+	}
+	for _, c := range codecs {
+		bz, err := c.marshal(env)
+		assert.NoError(t, err, c.name)
+
+		var env2 reuseEnvelope
+		err = c.unmarshal(bz, &env2)
+		assert.NoError(t, err, c.name)
+		assert.Equal(t, env, env2, c.name)
+
+		// A non-nil interface-pointer is kept.
+		var inner3 reuseMsg = reuseB{"c"}
+		var env3 = reuseEnvelope{Msg: &inner3}
+		err = c.unmarshal(bz, &env3)
+		assert.NoError(t, err, c.name)
+		assert.True(t, env3.Msg.(*reuseMsg) == &inner3, c.name)
+		assert.Equal(t, reuseB{"b"}, inner3, c.name)
+
+		// Top-level interface values are not wrapped.
+		bz, err = c.marshal(inner)
+		assert.NoError(t, err, c.name)
+		var top reuseMsg
+		err = c.unmarshal(bz, &top)
+		assert.NoError(t, err, c.name)
+		assert.Equal(t, inner, top, c.name)
+
+		// There is nothing to encode for a pointer to a nil interface.
+		_, err = c.marshal(reuseEnvelope{Msg: new(reuseMsg)})
+		assert.Error(t, err, c.name)
+	}
+
+	// The prefix of the interface-pointer is followed by that of the value.
+	var _, pMsg = amino.NameToDisfix("reuse/Msg")
+	var _, pB = amino.NameToDisfix("reuse/B")
+	var want = append(append([]byte{0x0a, 0x0b}, pMsg[:]...), pB[:]...)
+	assert.Equal(t, append(want, 0x0a, 0x01, 'b'), cdc.MustMarshalBinaryBare(env))
+	assert.Equal(t, `{"Msg":{"type":"reuse/Msg","value":{"type":"reuse/B","value":{"B":"b"}}},"PMsg":null,"PP":null}`,
+		string(cdc.MustMarshalJSON(env)))
+	_, err := cdc.UnmarshalJSONAny([]byte(`{"type":"reuse/Msg","value":{"type":"reuse/B","value":{"B":"b"}}}`))
+	assert.Error(t, err)
+}
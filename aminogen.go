@@ -0,0 +1,553 @@
+package amino
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"io"
+	"reflect"
+)
+
+//----------------------------------------
+// Binary code generation
+
+// GenerateBinary writes Go code for package pkg with MarshalAminoBinary,
+// AminoSize and UnmarshalAminoBinary methods for the struct types of objs,
+// which must all be declared in that package.  The Codec uses these methods
+// instead of reflection, see AminoBinaryMarshaler and cmd/aminogen.
+//
+// The generated code handles fields of boolean, integer, float, string, []byte
+// and [N]byte types, and fields of the other generated struct types.  It falls
+// back to reflection for other fields, e.g. interfaces, lists, maps, pointers
+// and time.Time.
+func (cdc *Codec) GenerateBinary(w io.Writer, pkg string, objs ...interface{}) error {
+	var gen = &binaryGenerator{
+		cdc:   cdc,
+		types: make(map[reflect.Type]bool),
+	}
+	var infos = make([]*TypeInfo, 0, len(objs))
+	for _, obj := range objs {
+		var rt = reflect.TypeOf(obj)
+		for rt.Kind() == reflect.Ptr {
+			rt = rt.Elem()
+		}
+		info, err := cdc.getTypeInfoWlock(rt)
+		if err != nil {
+			return err
+		}
+		if err := gen.checkType(info); err != nil {
+			return err
+		}
+		gen.types[rt] = true
+		infos = append(infos, info)
+	}
+	for _, info := range infos {
+		if err := gen.writeMarshal(info); err != nil {
+			return err
+		}
+		if err := gen.writeSize(info); err != nil {
+			return err
+		}
+		if err := gen.writeUnmarshal(info); err != nil {
+			return err
+		}
+	}
+
+	var buf = new(bytes.Buffer)
+	fmt.Fprintf(buf, "// Code generated by aminogen. DO NOT EDIT.\n\n")
+	fmt.Fprintf(buf, "package %v\n\nimport (\n\t\"bytes\"\n", pkg)
+	if gen.fmt {
+		fmt.Fprintf(buf, "\t\"fmt\"\n")
+	}
+	fmt.Fprintf(buf, "\n\tamino \"github.com/tendermint/go-amino\"\n)\n")
+	buf.Write(gen.body.Bytes())
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return fmt.Errorf("error formatting generated code: %v", err)
+	}
+	_, err = w.Write(src)
+	return err
+}
+
+type binaryGenerator struct {
+	cdc     *Codec
+	body    bytes.Buffer
+	pkgPath string                // Import path of the generated package.
+	types   map[reflect.Type]bool // Types with generated code.
+	fmt     bool                  // Whether fmt is used.
+}
+
+func (gen *binaryGenerator) checkType(info *TypeInfo) error {
+	var rt = info.Type
+	switch {
+	case rt.Kind() != reflect.Struct || rt == timeType:
+		return fmt.Errorf("cannot generate code for %v, not a struct", rt)
+	case rt.Name() == "":
+		return fmt.Errorf("cannot generate code for unnamed type %v", rt)
+	case gen.pkgPath != "" && rt.PkgPath() != gen.pkgPath:
+		return fmt.Errorf("cannot generate code for %v, not in package %v", rt, gen.pkgPath)
+	case info.IsAminoMarshaler || info.IsAminoUnmarshaler:
+		return fmt.Errorf("cannot generate code for %v, it implements MarshalAmino or UnmarshalAmino", rt)
+	case info.UnknownFieldsIndex >= 0:
+		return fmt.Errorf("cannot generate code for %v, it has an amino.UnknownFields field", rt)
+	}
+	gen.pkgPath = rt.PkgPath()
+	return nil
+}
+
+// Returns the Go expression for type rt in the generated package, if any.
+func (gen *binaryGenerator) typeExpr(rt reflect.Type) (string, bool) {
+	switch {
+	case rt.Name() != "" && (rt.PkgPath() == "" || rt.PkgPath() == gen.pkgPath):
+		return rt.Name(), true
+	case rt.Kind() == reflect.Slice && rt.Elem() == byteType:
+		return "[]byte", true
+	case rt.Kind() == reflect.Array && rt.Elem() == byteType:
+		return fmt.Sprintf("[%v]byte", rt.Len()), true
+	default:
+		return "", false
+	}
+}
+
+// Returns true if the generated code handles the field, rather than
+// EncodeBinaryField, SizeBinaryField and DecodeBinaryField.
+func (gen *binaryGenerator) isInline(field *FieldInfo) (bool, error) {
+	var rt = field.Type
+	if field.UnpackedList {
+		return false, nil
+	}
+	if _, ok := gen.typeExpr(rt); !ok {
+		return false, nil
+	}
+	finfo, err := gen.cdc.getTypeInfoWlock(rt)
+	if err != nil {
+		return false, err
+	}
+	if finfo.IsAminoMarshaler || finfo.IsAminoUnmarshaler || (finfo.IsEnum && finfo.Strict) {
+		return false, nil
+	}
+	switch rt.Kind() {
+	case reflect.Bool, reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true, nil
+	case reflect.Slice, reflect.Array:
+		return rt.Elem() == byteType, nil
+	case reflect.Struct:
+		// Generated code doesn't handle `amino:"write_empty"` of the struct.
+		return gen.types[rt] && !field.WriteEmpty, nil
+	default:
+		return false, nil
+	}
+}
+
+func (gen *binaryGenerator) p(format string, args ...interface{}) {
+	fmt.Fprintf(&gen.body, format+"\n", args...)
+}
+
+func (gen *binaryGenerator) writeMarshal(info *TypeInfo) error {
+	var name = info.Type.Name()
+	gen.p("")
+	gen.p("// MarshalAminoBinary implements amino.AminoBinaryMarshaler.")
+	gen.p("func (x %v) MarshalAminoBinary(cdc *amino.Codec) ([]byte, error) {", name)
+	if len(info.Fields) == 0 {
+		gen.p("return nil, nil")
+		gen.p("}")
+		return nil
+	}
+	gen.p("var buf = new(bytes.Buffer)")
+	gen.p("var err error")
+	for i := range info.Fields {
+		var field = &info.Fields[i]
+		var v = "x." + field.Name
+		gen.p("// Field %v: %v", field.BinFieldNum, field.Name)
+		inline, err := gen.isInline(field)
+		if err != nil {
+			return err
+		}
+		var typ3 Typ3
+		if inline {
+			typ3 = typeToTyp3(field.Type, field.FieldOptions)
+		}
+		switch {
+		case !inline:
+			gen.p("if err = cdc.EncodeBinaryField(buf, &x, %v); err != nil {", field.Index)
+			gen.p("return nil, err")
+			gen.p("}")
+		case field.Type.Kind() == reflect.Struct:
+			// Empty structs are not written.
+			gen.p("{")
+			gen.p("var bz []byte")
+			gen.p("if bz, err = %v.MarshalAminoBinary(cdc); err != nil {", v)
+			gen.p("return nil, err")
+			gen.p("}")
+			gen.p("if len(bz) != 0 {")
+			gen.writeEncodeKey(field.BinFieldNum, typ3)
+			gen.p("if err = amino.EncodeByteSlice(buf, bz); err != nil {")
+			gen.p("return nil, err")
+			gen.p("}")
+			gen.p("}")
+			gen.p("}")
+		default:
+			var cond = nonDefaultCond(field.Type, v)
+			if cond == "false" {
+				continue
+			}
+			if cond != "true" {
+				gen.p("if %v {", cond)
+			}
+			gen.writeEncodeKey(field.BinFieldNum, typ3)
+			gen.p("if err = %v; err != nil {", encodeExpr(field.Type, field.FieldOptions, v))
+			gen.p("return nil, err")
+			gen.p("}")
+			if cond != "true" {
+				gen.p("}")
+			}
+		}
+	}
+	gen.p("return buf.Bytes(), nil")
+	gen.p("}")
+	return nil
+}
+
+func (gen *binaryGenerator) writeEncodeKey(num uint32, typ3 Typ3) {
+	gen.p("if err = amino.EncodeFieldKey(buf, %v, amino.%v); err != nil {", num, typ3GoName(typ3))
+	gen.p("return nil, err")
+	gen.p("}")
+}
+
+func (gen *binaryGenerator) writeSize(info *TypeInfo) error {
+	var name = info.Type.Name()
+	gen.p("")
+	gen.p("// AminoSize implements amino.AminoBinaryMarshaler.")
+	gen.p("func (x %v) AminoSize(cdc *amino.Codec) (int, error) {", name)
+	gen.p("var n int")
+	var hasSize bool
+	for i := range info.Fields {
+		var field = &info.Fields[i]
+		var v = "x." + field.Name
+		gen.p("// Field %v: %v", field.BinFieldNum, field.Name)
+		inline, err := gen.isInline(field)
+		if err != nil {
+			return err
+		}
+		var keySize int
+		if inline {
+			var typ3 = typeToTyp3(field.Type, field.FieldOptions)
+			keySize = UvarintSize(uint64(field.BinFieldNum)<<3 | uint64(typ3))
+		}
+		switch {
+		case !inline:
+			if !hasSize {
+				gen.p("var size int")
+				gen.p("var err error")
+				hasSize = true
+			}
+			gen.p("if size, err = cdc.SizeBinaryField(&x, %v); err != nil {", field.Index)
+			gen.p("return 0, err")
+			gen.p("}")
+			gen.p("n += size")
+		case field.Type.Kind() == reflect.Struct:
+			gen.p("if size, err := %v.AminoSize(cdc); err != nil {", v)
+			gen.p("return 0, err")
+			gen.p("} else if size != 0 {")
+			gen.p("n += %v + amino.UvarintSize(uint64(size)) + size", keySize)
+			gen.p("}")
+		default:
+			var cond = nonDefaultCond(field.Type, v)
+			if cond == "false" {
+				continue
+			}
+			if cond != "true" {
+				gen.p("if %v {", cond)
+			}
+			gen.p("n += %v + %v", keySize, sizeExpr(field.Type, field.FieldOptions, v))
+			if cond != "true" {
+				gen.p("}")
+			}
+		}
+	}
+	gen.p("return n, nil")
+	gen.p("}")
+	return nil
+}
+
+func (gen *binaryGenerator) writeUnmarshal(info *TypeInfo) error {
+	var name = info.Type.Name()
+	gen.p("")
+	gen.p("// UnmarshalAminoBinary implements amino.AminoBinaryUnmarshaler.")
+	gen.p("func (x *%v) UnmarshalAminoBinary(cdc *amino.Codec, bz []byte) (err error) {", name)
+	gen.p("var lastFieldNum uint32")
+	var inlines = make([]bool, len(info.Fields))
+	var anyInline bool
+	for i := range info.Fields {
+		inline, err := gen.isInline(&info.Fields[i])
+		if err != nil {
+			return err
+		}
+		inlines[i] = inline
+		anyInline = anyInline || inline
+	}
+	if len(info.Fields) > 0 {
+		gen.p("var _n int")
+	}
+	if anyInline {
+		gen.p("var found bool")
+	}
+	for i := range info.Fields {
+		var field = &info.Fields[i]
+		var v = "x." + field.Name
+		gen.p("// Field %v: %v", field.BinFieldNum, field.Name)
+		if !inlines[i] {
+			gen.p("if _n, err = cdc.DecodeBinaryField(bz, x, %v, &lastFieldNum); err != nil {", field.Index)
+			gen.p("return")
+			gen.p("}")
+			gen.p("bz = bz[_n:]")
+			continue
+		}
+		var typ3 = typeToTyp3(field.Type, field.FieldOptions)
+		gen.p("if found, _n, err = amino.DecodeFieldKey(bz, %v, amino.%v, &lastFieldNum); err != nil {",
+			field.BinFieldNum, typ3GoName(typ3))
+		gen.p("return")
+		gen.p("}")
+		gen.p("bz = bz[_n:]")
+		gen.p("if found {")
+		gen.writeDecodeValue(field, v)
+		gen.p("bz = bz[_n:]")
+		gen.p("} else {")
+		texpr, _ := gen.typeExpr(field.Type)
+		switch field.Type.Kind() {
+		case reflect.Bool:
+			gen.p("%v = false", v)
+		case reflect.String:
+			gen.p("%v = \"\"", v)
+		case reflect.Slice:
+			gen.p("%v = nil", v)
+		case reflect.Array, reflect.Struct:
+			gen.p("%v = %v{}", v, texpr)
+		default:
+			gen.p("%v = 0", v)
+		}
+		gen.p("}")
+	}
+	gen.p("_, err = amino.SkipUnknownFields(bz, &lastFieldNum)")
+	gen.p("return")
+	gen.p("}")
+	return nil
+}
+
+// Writes code that decodes the value of the field from bz into v, and sets _n.
+func (gen *binaryGenerator) writeDecodeValue(field *FieldInfo, v string) {
+	var rt, fopts = field.Type, field.FieldOptions
+	var texpr, _ = gen.typeExpr(rt)
+	var decode = func(vtype, fn string) {
+		gen.p("var v %v", vtype)
+		gen.p("if v, _n, err = amino.%v(bz); err != nil {", fn)
+		gen.p("return")
+		gen.p("}")
+	}
+	var check = func(cond, errName string) {
+		gen.p("if %v {", cond)
+		gen.p("err = amino.%v", errName)
+		gen.p("return")
+		gen.p("}")
+	}
+	switch rt.Kind() {
+	case reflect.Int64:
+		if fopts.BinFixed64 {
+			decode("int64", "DecodeInt64")
+		} else {
+			decode("uint64", "DecodeUvarint")
+		}
+	case reflect.Int32:
+		if fopts.BinFixed32 {
+			decode("int32", "DecodeInt32")
+		} else {
+			decode("uint64", "DecodeUvarint")
+			check("int64(v) != int64(int32(v))", "ErrOverflowInt")
+		}
+	case reflect.Int16:
+		decode("int16", "DecodeInt16")
+	case reflect.Int8:
+		decode("int8", "DecodeInt8")
+	case reflect.Int:
+		decode("uint64", "DecodeUvarint")
+		check("int64(v) != int64(int(v))", "ErrOverflowInt")
+	case reflect.Uint64:
+		if fopts.BinFixed64 {
+			decode("uint64", "DecodeUint64")
+		} else {
+			decode("uint64", "DecodeUvarint")
+		}
+	case reflect.Uint32:
+		if fopts.BinFixed32 {
+			decode("uint32", "DecodeUint32")
+		} else {
+			decode("uint64", "DecodeUvarint")
+			check("v != uint64(uint32(v))", "ErrOverflowUint")
+		}
+	case reflect.Uint16:
+		decode("uint16", "DecodeUint16")
+	case reflect.Uint8:
+		decode("uint8", "DecodeUint8")
+	case reflect.Uint:
+		decode("uint64", "DecodeUvarint")
+		check("v != uint64(uint(v))", "ErrOverflowUint")
+	case reflect.Bool:
+		decode("bool", "DecodeBool")
+	case reflect.Float64:
+		decode("float64", "DecodeFloat64")
+	case reflect.Float32:
+		decode("float32", "DecodeFloat32")
+	case reflect.String:
+		decode("[]byte", "DecodeByteSliceNoCopy")
+	case reflect.Slice:
+		decode("[]byte", "DecodeByteSlice")
+		// NOTE: We prefer nil slices.
+		gen.p("if len(v) == 0 {")
+		gen.p("v = nil")
+		gen.p("}")
+	case reflect.Array:
+		decode("[]byte", "DecodeByteSliceNoCopy")
+		gen.p("if len(v) != %v {", rt.Len())
+		gen.p("err = fmt.Errorf(\"Mismatched byte array length: Expected %v, got %%v\", len(v))", rt.Len())
+		gen.p("return")
+		gen.p("}")
+		gen.p("copy(%v[:], v)", v)
+		gen.fmt = true
+		return
+	case reflect.Struct:
+		decode("[]byte", "DecodeByteSliceNoCopy")
+		gen.p("if err = %v.UnmarshalAminoBinary(cdc, v); err != nil {", v)
+		gen.p("return")
+		gen.p("}")
+		return
+	default:
+		panic("should not happen")
+	}
+	gen.p("%v = %v(v)", v, texpr)
+}
+
+// Returns the condition for writing a field of type rt with value v, see
+// isDefaultValue and writeFieldIfNotEmpty.
+func nonDefaultCond(rt reflect.Type, v string) string {
+	switch rt.Kind() {
+	case reflect.Bool:
+		return v
+	case reflect.String, reflect.Slice:
+		return fmt.Sprintf("len(%v) != 0", v)
+	case reflect.Array:
+		// Arrays are always written, unless empty.
+		if rt.Len() == 0 {
+			return "false"
+		}
+		return "true"
+	case reflect.Float32, reflect.Float64:
+		return "true"
+	default:
+		return fmt.Sprintf("%v != 0", v)
+	}
+}
+
+// Returns the expression that encodes v of type rt to buf, see
+// encodeReflectBinary.
+func encodeExpr(rt reflect.Type, fopts FieldOptions, v string) string {
+	switch rt.Kind() {
+	case reflect.Int64:
+		if fopts.BinFixed64 {
+			return fmt.Sprintf("amino.EncodeInt64(buf, int64(%v))", v)
+		}
+		return fmt.Sprintf("amino.EncodeUvarint(buf, uint64(%v))", v)
+	case reflect.Int32:
+		if fopts.BinFixed32 {
+			return fmt.Sprintf("amino.EncodeInt32(buf, int32(%v))", v)
+		}
+		return fmt.Sprintf("amino.EncodeUvarint(buf, uint64(%v))", v)
+	case reflect.Int16:
+		return fmt.Sprintf("amino.EncodeInt16(buf, int16(%v))", v)
+	case reflect.Int8:
+		return fmt.Sprintf("amino.EncodeInt8(buf, int8(%v))", v)
+	case reflect.Int:
+		return fmt.Sprintf("amino.EncodeUvarint(buf, uint64(%v))", v)
+	case reflect.Uint64:
+		if fopts.BinFixed64 {
+			return fmt.Sprintf("amino.EncodeUint64(buf, uint64(%v))", v)
+		}
+		return fmt.Sprintf("amino.EncodeUvarint(buf, uint64(%v))", v)
+	case reflect.Uint32:
+		if fopts.BinFixed32 {
+			return fmt.Sprintf("amino.EncodeUint32(buf, uint32(%v))", v)
+		}
+		return fmt.Sprintf("amino.EncodeUvarint(buf, uint64(%v))", v)
+	case reflect.Uint16:
+		return fmt.Sprintf("amino.EncodeUint16(buf, uint16(%v))", v)
+	case reflect.Uint8:
+		return fmt.Sprintf("amino.EncodeUint8(buf, uint8(%v))", v)
+	case reflect.Uint:
+		return fmt.Sprintf("amino.EncodeUvarint(buf, uint64(%v))", v)
+	case reflect.Bool:
+		return fmt.Sprintf("amino.EncodeBool(buf, bool(%v))", v)
+	case reflect.Float64:
+		return fmt.Sprintf("amino.EncodeFloat64(buf, float64(%v))", v)
+	case reflect.Float32:
+		return fmt.Sprintf("amino.EncodeFloat32(buf, float32(%v))", v)
+	case reflect.String:
+		return fmt.Sprintf("amino.EncodeString(buf, string(%v))", v)
+	case reflect.Slice:
+		return fmt.Sprintf("amino.EncodeByteSlice(buf, []byte(%v))", v)
+	case reflect.Array:
+		return fmt.Sprintf("amino.EncodeByteSlice(buf, %v[:])", v)
+	default:
+		panic("should not happen")
+	}
+}
+
+// Returns the expression for the encoded size of v of type rt.
+func sizeExpr(rt reflect.Type, fopts FieldOptions, v string) string {
+	switch rt.Kind() {
+	case reflect.Int64, reflect.Uint64:
+		if fopts.BinFixed64 {
+			return "8"
+		}
+		return fmt.Sprintf("amino.UvarintSize(uint64(%v))", v)
+	case reflect.Int32, reflect.Uint32:
+		if fopts.BinFixed32 {
+			return "4"
+		}
+		return fmt.Sprintf("amino.UvarintSize(uint64(%v))", v)
+	case reflect.Int16, reflect.Int8:
+		return fmt.Sprintf("amino.VarintSize(int64(%v))", v)
+	case reflect.Int, reflect.Uint, reflect.Uint16, reflect.Uint8:
+		return fmt.Sprintf("amino.UvarintSize(uint64(%v))", v)
+	case reflect.Bool:
+		return "1"
+	case reflect.Float64:
+		return "8"
+	case reflect.Float32:
+		return "4"
+	case reflect.String:
+		return fmt.Sprintf("amino.UvarintSize(uint64(len(%v))) + len(%v)", v, v)
+	case reflect.Slice:
+		return fmt.Sprintf("amino.ByteSliceSize([]byte(%v))", v)
+	case reflect.Array:
+		return fmt.Sprintf("%v", UvarintSize(uint64(rt.Len()))+rt.Len())
+	default:
+		panic("should not happen")
+	}
+}
+
+func typ3GoName(typ3 Typ3) string {
+	switch typ3 {
+	case Typ3Varint:
+		return "Typ3Varint"
+	case Typ38Byte:
+		return "Typ38Byte"
+	case Typ3ByteLength:
+		return "Typ3ByteLength"
+	case Typ3_4Byte:
+		return "Typ3_4Byte"
+	default:
+		panic("should not happen")
+	}
+}
@@ -0,0 +1,109 @@
+package amino_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"math/rand"
+	"testing"
+	"time"
+
+	fuzz "github.com/google/gofuzz"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	amino "github.com/tendermint/go-amino"
+	"github.com/tendermint/go-amino/tests/gen"
+)
+
+func newGenCodec() *amino.Codec {
+	cdc := amino.NewCodec()
+	cdc.RegisterInterface((*gen.Msg)(nil), nil)
+	cdc.RegisterConcrete(gen.MsgSend{}, "gen/MsgSend", nil)
+	cdc.SetCheckGenerated(true)
+	return cdc
+}
+
+var genFuzzFuncs = []interface{}{
+	func(tyme *time.Time, c fuzz.Continue) {
+		*tyme = time.Unix(c.Int63n(1<<33), c.Int63n(1e9)).UTC()
+	},
+	func(msgs *[]gen.Msg, c fuzz.Continue) {
+		*msgs = nil
+		for i := c.Intn(3); i > 0; i-- {
+			var msg gen.MsgSend
+			c.Fuzz(&msg)
+			*msgs = append(*msgs, msg)
+		}
+	},
+}
+
+func TestGeneratedBinary(t *testing.T) {
+	cdc := newGenCodec()
+	f := fuzz.New().NilChance(0.3).Funcs(genFuzzFuncs...)
+	f.RandSource(rand.New(rand.NewSource(10)))
+
+	for i := 0; i < 1e3; i++ {
+		var tx gen.Tx
+		f.Fuzz(&tx)
+
+		// Check mode compares generated code with reflection.
+		bz, err := cdc.MarshalBinaryBare(tx)
+		require.NoError(t, err)
+		var tx2 gen.Tx
+		require.NoError(t, cdc.UnmarshalBinaryBare(bz, &tx2))
+		bz2, err := cdc.MarshalBinaryBare(tx2)
+		require.NoError(t, err)
+		require.Equal(t, bz, bz2)
+
+		// Registered concrete types have generated code too.
+		for _, msg := range tx.Msgs {
+			bz, err = cdc.MarshalBinaryLengthPrefixed(msg)
+			require.NoError(t, err)
+			var msg2 gen.Msg
+			require.NoError(t, cdc.UnmarshalBinaryLengthPrefixed(bz, &msg2))
+			require.Equal(t, bz, cdc.MustMarshalBinaryLengthPrefixed(msg2))
+		}
+	}
+}
+
+func TestGeneratedBinaryErrors(t *testing.T) {
+	cdc := newGenCodec()
+
+	// Both generated code and reflection fail, and the error is the precise
+	// one of reflection.
+	bz := []byte{0x09, 0, 0, 0, 0, 0, 0, 0, 0}
+	derr := requireDecodeError(t, cdc.UnmarshalBinaryBare(bz, new(gen.Coin)))
+	assert.Equal(t, amino.ErrKindWrongTyp3, derr.Kind)
+	assert.Equal(t, "Coin.Denom", derr.Path)
+
+	bz = cdc.MustMarshalBinaryBare(gen.Header{Count: 1, Hash: [8]byte{1}})
+	bz = append(bz, 0x10, 0x01) // Height after Count.
+	derr = requireDecodeError(t, cdc.UnmarshalBinaryBare(bz, new(gen.Header)))
+	assert.Equal(t, amino.ErrKindFieldOrder, derr.Kind)
+
+	// Unknown fields are skipped.
+	bz = cdc.MustMarshalBinaryBare(gen.Coin{Denom: "atom", Amount: 1})
+	bz = append(bz, 0x1a, 0x01, 0x00)
+	var coin gen.Coin
+	require.NoError(t, cdc.UnmarshalBinaryBare(bz, &coin))
+	assert.Equal(t, gen.Coin{Denom: "atom", Amount: 1}, coin)
+}
+
+func TestGenerateBinaryUpToDate(t *testing.T) {
+	cdc := amino.NewCodec()
+	var buf bytes.Buffer
+	err := cdc.GenerateBinary(&buf, "gen",
+		gen.Coin{}, gen.Header{}, gen.MsgSend{}, gen.Tx{}, gen.Empty{})
+	require.NoError(t, err)
+	code, err := ioutil.ReadFile("tests/gen/amino_gen.go")
+	require.NoError(t, err)
+	assert.Equal(t, string(code), buf.String(), "run go generate in tests/gen")
+}
+
+func TestGenerateBinaryErrors(t *testing.T) {
+	cdc := amino.NewCodec()
+	var buf bytes.Buffer
+	assert.Error(t, cdc.GenerateBinary(&buf, "gen", gen.Kind(0)))
+	assert.Error(t, cdc.GenerateBinary(&buf, "gen", time.Time{}))
+	assert.Error(t, cdc.GenerateBinary(&buf, "gen", gen.Coin{}, errCoin{}))
+}
@@ -1,6 +1,8 @@
 package amino
 
 import (
+	"bytes"
+	"encoding/binary"
 	"fmt"
 	"math"
 	"reflect"
@@ -15,26 +17,41 @@ import (
 // cdc.decodeReflectBinary
 
 var (
-	ErrOverflowInt = errors.New("encoded integer value overflows int(32)")
+	ErrOverflowInt  = kindErrorf(ErrKindOverflow, "encoded integer value overflows int(32)")
+	ErrOverflowUint = kindErrorf(ErrKindOverflow, "encoded integer value overflows uint(32)")
 )
 
 const (
 	// architecture dependent int limits:
-	maxInt = int(^uint(0) >> 1)
-	minInt = -maxInt - 1
+	maxInt  = int(^uint(0) >> 1)
+	minInt  = -maxInt - 1
+	maxUint = ^uint(0)
 )
 
 // This is the main entrypoint for decoding all types from binary form. This
 // function calls decodeReflectBinary*, and generally those functions should
 // only call this one, for the prefix bytes are consumed here when present.
+// Errors are returned as DecodeErrors for the innermost failing value.
 // CONTRACT: rv.CanAddr() is true.
-func (cdc *Codec) decodeReflectBinary(bz []byte, info *TypeInfo, rv reflect.Value, fopts FieldOptions, bare bool) (n int, err error) {
+func (cdc *Codec) decodeReflectBinary(bz []byte, info *TypeInfo, rv reflect.Value, fopts FieldOptions, bare bool, ds *decodeState) (n int, err error) {
+	n, err = cdc.decodeReflectBinaryValue(bz, info, rv, fopts, bare, ds)
+	if err != nil {
+		// Composite values fail at an offending field or element.
+		var offset = ds.offset(bz)
+		switch info.Type.Kind() {
+		case reflect.Array, reflect.Slice, reflect.Struct, reflect.Map, reflect.Interface:
+			offset += n
+		}
+		err = ds.binaryError(err, info.Type, offset)
+	}
+	return
+}
+
+// CONTRACT: rv.CanAddr() is true.
+func (cdc *Codec) decodeReflectBinaryValue(bz []byte, info *TypeInfo, rv reflect.Value, fopts FieldOptions, bare bool, ds *decodeState) (n int, err error) {
 	if !rv.CanAddr() {
 		panic("rv not addressable")
 	}
-	if info.Type.Kind() == reflect.Interface && rv.Kind() == reflect.Ptr {
-		panic("should not happen")
-	}
 	if printLog {
 		spew.Printf("(D) decodeReflectBinary(bz: %X, info: %v, rv: %#v (%v), fopts: %v)\n",
 			bz, info, rv.Interface(), rv.Type(), fopts)
@@ -42,7 +59,6 @@ func (cdc *Codec) decodeReflectBinary(bz []byte, info *TypeInfo, rv reflect.Valu
 			fmt.Printf("(D) -> n: %v, err: %v\n", n, err)
 		}()
 	}
-	var _n int
 
 	// TODO consider the binary equivalent of json.Unmarshaller.
 
@@ -50,32 +66,57 @@ func (cdc *Codec) decodeReflectBinary(bz []byte, info *TypeInfo, rv reflect.Valu
 	// This works for pointer-pointers.
 	for rv.Kind() == reflect.Ptr {
 		if rv.IsNil() {
+			if err = ds.allocateType(rv.Type().Elem()); err != nil {
+				return
+			}
 			newPtr := reflect.New(rv.Type().Elem())
 			rv.Set(newPtr)
 		}
 		rv = rv.Elem()
 	}
+	// Pointers to interfaces (e.g. *Iface fields) are dereferenced above,
+	// but nothing else may be decoded as an interface.
+	if info.Type.Kind() == reflect.Interface && rv.Kind() != reflect.Interface {
+		panic("should not happen")
+	}
+
+	var plan *binaryPlan
+	plan, err = cdc.getBinaryPlan(info)
+	if err != nil {
+		return
+	}
+	n, err = plan.decode(bz, rv, fopts, bare, ds)
+	if err == nil && ds.strict && plan.scalar && n > 0 {
+		// The value, or the length of a string or bytes.
+		switch typeToTyp3(info.Type, fopts) {
+		case Typ3Varint, Typ3ByteLength:
+			err = ds.checkUvarint(bz)
+		}
+	}
+	return
+}
+
+// Returns the function that decodes values of type info, see binaryPlan.
+func (cdc *Codec) newBinaryDecoder(info *TypeInfo, plan *binaryPlan) binaryDecoder {
 
 	// Handle override if a pointer to rv implements UnmarshalAmino.
 	if info.IsAminoUnmarshaler {
-		// First, decode repr instance from bytes.
-		rrv, rinfo := reflect.New(info.AminoUnmarshalReprType).Elem(), (*TypeInfo)(nil)
-		rinfo, err = cdc.getTypeInfoWlock(info.AminoUnmarshalReprType)
-		if err != nil {
-			return
-		}
-		_n, err = cdc.decodeReflectBinary(bz, rinfo, rrv, fopts, bare)
-		if slide(&bz, &n, _n) && err != nil {
+		return func(bz []byte, rv reflect.Value, fopts FieldOptions, bare bool, ds *decodeState) (n int, err error) {
+			// First, decode repr instance from bytes.
+			rrv := reflect.New(info.AminoUnmarshalReprType).Elem()
+			n, err = cdc.decodeReflectBinary(bz, plan.unreprInfo, rrv, fopts, bare, ds)
+			if err != nil {
+				return
+			}
+			// Then, decode from repr instance.
+			uwrm := rv.Addr().MethodByName("UnmarshalAmino")
+			uwouts := uwrm.Call([]reflect.Value{rrv})
+			erri := uwouts[0].Interface()
+			if erri != nil {
+				err = erri.(error)
+			}
 			return
 		}
-		// Then, decode from repr instance.
-		uwrm := rv.Addr().MethodByName("UnmarshalAmino")
-		uwouts := uwrm.Call([]reflect.Value{rrv})
-		erri := uwouts[0].Interface()
-		if erri != nil {
-			err = erri.(error)
-		}
-		return
 	}
 
 	switch info.Type.Kind() {
@@ -84,231 +125,267 @@ func (cdc *Codec) decodeReflectBinary(bz []byte, info *TypeInfo, rv reflect.Valu
 	// Complex
 
 	case reflect.Interface:
-		_n, err = cdc.decodeReflectBinaryInterface(bz, info, rv, fopts, bare)
-		n += _n
-		return
+		return func(bz []byte, rv reflect.Value, fopts FieldOptions, bare bool, ds *decodeState) (int, error) {
+			return cdc.decodeReflectBinaryInterface(bz, info, rv, fopts, bare, ds)
+		}
 
 	case reflect.Array:
-		ert := info.Type.Elem()
-		if ert.Kind() == reflect.Uint8 {
-			_n, err = cdc.decodeReflectBinaryByteArray(bz, info, rv, fopts)
-			n += _n
-		} else {
-			_n, err = cdc.decodeReflectBinaryArray(bz, info, rv, fopts, bare)
-			n += _n
+		if info.Type.Elem().Kind() == reflect.Uint8 {
+			return func(bz []byte, rv reflect.Value, fopts FieldOptions, _ bool, ds *decodeState) (int, error) {
+				return cdc.decodeReflectBinaryByteArray(bz, info, rv, fopts, ds)
+			}
+		}
+		return func(bz []byte, rv reflect.Value, fopts FieldOptions, bare bool, ds *decodeState) (int, error) {
+			return cdc.decodeReflectBinaryArray(bz, info, plan, rv, fopts, bare, ds)
 		}
-		return
 
 	case reflect.Slice:
-		ert := info.Type.Elem()
-		if ert.Kind() == reflect.Uint8 {
-			_n, err = cdc.decodeReflectBinaryByteSlice(bz, info, rv, fopts)
-			n += _n
-		} else {
-			_n, err = cdc.decodeReflectBinarySlice(bz, info, rv, fopts, bare)
-			n += _n
+		if info.Type.Elem().Kind() == reflect.Uint8 {
+			return func(bz []byte, rv reflect.Value, fopts FieldOptions, _ bool, ds *decodeState) (int, error) {
+				return cdc.decodeReflectBinaryByteSlice(bz, info, rv, fopts, ds)
+			}
+		}
+		return func(bz []byte, rv reflect.Value, fopts FieldOptions, bare bool, ds *decodeState) (int, error) {
+			return cdc.decodeReflectBinarySlice(bz, info, plan, rv, fopts, bare, ds)
 		}
-		return
 
 	case reflect.Struct:
-		_n, err = cdc.decodeReflectBinaryStruct(bz, info, rv, fopts, bare)
-		n += _n
-		return
+		return func(bz []byte, rv reflect.Value, fopts FieldOptions, bare bool, ds *decodeState) (int, error) {
+			return cdc.decodeReflectBinaryStruct(bz, info, plan, rv, fopts, bare, ds)
+		}
+
+	case reflect.Map:
+		return func(bz []byte, rv reflect.Value, fopts FieldOptions, bare bool, ds *decodeState) (int, error) {
+			return cdc.decodeReflectBinaryMap(bz, info, plan, rv, fopts, bare, ds)
+		}
 
 	//----------------------------------------
 	// Signed
 
 	case reflect.Int64:
-		var num int64
-		if fopts.BinFixed64 {
-			num, _n, err = DecodeInt64(bz)
-			if slide(&bz, &n, _n) && err != nil {
-				return
-			}
-			rv.SetInt(num)
-		} else {
-			var u64 uint64
-			u64, _n, err = DecodeUvarint(bz)
-			if slide(&bz, &n, _n) && err != nil {
-				return
+		return func(bz []byte, rv reflect.Value, fopts FieldOptions, _ bool, _ *decodeState) (n int, err error) {
+			if fopts.BinFixed64 {
+				var num int64
+				num, n, err = DecodeInt64(bz)
+				if err != nil {
+					return
+				}
+				rv.SetInt(num)
+			} else {
+				var u64 uint64
+				u64, n, err = DecodeUvarint(bz)
+				if err != nil {
+					return
+				}
+				rv.SetInt(int64(u64))
 			}
-			rv.SetInt(int64(u64))
+			return
 		}
-		return
 
 	case reflect.Int32:
-		if fopts.BinFixed32 {
-			var num int32
-			num, _n, err = DecodeInt32(bz)
-			if slide(&bz, &n, _n) && err != nil {
-				return
-			}
-			rv.SetInt(int64(num))
-		} else {
-			var num uint64
-			num, _n, err = DecodeUvarint(bz)
-			if slide(&bz, &n, _n) && err != nil {
-				return
-			}
-			if int64(num) > math.MaxInt32 || int64(num) < math.MinInt32 {
-				err = ErrOverflowInt
-				return
+		return func(bz []byte, rv reflect.Value, fopts FieldOptions, _ bool, _ *decodeState) (n int, err error) {
+			if fopts.BinFixed32 {
+				var num int32
+				num, n, err = DecodeInt32(bz)
+				if err != nil {
+					return
+				}
+				rv.SetInt(int64(num))
+			} else {
+				var num uint64
+				num, n, err = DecodeUvarint(bz)
+				if err != nil {
+					return
+				}
+				if int64(num) > math.MaxInt32 || int64(num) < math.MinInt32 {
+					err = ErrOverflowInt
+					return
+				}
+				rv.SetInt(int64(num))
 			}
-			rv.SetInt(int64(num))
+			return
 		}
-		return
 
 	case reflect.Int16:
-		var num int16
-		num, _n, err = DecodeInt16(bz)
-		if slide(&bz, &n, _n) && err != nil {
+		return func(bz []byte, rv reflect.Value, _ FieldOptions, _ bool, _ *decodeState) (n int, err error) {
+			var num int16
+			num, n, err = DecodeInt16(bz)
+			if err != nil {
+				return
+			}
+			rv.SetInt(int64(num))
 			return
 		}
-		rv.SetInt(int64(num))
-		return
 
 	case reflect.Int8:
-		var num int8
-		num, _n, err = DecodeInt8(bz)
-		if slide(&bz, &n, _n) && err != nil {
+		return func(bz []byte, rv reflect.Value, _ FieldOptions, _ bool, _ *decodeState) (n int, err error) {
+			var num int8
+			num, n, err = DecodeInt8(bz)
+			if err != nil {
+				return
+			}
+			rv.SetInt(int64(num))
 			return
 		}
-		rv.SetInt(int64(num))
-		return
 
 	case reflect.Int:
-		var num uint64
-		num, _n, err = DecodeUvarint(bz)
-		if slide(&bz, &n, _n) && err != nil {
-			return
-		}
-		if int64(num) > int64(maxInt) || int64(num) < int64(minInt) {
-			err = ErrOverflowInt
+		return func(bz []byte, rv reflect.Value, _ FieldOptions, _ bool, _ *decodeState) (n int, err error) {
+			var num uint64
+			num, n, err = DecodeUvarint(bz)
+			if err != nil {
+				return
+			}
+			if int64(num) > int64(maxInt) || int64(num) < int64(minInt) {
+				err = ErrOverflowInt
+				return
+			}
+			rv.SetInt(int64(num))
 			return
 		}
-		rv.SetInt(int64(num))
-		return
 
 	//----------------------------------------
 	// Unsigned
 
 	case reflect.Uint64:
-		var num uint64
-		if fopts.BinFixed64 {
-			num, _n, err = DecodeUint64(bz)
-			if slide(&bz, &n, _n) && err != nil {
-				return
+		return func(bz []byte, rv reflect.Value, fopts FieldOptions, _ bool, _ *decodeState) (n int, err error) {
+			var num uint64
+			if fopts.BinFixed64 {
+				num, n, err = DecodeUint64(bz)
+			} else {
+				num, n, err = DecodeUvarint(bz)
 			}
-			rv.SetUint(num)
-		} else {
-			num, _n, err = DecodeUvarint(bz)
-			if slide(&bz, &n, _n) && err != nil {
+			if err != nil {
 				return
 			}
 			rv.SetUint(num)
+			return
 		}
-		return
 
 	case reflect.Uint32:
-		if fopts.BinFixed32 {
-			var num uint32
-			num, _n, err = DecodeUint32(bz)
-			if slide(&bz, &n, _n) && err != nil {
-				return
-			}
-			rv.SetUint(uint64(num))
-		} else {
-			var num uint64
-			num, _n, err = DecodeUvarint(bz)
-			if slide(&bz, &n, _n) && err != nil {
-				return
+		return func(bz []byte, rv reflect.Value, fopts FieldOptions, _ bool, _ *decodeState) (n int, err error) {
+			if fopts.BinFixed32 {
+				var num uint32
+				num, n, err = DecodeUint32(bz)
+				if err != nil {
+					return
+				}
+				rv.SetUint(uint64(num))
+			} else {
+				var num uint64
+				num, n, err = DecodeUvarint(bz)
+				if err != nil {
+					return
+				}
+				if num > math.MaxUint32 {
+					err = ErrOverflowUint
+					return
+				}
+				rv.SetUint(num)
 			}
-			rv.SetUint(uint64(num))
+			return
 		}
-		return
 
 	case reflect.Uint16:
-		var num uint16
-		num, _n, err = DecodeUint16(bz)
-		if slide(&bz, &n, _n) && err != nil {
+		return func(bz []byte, rv reflect.Value, _ FieldOptions, _ bool, _ *decodeState) (n int, err error) {
+			var num uint16
+			num, n, err = DecodeUint16(bz)
+			if err != nil {
+				return
+			}
+			rv.SetUint(uint64(num))
 			return
 		}
-		rv.SetUint(uint64(num))
-		return
 
 	case reflect.Uint8:
-		var num uint8
-		num, _n, err = DecodeUint8(bz)
-		if slide(&bz, &n, _n) && err != nil {
+		return func(bz []byte, rv reflect.Value, _ FieldOptions, _ bool, _ *decodeState) (n int, err error) {
+			var num uint8
+			num, n, err = DecodeUint8(bz)
+			if err != nil {
+				return
+			}
+			rv.SetUint(uint64(num))
 			return
 		}
-		rv.SetUint(uint64(num))
-		return
 
 	case reflect.Uint:
-		var num uint64
-		num, _n, err = DecodeUvarint(bz)
-		if slide(&bz, &n, _n) && err != nil {
+		return func(bz []byte, rv reflect.Value, _ FieldOptions, _ bool, _ *decodeState) (n int, err error) {
+			var num uint64
+			num, n, err = DecodeUvarint(bz)
+			if err != nil {
+				return
+			}
+			if num > uint64(maxUint) {
+				err = ErrOverflowUint
+				return
+			}
+			rv.SetUint(num)
 			return
 		}
-		rv.SetUint(num)
-		return
 
 	//----------------------------------------
 	// Misc.
 
 	case reflect.Bool:
-		var b bool
-		b, _n, err = DecodeBool(bz)
-		if slide(&bz, &n, _n) && err != nil {
+		return func(bz []byte, rv reflect.Value, _ FieldOptions, _ bool, _ *decodeState) (n int, err error) {
+			var b bool
+			b, n, err = DecodeBool(bz)
+			if err != nil {
+				return
+			}
+			rv.SetBool(b)
 			return
 		}
-		rv.SetBool(b)
-		return
 
 	case reflect.Float64:
-		var f float64
-		if !fopts.Unsafe {
-			err = errors.New("float support requires `amino:\"unsafe\"`")
-			return
-		}
-		f, _n, err = DecodeFloat64(bz)
-		if slide(&bz, &n, _n) && err != nil {
+		return func(bz []byte, rv reflect.Value, fopts FieldOptions, _ bool, _ *decodeState) (n int, err error) {
+			var f float64
+			if !fopts.Unsafe {
+				err = errors.New("float support requires `amino:\"unsafe\"`")
+				return
+			}
+			f, n, err = DecodeFloat64(bz)
+			if err != nil {
+				return
+			}
+			rv.SetFloat(f)
 			return
 		}
-		rv.SetFloat(f)
-		return
 
 	case reflect.Float32:
-		var f float32
-		if !fopts.Unsafe {
-			err = errors.New("float support requires `amino:\"unsafe\"`")
-			return
-		}
-		f, _n, err = DecodeFloat32(bz)
-		if slide(&bz, &n, _n) && err != nil {
+		return func(bz []byte, rv reflect.Value, fopts FieldOptions, _ bool, _ *decodeState) (n int, err error) {
+			var f float32
+			if !fopts.Unsafe {
+				err = errors.New("float support requires `amino:\"unsafe\"`")
+				return
+			}
+			f, n, err = DecodeFloat32(bz)
+			if err != nil {
+				return
+			}
+			rv.SetFloat(float64(f))
 			return
 		}
-		rv.SetFloat(float64(f))
-		return
 
 	case reflect.String:
-		var str string
-		str, _n, err = DecodeString(bz)
-		if slide(&bz, &n, _n) && err != nil {
+		return func(bz []byte, rv reflect.Value, _ FieldOptions, _ bool, ds *decodeState) (n int, err error) {
+			var bz2 []byte
+			bz2, n, err = ds.decodeByteSlice(bz)
+			if err != nil {
+				return
+			}
+			rv.SetString(string(bz2))
 			return
 		}
-		rv.SetString(str)
-		return
 
 	default:
-		panic(fmt.Sprintf("unknown field type %v", info.Type.Kind()))
+		return func([]byte, reflect.Value, FieldOptions, bool, *decodeState) (int, error) {
+			panic(fmt.Sprintf("unknown field type %v", info.Type.Kind()))
+		}
 	}
-
 }
 
 // CONTRACT: rv.CanAddr() is true.
-func (cdc *Codec) decodeReflectBinaryInterface(bz []byte, iinfo *TypeInfo, rv reflect.Value, fopts FieldOptions, bare bool) (n int, err error) {
+func (cdc *Codec) decodeReflectBinaryInterface(bz []byte, iinfo *TypeInfo, rv reflect.Value, fopts FieldOptions, bare bool, ds *decodeState) (n int, err error) {
 	if !rv.CanAddr() {
 		panic("rv not addressable")
 	}
@@ -318,18 +395,17 @@ func (cdc *Codec) decodeReflectBinaryInterface(bz []byte, iinfo *TypeInfo, rv re
 			fmt.Printf("(d) -> err: %v\n", err)
 		}()
 	}
-	if !rv.IsNil() {
-		// JAE: Heed this note, this is very tricky.
-		// I've forgotten the reason a second time,
-		// but I'm pretty sure that reason exists.
-		err = errors.New("decoding to a non-nil interface is not supported yet")
+	if err = ds.enter(); err != nil {
 		return
 	}
-
+	defer ds.leave()
 	if !bare {
 		// Read byte-length prefixed byteslice.
+		if err = ds.checkUvarint(bz); err != nil {
+			return
+		}
 		var buf, _n = []byte(nil), int(0)
-		buf, _n, err = DecodeByteSlice(bz)
+		buf, _n, err = DecodeByteSliceNoCopy(bz)
 		if slide(&bz, nil, _n) && err != nil {
 			return
 		}
@@ -340,7 +416,7 @@ func (cdc *Codec) decodeReflectBinaryInterface(bz []byte, iinfo *TypeInfo, rv re
 
 	// Consume disambiguation / prefix bytes.
 	disamb, hasDisamb, prefix, hasPrefix, _n, err := DecodeDisambPrefixBytes(bz)
-	if slide(&bz, &n, _n) && err != nil {
+	if err != nil {
 		return
 	}
 
@@ -352,38 +428,73 @@ func (cdc *Codec) decodeReflectBinaryInterface(bz []byte, iinfo *TypeInfo, rv re
 	case hasPrefix:
 		cinfo, err = cdc.getTypeInfoFromPrefixRlock(iinfo, prefix)
 	default:
-		err = errors.New("expected disambiguation or prefix bytes")
+		err = kindErrorf(ErrKindUnknownPrefix, "expected disambiguation or prefix bytes")
+	}
+	if err != nil && hasPrefix && allowsUnknownConcrete(iinfo) && errorKind(err) == ErrKindUnknownPrefix {
+		// Keep the value as is, see UnknownConcrete.
+		slide(&bz, &n, _n)
+		if err = ds.allocate(int64(len(bz))); err != nil {
+			return
+		}
+		var uc = UnknownConcrete{Disamb: disamb, HasDisamb: hasDisamb, Prefix: prefix}
+		if len(bz) > 0 {
+			uc.Value = append([]byte(nil), bz...)
+		}
+		slide(&bz, &n, len(bz))
+		rv.Set(reflect.ValueOf(uc))
+		return
 	}
 	if err != nil {
 		return
 	}
+	if err = checkConcreteAllowed(iinfo, cinfo, fopts); err != nil {
+		return
+	}
+	if cinfo.Type.Kind() == reflect.Interface {
+		// The allowed names of the field don't apply to the value of an
+		// interface-pointer registered as concrete, see RegisterConcrete.
+		fopts.Allow = nil
+	}
+	if ds.strict && hasDisamb != cdc.needDisamb(iinfo, cinfo) {
+		err = ds.nonCanonical(bz)
+		return
+	}
+	slide(&bz, &n, _n)
 
 	// Construct the concrete type.
-	var crv, irvSet = constructConcreteType(cinfo)
-	isKnownType := (cinfo.Type.Kind() != reflect.Map) && (cinfo.Type.Kind() != reflect.Func)
+	if err = ds.allocateType(cinfo.Type); err != nil {
+		return
+	}
+	// A non-nil rv pointing to the concrete type keeps its pointer, otherwise
+	// it is replaced.
+	var crv, irvSet, reused = reuseConcreteType(rv, cinfo)
+	isKnownType := cinfo.Type.Kind() != reflect.Func
 	if !isStructOrRepeatedStruct(cinfo) &&
 		!isPointerToStructOrToRepeatedStruct(crv, cinfo.Type) &&
 		len(bz) > 0 &&
 		(crv.Kind() != reflect.Interface) &&
 		isKnownType &&
 		fopts.BinFieldNum == 1 {
+		if err = ds.checkUvarint(bz); err != nil {
+			return
+		}
 		fnum, typ, nFnumTyp3, err := decodeFieldNumberAndTyp3(bz)
 		if err != nil {
 			return n, errors.Wrap(err, "could not decode field number and type")
 		}
 		if fnum != 1 {
-			return n, fmt.Errorf("expected field number: 1; got: %v", fnum)
+			return n, kindErrorf(ErrKindFieldOrder, "expected field number: 1; got: %v", fnum)
 		}
 		typWanted := typeToTyp3(cinfo.Type, FieldOptions{})
 		if typ != typWanted {
-			return n, fmt.Errorf("expected field type %v for # %v of %v, got %v",
+			return n, kindErrorf(ErrKindWrongTyp3, "expected field type %v for # %v of %v, got %v",
 				typWanted, fnum, cinfo.Type, typ)
 		}
 		slide(&bz, &n, nFnumTyp3)
 	}
 
 	// Decode into the concrete type.
-	_n, err = cdc.decodeReflectBinary(bz, cinfo, crv, fopts, true)
+	_n, err = cdc.decodeReflectBinary(bz, cinfo, crv, fopts, true, ds)
 	if slide(&bz, &n, _n) && err != nil {
 		rv.Set(irvSet) // Helps with debugging
 		return
@@ -401,12 +512,15 @@ func (cdc *Codec) decodeReflectBinaryInterface(bz []byte, iinfo *TypeInfo, rv re
 	// rv.Set() *after* the value was acquired.
 	// NOTE: rv.Set() should succeed because it was validated
 	// already during Register[Interface/Concrete].
+	if reused.IsValid() {
+		reused.Set(crv)
+	}
 	rv.Set(irvSet)
 	return
 }
 
 // CONTRACT: rv.CanAddr() is true.
-func (cdc *Codec) decodeReflectBinaryByteArray(bz []byte, info *TypeInfo, rv reflect.Value, fopts FieldOptions) (n int, err error) {
+func (cdc *Codec) decodeReflectBinaryByteArray(bz []byte, info *TypeInfo, rv reflect.Value, fopts FieldOptions, ds *decodeState) (n int, err error) {
 	if !rv.CanAddr() {
 		panic("rv not addressable")
 	}
@@ -422,12 +536,12 @@ func (cdc *Codec) decodeReflectBinaryByteArray(bz []byte, info *TypeInfo, rv ref
 	}
 	length := info.Type.Len()
 	if len(bz) < length {
-		return 0, fmt.Errorf("insufficient bytes to decode [%v]byte", length)
+		return 0, kindErrorf(ErrKindEOF, "insufficient bytes to decode [%v]byte", length)
 	}
 
 	// Read byte-length prefixed byteslice.
 	var byteslice, _n = []byte(nil), int(0)
-	byteslice, _n, err = DecodeByteSlice(bz)
+	byteslice, _n, err = DecodeByteSliceNoCopy(bz)
 	if slide(&bz, &n, _n) && err != nil {
 		return
 	}
@@ -438,13 +552,17 @@ func (cdc *Codec) decodeReflectBinaryByteArray(bz []byte, info *TypeInfo, rv ref
 	}
 
 	// Copy read byteslice to rv array.
-	reflect.Copy(rv, reflect.ValueOf(byteslice))
+	if ert == byteType {
+		reflect.Copy(rv, reflect.ValueOf(byteslice))
+	} else {
+		err = cdc.copyNamedBytes(rv, byteslice)
+	}
 	return
 }
 
 // CONTRACT: rv.CanAddr() is true.
 // NOTE: Keep the code structure similar to decodeReflectBinarySlice.
-func (cdc *Codec) decodeReflectBinaryArray(bz []byte, info *TypeInfo, rv reflect.Value, fopts FieldOptions, bare bool) (n int, err error) {
+func (cdc *Codec) decodeReflectBinaryArray(bz []byte, info *TypeInfo, plan *binaryPlan, rv reflect.Value, fopts FieldOptions, bare bool, ds *decodeState) (n int, err error) {
 	if !rv.CanAddr() {
 		panic("rv not addressable")
 	}
@@ -454,20 +572,24 @@ func (cdc *Codec) decodeReflectBinaryArray(bz []byte, info *TypeInfo, rv reflect
 			fmt.Printf("(d) -> err: %v\n", err)
 		}()
 	}
+	if err = ds.enter(); err != nil {
+		return
+	}
+	defer ds.leave()
 	ert := info.Type.Elem()
 	if ert.Kind() == reflect.Uint8 {
 		panic("should not happen")
 	}
 	length := info.Type.Len()
-	einfo, err := cdc.getTypeInfoWlock(ert)
-	if err != nil {
-		return
-	}
+	einfo := plan.elemInfo
 
 	if !bare {
 		// Read byte-length prefixed byteslice.
+		if err = ds.checkUvarint(bz); err != nil {
+			return
+		}
 		var buf, _n = []byte(nil), int(0)
-		buf, _n, err = DecodeByteSlice(bz)
+		buf, _n, err = DecodeByteSliceNoCopy(bz)
 		if slide(&bz, nil, _n) && err != nil {
 			return
 		}
@@ -484,11 +606,12 @@ func (cdc *Codec) decodeReflectBinaryArray(bz []byte, info *TypeInfo, rv reflect
 		// Read elements in packed form.
 		for i := 0; i < length; i++ {
 			var erv, _n = rv.Index(i), int(0)
-			_n, err = cdc.decodeReflectBinary(bz, einfo, erv, fopts, false)
+			ds.pushIndex(i)
+			_n, err = cdc.decodeReflectBinary(bz, einfo, erv, fopts, false, ds)
 			if slide(&bz, &n, _n) && err != nil {
-				err = fmt.Errorf("error reading array contents: %v", err)
 				return
 			}
+			ds.pop()
 			// Special case when reading default value, prefer nil.
 			if erv.Kind() == reflect.Ptr {
 				_, isDefault := isDefaultValue(erv)
@@ -510,15 +633,18 @@ func (cdc *Codec) decodeReflectBinaryArray(bz []byte, info *TypeInfo, rv reflect
 		// Read elements in unpacked form.
 		for i := 0; i < length; i++ {
 			// Read field key (number and type).
+			if err = ds.checkUvarint(bz); err != nil {
+				return
+			}
 			var fnum, typ, _n = uint32(0), Typ3(0x00), int(0)
 			fnum, typ, _n, err = decodeFieldNumberAndTyp3(bz)
 			// Validate field number and typ3.
 			if fnum != fopts.BinFieldNum {
-				err = errors.New(fmt.Sprintf("expected repeated field number %v, got %v", fopts.BinFieldNum, fnum))
+				err = kindErrorf(ErrKindFieldOrder, "expected repeated field number %v, got %v", fopts.BinFieldNum, fnum)
 				return
 			}
 			if typ != Typ3ByteLength {
-				err = errors.New(fmt.Sprintf("expected repeated field type %v, got %v", Typ3ByteLength, typ))
+				err = kindErrorf(ErrKindWrongTyp3, "expected repeated field type %v, got %v", Typ3ByteLength, typ)
 				return
 			}
 			if slide(&bz, &n, _n) && err != nil {
@@ -542,11 +668,16 @@ func (cdc *Codec) decodeReflectBinaryArray(bz []byte, info *TypeInfo, rv reflect
 			// In case of any inner lists in unpacked form.
 			efopts := fopts
 			efopts.BinFieldNum = 1
-			_n, err = cdc.decodeReflectBinary(bz, einfo, erv, efopts, false)
+			ds.pushIndex(i)
+			if plan.wrapElems {
+				_n, err = cdc.decodeReflectBinaryWrapped(bz, einfo, erv, efopts, ds)
+			} else {
+				_n, err = cdc.decodeReflectBinary(bz, einfo, erv, efopts, false, ds)
+			}
 			if slide(&bz, &n, _n) && err != nil {
-				err = fmt.Errorf("error reading array contents: %v", err)
 				return
 			}
+			ds.pop()
 		}
 		// Ensure that there are no more elements left,
 		// and no field number regression either.
@@ -558,7 +689,7 @@ func (cdc *Codec) decodeReflectBinaryArray(bz []byte, info *TypeInfo, rv reflect
 				return
 			}
 			if fnum <= fopts.BinFieldNum {
-				err = fmt.Errorf("unexpected field number %v after repeated field number %v", fnum, fopts.BinFieldNum)
+				err = kindErrorf(ErrKindFieldOrder, "unexpected field number %v after repeated field number %v", fnum, fopts.BinFieldNum)
 				return
 			}
 		}
@@ -566,8 +697,56 @@ func (cdc *Codec) decodeReflectBinaryArray(bz []byte, info *TypeInfo, rv reflect
 	return
 }
 
+// Reads a list in packed form from field 1 of a length-prefixed message, for
+// lists of lists, see encodeReflectBinaryWrapped.
+func (cdc *Codec) decodeReflectBinaryWrapped(bz []byte, info *TypeInfo, rv reflect.Value, fopts FieldOptions, ds *decodeState) (n int, err error) {
+	if err = ds.checkUvarint(bz); err != nil {
+		return
+	}
+	var buf, _n = []byte(nil), int(0)
+	buf, _n, err = DecodeByteSliceNoCopy(bz)
+	if slide(&bz, nil, _n) && err != nil {
+		return
+	}
+	// This is a trick for debuggability -- we slide on &n more later.
+	n += UvarintSize(uint64(len(buf)))
+	if len(buf) == 0 {
+		rv.Set(defaultValue(rv.Type()))
+		return
+	}
+	if err = ds.checkUvarint(buf); err != nil {
+		return
+	}
+	var fnum, typ = uint32(0), Typ3(0x00)
+	fnum, typ, _n, err = decodeFieldNumberAndTyp3(buf)
+	if slide(&buf, &n, _n) && err != nil {
+		return
+	}
+	if fnum != 1 {
+		err = kindErrorf(ErrKindFieldOrder, "expected field number 1 of nested list, got %v", fnum)
+		return
+	}
+	if typ != Typ3ByteLength {
+		err = kindErrorf(ErrKindWrongTyp3, "expected field type %v of nested list, got %v", Typ3ByteLength, typ)
+		return
+	}
+	_n, err = cdc.decodeReflectBinary(buf, info, rv, fopts, false, ds)
+	if _, isDefault := isDefaultValue(rv); ds.strict && err == nil && isDefault {
+		// An empty list is written as 0x00, see encodeReflectBinaryList.
+		err = ds.nonCanonical(bz)
+		return
+	}
+	if slide(&buf, &n, _n) && err != nil {
+		return
+	}
+	if len(buf) > 0 {
+		err = errors.New("bytes left over after reading nested list")
+	}
+	return
+}
+
 // CONTRACT: rv.CanAddr() is true.
-func (cdc *Codec) decodeReflectBinaryByteSlice(bz []byte, info *TypeInfo, rv reflect.Value, fopts FieldOptions) (n int, err error) {
+func (cdc *Codec) decodeReflectBinaryByteSlice(bz []byte, info *TypeInfo, rv reflect.Value, fopts FieldOptions, ds *decodeState) (n int, err error) {
 	if !rv.CanAddr() {
 		panic("rv not addressable")
 	}
@@ -589,23 +768,29 @@ func (cdc *Codec) decodeReflectBinaryByteSlice(bz []byte, info *TypeInfo, rv ref
 
 	// Read byte-length prefixed byteslice.
 	var byteslice, _n = []byte(nil), int(0)
-	byteslice, _n, err = DecodeByteSlice(bz)
+	byteslice, _n, err = ds.decodeByteSlice(bz)
 	if slide(&bz, &n, _n) && err != nil {
 		return
 	}
-	if len(byteslice) == 0 {
+	if len(byteslice) == 0 && info.Type == rawType {
+		// The field is present, see Raw.
+		rv.Set(reflect.ValueOf(Raw{}))
+	} else if len(byteslice) == 0 {
 		// Special case when length is 0.
 		// NOTE: We prefer nil slices.
 		rv.Set(info.ZeroValue)
-	} else {
+	} else if ert == byteType {
 		rv.Set(reflect.ValueOf(byteslice))
+	} else {
+		rv.Set(reflect.MakeSlice(info.Type, len(byteslice), len(byteslice)))
+		err = cdc.copyNamedBytes(rv, byteslice)
 	}
 	return
 }
 
 // CONTRACT: rv.CanAddr() is true.
 // NOTE: Keep the code structure similar to decodeReflectBinaryArray.
-func (cdc *Codec) decodeReflectBinarySlice(bz []byte, info *TypeInfo, rv reflect.Value, fopts FieldOptions, bare bool) (n int, err error) {
+func (cdc *Codec) decodeReflectBinarySlice(bz []byte, info *TypeInfo, plan *binaryPlan, rv reflect.Value, fopts FieldOptions, bare bool, ds *decodeState) (n int, err error) {
 	if !rv.CanAddr() {
 		panic("rv not addressable")
 	}
@@ -615,14 +800,15 @@ func (cdc *Codec) decodeReflectBinarySlice(bz []byte, info *TypeInfo, rv reflect
 			fmt.Printf("(d) -> err: %v\n", err)
 		}()
 	}
+	if err = ds.enter(); err != nil {
+		return
+	}
+	defer ds.leave()
 	ert := info.Type.Elem()
 	if ert.Kind() == reflect.Uint8 {
 		panic("should not happen")
 	}
-	einfo, err := cdc.getTypeInfoWlock(ert)
-	if err != nil {
-		return
-	}
+	einfo := plan.elemInfo
 
 	// Construct slice to collect decoded items to.
 	// NOTE: This is due to Proto3.  How to best optimize?
@@ -631,8 +817,11 @@ func (cdc *Codec) decodeReflectBinarySlice(bz []byte, info *TypeInfo, rv reflect
 
 	if !bare {
 		// Read byte-length prefixed byteslice.
+		if err = ds.checkUvarint(bz); err != nil {
+			return
+		}
 		var buf, _n = []byte(nil), int(0)
-		buf, _n, err = DecodeByteSlice(bz)
+		buf, _n, err = DecodeByteSliceNoCopy(bz)
 		if slide(&bz, nil, _n) && err != nil {
 			return
 		}
@@ -651,12 +840,16 @@ func (cdc *Codec) decodeReflectBinarySlice(bz []byte, info *TypeInfo, rv reflect
 			if len(bz) == 0 {
 				break
 			}
+			if err = ds.allocateElem(srv.Len(), ert); err != nil {
+				return
+			}
 			erv, _n := reflect.New(ert).Elem(), int(0)
-			_n, err = cdc.decodeReflectBinary(bz, einfo, erv, fopts, false)
+			ds.pushIndex(srv.Len())
+			_n, err = cdc.decodeReflectBinary(bz, einfo, erv, fopts, false, ds)
 			if slide(&bz, &n, _n) && err != nil {
-				err = fmt.Errorf("error reading array contents: %v", err)
 				return
 			}
+			ds.pop()
 			// Special case when reading default value, prefer nil.
 			if ert.Kind() == reflect.Ptr {
 				_, isDefault := isDefaultValue(erv)
@@ -678,24 +871,30 @@ func (cdc *Codec) decodeReflectBinarySlice(bz []byte, info *TypeInfo, rv reflect
 				break
 			}
 			// Read field key (number and type).
+			if err = ds.checkUvarint(bz); err != nil {
+				return
+			}
 			var fnum, typ, _n = uint32(0), Typ3(0x00), int(0)
 			fnum, typ, _n, err = decodeFieldNumberAndTyp3(bz)
 			// Validate field number and typ3.
 			if fnum < fopts.BinFieldNum {
-				err = errors.New(fmt.Sprintf("expected repeated field number %v or greater, got %v", fopts.BinFieldNum, fnum))
+				err = kindErrorf(ErrKindFieldOrder, "expected repeated field number %v or greater, got %v", fopts.BinFieldNum, fnum)
 				return
 			}
 			if fnum > fopts.BinFieldNum {
 				break
 			}
 			if typ != Typ3ByteLength {
-				err = errors.New(fmt.Sprintf("expected repeated field type %v, got %v", Typ3ByteLength, typ))
+				err = kindErrorf(ErrKindWrongTyp3, "expected repeated field type %v, got %v", Typ3ByteLength, typ)
 				return
 			}
 			if slide(&bz, &n, _n) && err != nil {
 				return
 			}
 			// Decode the next ByteLength bytes into erv.
+			if err = ds.allocateElem(srv.Len(), ert); err != nil {
+				return
+			}
 			erv, _n := reflect.New(ert).Elem(), int(0)
 			// Special case if:
 			//  * next ByteLength bytes are 0x00, and
@@ -714,11 +913,16 @@ func (cdc *Codec) decodeReflectBinarySlice(bz []byte, info *TypeInfo, rv reflect
 			// In case of any inner lists in unpacked form.
 			efopts := fopts
 			efopts.BinFieldNum = 1
-			_n, err = cdc.decodeReflectBinary(bz, einfo, erv, efopts, false)
+			ds.pushIndex(srv.Len())
+			if plan.wrapElems {
+				_n, err = cdc.decodeReflectBinaryWrapped(bz, einfo, erv, efopts, ds)
+			} else {
+				_n, err = cdc.decodeReflectBinary(bz, einfo, erv, efopts, false, ds)
+			}
 			if slide(&bz, &n, _n) && err != nil {
-				err = fmt.Errorf("error reading array contents: %v", err)
 				return
 			}
+			ds.pop()
 			srv = reflect.Append(srv, erv)
 		}
 	}
@@ -727,7 +931,160 @@ func (cdc *Codec) decodeReflectBinarySlice(bz []byte, info *TypeInfo, rv reflect
 }
 
 // CONTRACT: rv.CanAddr() is true.
-func (cdc *Codec) decodeReflectBinaryStruct(bz []byte, info *TypeInfo, rv reflect.Value, _ FieldOptions, bare bool) (n int, err error) {
+func (cdc *Codec) decodeReflectBinaryMap(bz []byte, info *TypeInfo, plan *binaryPlan, rv reflect.Value, fopts FieldOptions, bare bool, ds *decodeState) (n int, err error) {
+	if !rv.CanAddr() {
+		panic("rv not addressable")
+	}
+	if printLog {
+		fmt.Println("(d) decodeReflectBinaryMap")
+		defer func() {
+			fmt.Printf("(d) -> err: %v\n", err)
+		}()
+	}
+	if err = ds.enter(); err != nil {
+		return
+	}
+	defer ds.leave()
+	kinfo, vinfo := plan.keyInfo, plan.elemInfo
+	kopts, vopts := mapEntryOptions(fopts)
+
+	if !bare {
+		// Read byte-length prefixed byteslice.
+		if err = ds.checkUvarint(bz); err != nil {
+			return
+		}
+		var buf, _n = []byte(nil), int(0)
+		buf, _n, err = DecodeByteSliceNoCopy(bz)
+		if slide(&bz, nil, _n) && err != nil {
+			return
+		}
+		// This is a trick for debuggability -- we slide on &n more later.
+		n += UvarintSize(uint64(len(buf)))
+		bz = buf
+	}
+
+	// Read entries in unpacked form.
+	var mrv = reflect.Zero(info.Type)
+	var lastKeyBz []byte
+	for len(bz) > 0 {
+		// Read field key (number and type).
+		if err = ds.checkUvarint(bz); err != nil {
+			return
+		}
+		var fnum, typ, _n = uint32(0), Typ3(0x00), int(0)
+		fnum, typ, _n, err = decodeFieldNumberAndTyp3(bz)
+		if err != nil {
+			return
+		}
+		// Validate field number and typ3.
+		if fnum < fopts.BinFieldNum {
+			err = kindErrorf(ErrKindFieldOrder, "expected repeated field number %v or greater, got %v", fopts.BinFieldNum, fnum)
+			return
+		}
+		if fnum > fopts.BinFieldNum {
+			break
+		}
+		if typ != Typ3ByteLength {
+			err = kindErrorf(ErrKindWrongTyp3, "expected repeated field type %v, got %v", Typ3ByteLength, typ)
+			return
+		}
+		slide(&bz, &n, _n)
+		// Read the entry.
+		if err = ds.checkUvarint(bz); err != nil {
+			return
+		}
+		var ebz []byte
+		ebz, _n, err = DecodeByteSliceNoCopy(bz)
+		if slide(&bz, &n, _n) && err != nil {
+			return
+		}
+		if err = ds.allocateElem(mrv.Len(), info.Type.Key()); err != nil {
+			return
+		}
+		if err = ds.allocateType(info.Type.Elem()); err != nil {
+			return
+		}
+		krv, vrv := reflect.New(info.Type.Key()).Elem(), reflect.New(info.Type.Elem()).Elem()
+		_n, err = cdc.decodeReflectBinaryMapEntryField(ebz, kinfo, krv, kopts, ds)
+		if err != nil {
+			return
+		}
+		// Keys must be strictly increasing, see encodeReflectBinaryMap.
+		var keyBz = ebz[:_n]
+		if mrv.IsNil() {
+			mrv = reflect.MakeMap(info.Type)
+		} else if bytes.Compare(lastKeyBz, keyBz) >= 0 {
+			err = kindErrorf(ErrKindFieldOrder, "map keys must be unique and sorted by their encoding")
+			return
+		}
+		lastKeyBz = keyBz
+		ds.pushKey(krv)
+		_n, err = cdc.decodeReflectBinaryMapEntryField(ebz[_n:], vinfo, vrv, vopts, ds)
+		if err != nil {
+			return
+		}
+		ds.pop()
+		if len(keyBz)+_n != len(ebz) {
+			err = errors.New("unexpected bytes left over after reading map entry")
+			return
+		}
+		mrv.SetMapIndex(krv, vrv)
+	}
+	rv.Set(mrv)
+	return
+}
+
+// Reads the key or value of a map entry, just like a struct field.  If the
+// field is absent, rv is set to the default value.
+// CONTRACT: rv.CanAddr() is true.
+func (cdc *Codec) decodeReflectBinaryMapEntryField(bz []byte, info *TypeInfo, rv reflect.Value, fopts FieldOptions, ds *decodeState) (n int, err error) {
+	if isUnpackedList(rv.Type(), fopts) {
+		return cdc.decodeReflectBinary(bz, info, rv, fopts, true, ds)
+	}
+	if len(bz) == 0 {
+		rv.Set(defaultValue(rv.Type()))
+		if ds.strict {
+			err = cdc.checkMapEntryFieldOmitted(bz, info, rv, fopts, ds)
+		}
+		return
+	}
+	var fnum, typ, _n = uint32(0), Typ3(0x00), int(0)
+	fnum, typ, _n, err = decodeFieldNumberAndTyp3(bz)
+	if err != nil {
+		return
+	}
+	if fnum > fopts.BinFieldNum {
+		rv.Set(defaultValue(rv.Type()))
+		if ds.strict {
+			err = cdc.checkMapEntryFieldOmitted(bz, info, rv, fopts, ds)
+		}
+		return // Do not slide, the caller will read it again.
+	}
+	if fnum < fopts.BinFieldNum {
+		err = kindErrorf(ErrKindFieldOrder, "expected map entry field # %v, got %v", fopts.BinFieldNum, fnum)
+		return
+	}
+	typWanted := typeToTyp3(info.Type, fopts)
+	if typ != typWanted {
+		err = kindErrorf(ErrKindWrongTyp3, "expected field type %v for map entry field # %v, got %v",
+			typWanted, fnum, typ)
+		return
+	}
+	if err = ds.checkUvarint(bz); err != nil {
+		return
+	}
+	var key = bz
+	slide(&bz, &n, _n)
+	_n, err = cdc.decodeReflectBinary(bz, info, rv, fopts, false, ds)
+	if err == nil {
+		err = ds.checkFieldWritten(key, bz[:_n], rv, false)
+	}
+	slide(&bz, &n, _n)
+	return
+}
+
+// CONTRACT: rv.CanAddr() is true.
+func (cdc *Codec) decodeReflectBinaryStruct(bz []byte, info *TypeInfo, plan *binaryPlan, rv reflect.Value, fopts FieldOptions, bare bool, ds *decodeState) (n int, err error) {
 	if !rv.CanAddr() {
 		panic("rv not addressable")
 	}
@@ -737,6 +1094,10 @@ func (cdc *Codec) decodeReflectBinaryStruct(bz []byte, info *TypeInfo, rv reflec
 			fmt.Printf("(d) -> err: %v\n", err)
 		}()
 	}
+	if err = ds.enter(); err != nil {
+		return
+	}
+	defer ds.leave()
 	_n := 0 // nolint: ineffassign
 
 	// NOTE: The "Struct" typ3 doesn't get read here.
@@ -744,8 +1105,11 @@ func (cdc *Codec) decodeReflectBinaryStruct(bz []byte, info *TypeInfo, rv reflec
 
 	if !bare {
 		// Read byte-length prefixed byteslice.
+		if err = ds.checkUvarint(bz); err != nil {
+			return
+		}
 		var buf, _n = []byte(nil), int(0)
-		buf, _n, err = DecodeByteSlice(bz)
+		buf, _n, err = DecodeByteSliceNoCopy(bz)
 		if slide(&bz, nil, _n) && err != nil {
 			return
 		}
@@ -754,110 +1118,253 @@ func (cdc *Codec) decodeReflectBinaryStruct(bz []byte, info *TypeInfo, rv reflec
 		bz = buf
 	}
 
+	// Use generated code if any, see cmd/aminogen.  Generated code doesn't
+	// enforce DecodeLimits or canonical encoding, and on failure we decode
+	// again below for a precise DecodeError.
+	if info.IsAminoBinaryUnmarshaler && !ds.noGenerated && !ds.strict && ds.limits == (DecodeLimits{}) && rv.CanInterface() {
+		err = cdc.decodeGeneratedBinary(bz, info, rv)
+		if _, ok := err.(generatedMismatchErr); ok || err == nil {
+			n += len(bz)
+			return
+		}
+	}
+
 	switch info.Type {
 
 	case timeType:
 		// Special case: time.Time
-		var t time.Time
+		var t, tbz = time.Time{}, bz
 		t, _n, err = DecodeTime(bz)
 		if slide(&bz, &n, _n) && err != nil {
 			return
 		}
+		if ds.strict {
+			// Compare with the length of the canonical encoding, which
+			// has no default-valued fields and minimal varints.
+			var size int
+			if size, err = timeSize(t); err != nil {
+				return
+			}
+			if size != _n || len(bz) > 0 {
+				err = ds.nonCanonical(tbz)
+				return
+			}
+		}
 		rv.Set(reflect.ValueOf(t))
 
 	default:
 		// Track the last seen field number.
 		var lastFieldNum uint32
+		// Collect unknown fields if the struct has an UnknownFields field.
+		var keepUnknown = info.UnknownFieldsIndex >= 0
+		var unknown UnknownFields
 		// Read each field.
-		for _, field := range info.Fields {
-			// Get field rv and info.
-			var frv = rv.Field(field.Index)
-			var finfo *TypeInfo
-			finfo, err = cdc.getTypeInfoWlock(field.Type)
-			if err != nil {
+		for i := range info.Fields {
+			var field = &info.Fields[i]
+
+			// Skip unknown fields that precede this one,
+			// e.g. fields that have since been reserved.
+			_n, err = consumeFieldsBefore(bz, field.BinFieldNum, &lastFieldNum)
+			if keepUnknown {
+				unknown = append(unknown, bz[:_n]...)
+			} else if ds.strict && _n > 0 && err == nil {
+				err = ds.nonCanonical(bz)
+			}
+			if slide(&bz, &n, _n) && err != nil {
 				return
 			}
 
-			// We're done if we've consumed all the bytes.
-			if len(bz) == 0 {
-				frv.Set(defaultValue(frv.Type()))
-				continue
+			_n, err = cdc.decodeReflectBinaryField(bz, info, field, &plan.fields[i], rv.Field(field.Index), fopts.WriteEmpty, &lastFieldNum, ds)
+			if slide(&bz, &n, _n) && err != nil {
+				return
 			}
+		}
 
-			if field.UnpackedList {
-				// This is a list that was encoded unpacked, e.g.
-				// with repeated field entries for each list item.
-				_n, err = cdc.decodeReflectBinary(bz, finfo, frv, field.FieldOptions, true)
-				if slide(&bz, &n, _n) && err != nil {
-					return
-				}
-			} else {
-				// Read field key (number and type).
-				var fnum, typ = uint32(0), Typ3(0x00)
-				fnum, typ, _n, err = decodeFieldNumberAndTyp3(bz)
-				if field.BinFieldNum < fnum {
-					// Set zero field value.
-					frv.Set(defaultValue(frv.Type()))
-					continue
-					// Do not slide, we will read it again.
-				}
-				if fnum <= lastFieldNum {
-					err = fmt.Errorf("encountered fieldnNum: %v, but we have already seen fnum: %v\nbytes:%X",
-						fnum, lastFieldNum, bz)
-					return
-				}
-				lastFieldNum = fnum
-				if slide(&bz, &n, _n) && err != nil {
-					return
-				}
+		// Consume any remaining fields.
+		_n, err = consumeFieldsBefore(bz, maxFieldNum+1, &lastFieldNum)
+		if keepUnknown {
+			unknown = append(unknown, bz[:_n]...)
+		} else if ds.strict && _n > 0 && err == nil {
+			err = ds.nonCanonical(bz)
+		}
+		if slide(&bz, &n, _n) && err != nil {
+			return
+		}
+		if keepUnknown {
+			rv.Field(info.UnknownFieldsIndex).Set(reflect.ValueOf(unknown))
+		}
+	}
+	return
+}
 
-				// Validate fnum and typ.
-				// NOTE: In the future, we'll support upgradeability.
-				// So in the future, this may not match,
-				// so we will need to remove this sanity check.
-				if field.BinFieldNum != fnum {
-					err = errors.New(fmt.Sprintf("expected field # %v of %v, got %v",
-						field.BinFieldNum, info.Type, fnum))
-					return
-				}
-				typWanted := typeToTyp3(finfo.Type, field.FieldOptions)
-				if typ != typWanted {
-					err = errors.New(fmt.Sprintf("expected field type %v for # %v of %v, got %v",
-						typWanted, fnum, info.Type, typ))
-					return
-				}
-				// Decode field into frv.
-				_n, err = cdc.decodeReflectBinary(bz, finfo, frv, field.FieldOptions, false)
-				if slide(&bz, &n, _n) && err != nil {
-					return
-				}
-			}
+// Decodes the field of a struct of type info at the start of bz, or sets it to
+// the default value if bz is empty or starts with a later field.  writeEmpty
+// is the `amino:"write_empty"` option of the struct, see
+// encodeReflectBinaryField.
+func (cdc *Codec) decodeReflectBinaryField(bz []byte, info *TypeInfo, field *FieldInfo, fplan *fieldPlan, frv reflect.Value, writeEmpty bool, lastFieldNum *uint32, ds *decodeState) (n int, err error) {
+	var _n int
+	var finfo = fplan.info
+
+	// We're done if we've consumed all the bytes.
+	if len(bz) == 0 {
+		frv.Set(defaultValue(frv.Type()))
+		if ds.strict {
+			err = cdc.checkFieldOmitted(bz, field, fplan, frv, writeEmpty, ds)
 		}
+		return
+	}
 
-		// Consume any remaining fields.
-		var _n, fnum = 0, uint32(0)
-		var typ3 Typ3
-		for len(bz) > 0 {
-			fnum, typ3, _n, err = decodeFieldNumberAndTyp3(bz)
-			if slide(&bz, &n, _n) && err != nil {
-				return
-			}
-			if fnum <= lastFieldNum {
-				err = fmt.Errorf("encountered fieldnNum: %v, but we have already seen fnum: %v\nbytes:%X",
-					fnum, lastFieldNum, bz)
-				return
-			}
-			lastFieldNum = fnum
+	if field.UnpackedList {
+		// This is a list that was encoded unpacked, e.g.
+		// with repeated field entries for each list item.
+		ds.pushField(field)
+		_n, err = cdc.decodeReflectBinary(bz, finfo, frv, field.FieldOptions, true, ds)
+		if slide(&bz, &n, _n) && err != nil {
+			return
+		}
+		ds.pop()
+		if _n > 0 {
+			*lastFieldNum = field.BinFieldNum
+		}
+		return
+	}
 
-			_n, err = consumeAny(typ3, bz)
-			if slide(&bz, &n, _n) && err != nil {
-				return
-			}
+	// Read field key (number and type).
+	var fnum, typ = uint32(0), Typ3(0x00)
+	if len(fplan.key) > 0 && bytes.HasPrefix(bz, fplan.key) {
+		// Fast path for the expected key.
+		fnum, typ, _n = field.BinFieldNum, typeToTyp3(finfo.Type, field.FieldOptions), len(fplan.key)
+	} else {
+		if err = ds.checkUvarint(bz); err != nil {
+			return
+		}
+		fnum, typ, _n, err = decodeFieldNumberAndTyp3(bz)
+		if err != nil {
+			return
 		}
 	}
+	if field.BinFieldNum < fnum {
+		// Set zero field value.
+		frv.Set(defaultValue(frv.Type()))
+		if ds.strict {
+			err = cdc.checkFieldOmitted(bz, field, fplan, frv, writeEmpty, ds)
+		}
+		return
+		// Do not slide, we will read it again.
+	}
+	if fnum <= *lastFieldNum {
+		err = kindErrorf(ErrKindFieldOrder, "encountered fieldNum: %v, but we have already seen fnum: %v",
+			fnum, *lastFieldNum)
+		return
+	}
+	*lastFieldNum = fnum
+	ds.pushField(field)
+
+	// Validate fnum and typ.
+	// NOTE: In the future, we'll support upgradeability.
+	// So in the future, this may not match,
+	// so we will need to remove this sanity check.
+	if field.BinFieldNum != fnum {
+		err = kindErrorf(ErrKindFieldOrder, "expected field # %v of %v, got %v",
+			field.BinFieldNum, info.Type, fnum)
+		return
+	}
+	typWanted := typeToTyp3(finfo.Type, field.FieldOptions)
+	if typ != typWanted {
+		err = kindErrorf(ErrKindWrongTyp3, "expected field type %v for # %v of %v, got %v",
+			typWanted, fnum, info.Type, typ)
+		return
+	}
+	var key = bz
+	slide(&bz, &n, _n)
+	// Decode field into frv.
+	_n, err = cdc.decodeReflectBinary(bz, finfo, frv, field.FieldOptions, false, ds)
+	if err == nil {
+		err = ds.checkFieldWritten(key, bz[:_n], frv, writeEmpty)
+	}
+	if slide(&bz, &n, _n) && err != nil {
+		return
+	}
+	ds.pop()
 	return
 }
 
+//----------------------------------------
+// Strict decoding, see UnmarshalBinaryBareStrict
+
+// Returns a NonCanonicalErr at bz if ds is strict.
+func (ds *decodeState) nonCanonical(bz []byte) error {
+	if !ds.strict {
+		return nil
+	}
+	return NonCanonicalErr{Offset: ds.offset(bz)}
+}
+
+// Checks that the uvarint at the start of bz, e.g. a field key or a length
+// prefix, is minimal if ds is strict.  A uvarint is not minimal if its last
+// byte is 0x00 (other than the first).  Malformed uvarints are left to the
+// decoder.
+func (ds *decodeState) checkUvarint(bz []byte) error {
+	if !ds.strict {
+		return nil
+	}
+	for i := 0; i < len(bz) && i < binary.MaxVarintLen64; i++ {
+		if bz[i]&0x80 == 0 {
+			if i > 0 && bz[i] == 0x00 {
+				return ds.nonCanonical(bz)
+			}
+			break
+		}
+	}
+	return nil
+}
+
+// Checks that the field with the given key and value bytes, decoded into rv,
+// would be written by the encoder if ds is strict, i.e. that it doesn't have
+// the default value and that its value isn't a single 0x00 byte (e.g. an
+// empty struct), unless written anyway, see encodeReflectBinaryField and
+// writeFieldIfNotEmpty.
+func (ds *decodeState) checkFieldWritten(key, value []byte, rv reflect.Value, writeEmpty bool) error {
+	if !ds.strict || writeEmpty {
+		return nil
+	}
+	if _, isDefault := isDefaultValue(rv); isDefault {
+		return ds.nonCanonical(key)
+	}
+	if rv.Kind() != reflect.Ptr && rv.Type() != rawType && len(value) == 1 && value[0] == 0x00 {
+		return ds.nonCanonical(key)
+	}
+	return nil
+}
+
+// Checks that the field, which is absent at bz and so was set to its default
+// value frv, would not be written by the encoder, e.g. arrays are always
+// written.  Pointers are only written if non-nil, but pointers to time are
+// decoded as 1970 (see defaultValue), so they are not checked.
+func (cdc *Codec) checkFieldOmitted(bz []byte, field *FieldInfo, fplan *fieldPlan, frv reflect.Value, writeEmpty bool, ds *decodeState) error {
+	if frv.Kind() == reflect.Ptr && !writeEmpty {
+		return nil
+	}
+	size, err := cdc.sizeReflectBinaryField(field, fplan, frv, FieldOptions{WriteEmpty: writeEmpty}, nil)
+	if err == nil && size > 0 {
+		err = ds.nonCanonical(bz)
+	}
+	return err
+}
+
+// Like checkFieldOmitted, but for the key or value of a map entry.
+func (cdc *Codec) checkMapEntryFieldOmitted(bz []byte, info *TypeInfo, rv reflect.Value, fopts FieldOptions, ds *decodeState) error {
+	if rv.Kind() == reflect.Ptr {
+		return nil
+	}
+	keySize := fieldKeySize(fopts.BinFieldNum, typeToTyp3(info.Type, fopts))
+	size, err := cdc.sizeReflectBinaryMapEntryField(keySize, info, rv, fopts, nil)
+	if err == nil && size > 0 {
+		err = ds.nonCanonical(bz)
+	}
+	return err
+}
+
 //----------------------------------------
 // consume* for skipping struct fields
 
@@ -870,11 +1377,11 @@ func consumeAny(typ3 Typ3, bz []byte) (n int, err error) {
 	case Typ38Byte:
 		_, _n, err = DecodeInt64(bz)
 	case Typ3ByteLength:
-		_, _n, err = DecodeByteSlice(bz)
+		_, _n, err = DecodeByteSliceNoCopy(bz)
 	case Typ3_4Byte:
 		_, _n, err = DecodeInt32(bz)
 	default:
-		err = fmt.Errorf("invalid typ3 bytes %v", typ3)
+		err = kindErrorf(ErrKindWrongTyp3, "invalid typ3 bytes %v", typ3)
 		return
 	}
 	if err != nil {
@@ -885,18 +1392,64 @@ func consumeAny(typ3 Typ3, bz []byte) (n int, err error) {
 	return
 }
 
+// Consume all fields with field numbers less than num, updating lastFieldNum.
+// Stops at (and does not consume) the first field numbered num or greater.
+// Field numbers must not decrease, and only the skipped fields may repeat.
+func consumeFieldsBefore(bz []byte, num uint32, lastFieldNum *uint32) (n int, err error) {
+	var _n, fnum = 0, uint32(0)
+	var typ3 Typ3
+	for len(bz) > 0 {
+		fnum, typ3, _n, err = decodeFieldNumberAndTyp3(bz)
+		if err != nil {
+			return
+		}
+		if num <= fnum {
+			return // Do not slide, the caller will read it again.
+		}
+		// Unknown fields may be repeated, e.g. unpacked lists.
+		if fnum < *lastFieldNum || (fnum == *lastFieldNum && n == 0) {
+			err = kindErrorf(ErrKindFieldOrder, "encountered fieldNum: %v, but we have already seen fnum: %v",
+				fnum, *lastFieldNum)
+			return
+		}
+		*lastFieldNum = fnum
+		slide(&bz, &n, _n)
+
+		_n, err = consumeAny(typ3, bz)
+		if slide(&bz, &n, _n) && err != nil {
+			return
+		}
+	}
+	return
+}
+
 //----------------------------------------
 
+// Consumes the prefix bytes of the registered concrete type info, which bz
+// must start with.
+func (ds *decodeState) consumePrefix(bz []byte, info *TypeInfo) (n int, err error) {
+	pb := info.Prefix.Bytes()
+	if len(bz) < 4 {
+		err = kindErrorf(ErrKindEOF, "expected prefix bytes %X of registered concrete type, got EOF", pb)
+	} else if !bytes.Equal(bz[:4], pb) {
+		err = kindErrorf(ErrKindUnknownPrefix, "expected prefix bytes %X of registered concrete type, got %X", pb, bz[:4])
+	}
+	if err != nil {
+		return 0, ds.binaryError(err, info.Type, ds.offset(bz))
+	}
+	return 4, nil
+}
+
 func DecodeDisambPrefixBytes(bz []byte) (db DisambBytes, hasDb bool, pb PrefixBytes, hasPb bool, n int, err error) {
 	// Validate
 	if len(bz) < 4 {
-		err = errors.New("while reading prefix bytes, EOF was encountered")
+		err = kindErrorf(ErrKindEOF, "while reading prefix bytes, EOF was encountered")
 		return // hasPb = false
 	}
 	if bz[0] == 0x00 { // Disfix
 		// Validate
 		if len(bz) < 8 {
-			err = errors.New("while reading prefix bytes, EOF was encountered")
+			err = kindErrorf(ErrKindEOF, "while reading prefix bytes, EOF was encountered")
 			return // hasPb = false
 		}
 		copy(db[0:3], bz[1:4])
@@ -932,8 +1485,8 @@ func decodeFieldNumberAndTyp3(bz []byte) (num uint32, typ Typ3, n int, err error
 	// Decode num.
 	var num64 uint64
 	num64 = value64 >> 3
-	if num64 > (1<<29 - 1) {
-		err = fmt.Errorf("invalid field num %v", num64)
+	if num64 > maxFieldNum {
+		err = kindErrorf(ErrKindOverflow, "invalid field num %v", num64)
 		return
 	}
 	num = uint32(num64)
@@ -2,16 +2,116 @@ package amino
 
 import (
 	"bytes"
-	"encoding/binary"
 	"errors"
 	"fmt"
 	"io"
 	"reflect"
+	"sync"
 	"time"
 
 	"github.com/davecgh/go-spew/spew"
 )
 
+//----------------------------------------
+// Scratch buffers
+
+// Buffers larger than this are left to the garbage collector, so that one
+// large value doesn't pin its memory in the pool.
+const maxPooledBufferSize = 64 * 1024
+
+var bufferPool = sync.Pool{
+	New: func() interface{} { return new(bytes.Buffer) },
+}
+
+// Returns an empty scratch buffer.  Once its bytes are no longer used, give
+// it back with putBuffer.
+func getBuffer() *bytes.Buffer {
+	buf := bufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	return buf
+}
+
+func putBuffer(buf *bytes.Buffer) {
+	if buf.Cap() > maxPooledBufferSize {
+		return
+	}
+	bufferPool.Put(buf)
+}
+
+//----------------------------------------
+// encodeState
+
+// encodeState carries what sizing a value computes over to encoding it, so
+// that each value is sized only once per encoding, and MarshalAmino is only
+// called once per repr value.  The size* functions append an entry for each
+// interface, list, map, struct, repr value and field, in the order in which
+// the encodeReflectBinary* functions then take them, so the two must be kept
+// in sync.
+type encodeState struct {
+	sized []sizedValue
+	next  int // Index of the entry to take next.
+}
+
+// What sizing a value computed.
+type sizedValue struct {
+	size    int           // Of the bare encoding, or of the whole field.
+	end     int           // Index of the entry after those of the children.
+	info    *TypeInfo     // Of rv, for fields.
+	rv      reflect.Value // The value to encode, for repr types and fields.
+	entries []mapEntry    // Of maps, sorted.
+}
+
+// A map entry, with its key already encoded as field 1.
+type mapEntry struct {
+	key   []byte
+	value reflect.Value
+	size  int // Of the value encoded as field 2.
+}
+
+func newEncodeState() *encodeState {
+	return &encodeState{}
+}
+
+func (es *encodeState) reset() {
+	es.sized = es.sized[:0]
+	es.next = 0
+}
+
+// Appends an entry for the value about to be sized, and returns its index for
+// set.  If es is nil, the value is only sized, and nothing is recorded.
+func (es *encodeState) push() int {
+	if es == nil {
+		return -1
+	}
+	es.sized = append(es.sized, sizedValue{})
+	return len(es.sized) - 1
+}
+
+// Sets entry i, once the value and its children are sized.
+func (es *encodeState) set(i int, sv sizedValue) {
+	if i < 0 {
+		return
+	}
+	sv.end = len(es.sized)
+	es.sized[i] = sv
+}
+
+// Returns the entry of the value about to be encoded.
+func (es *encodeState) take() sizedValue {
+	if es.next >= len(es.sized) {
+		panic("should not happen: value was not sized")
+	}
+	sv := es.sized[es.next]
+	es.next++
+	return sv
+}
+
+// Skips the entries of the children of sv, the value just taken, when it is
+// not written after all.
+func (es *encodeState) skip(sv sizedValue) {
+	es.next = sv.end
+}
+
 //----------------------------------------
 // cdc.encodeReflectBinary
 
@@ -22,7 +122,8 @@ import (
 // The following contracts apply to all similar encode methods.
 // CONTRACT: rv is not a pointer
 // CONTRACT: rv is valid.
-func (cdc *Codec) encodeReflectBinary(w io.Writer, info *TypeInfo, rv reflect.Value, fopts FieldOptions, bare bool) (err error) {
+// CONTRACT: rv was sized with es, see encodeState.
+func (cdc *Codec) encodeReflectBinary(w io.Writer, info *TypeInfo, rv reflect.Value, fopts FieldOptions, bare bool, es *encodeState) (err error) {
 	if rv.Kind() == reflect.Ptr {
 		panic("not allowed to be called with a reflect.Ptr")
 	}
@@ -37,140 +138,198 @@ func (cdc *Codec) encodeReflectBinary(w io.Writer, info *TypeInfo, rv reflect.Va
 		}()
 	}
 
-	// Handle override if rv implements json.Marshaler.
+	var plan *binaryPlan
+	plan, err = cdc.getBinaryPlan(info)
+	if err != nil {
+		return
+	}
+	err = plan.encode(w, rv, fopts, bare, es)
+	return
+}
+
+// Returns the function that encodes values of type info, see binaryPlan.
+func (cdc *Codec) newBinaryEncoder(info *TypeInfo, plan *binaryPlan) binaryEncoder {
+
+	// Handle override if rv implements MarshalAmino.
 	if info.IsAminoMarshaler {
-		// First, encode rv into repr instance.
-		var rrv, rinfo = reflect.Value{}, (*TypeInfo)(nil)
-		rrv, err = toReprObject(rv)
-		if err != nil {
-			return
-		}
-		rinfo, err = cdc.getTypeInfoWlock(info.AminoMarshalReprType)
-		if err != nil {
-			return
+		return func(w io.Writer, rv reflect.Value, fopts FieldOptions, bare bool, es *encodeState) error {
+			// Encode the repr instance, made when sizing rv.
+			sv := es.take()
+			return cdc.encodeReflectBinary(w, plan.reprInfo, sv.rv, fopts, bare, es)
 		}
-		// Then, encode the repr instance.
-		err = cdc.encodeReflectBinary(w, rinfo, rrv, fopts, bare)
-		return
 	}
 
+	var encode binaryEncoder
 	switch info.Type.Kind() {
 
 	//----------------------------------------
 	// Complex
 
 	case reflect.Interface:
-		err = cdc.encodeReflectBinaryInterface(w, info, rv, fopts, bare)
+		encode = func(w io.Writer, rv reflect.Value, fopts FieldOptions, bare bool, es *encodeState) error {
+			return cdc.encodeReflectBinaryInterface(w, info, rv, fopts, bare, es)
+		}
 
 	case reflect.Array:
 		if info.Type.Elem().Kind() == reflect.Uint8 {
-			err = cdc.encodeReflectBinaryByteArray(w, info, rv, fopts)
+			encode = func(w io.Writer, rv reflect.Value, fopts FieldOptions, _ bool, _ *encodeState) error {
+				return cdc.encodeReflectBinaryByteArray(w, info, rv, fopts)
+			}
 		} else {
-			err = cdc.encodeReflectBinaryList(w, info, rv, fopts, bare)
+			encode = func(w io.Writer, rv reflect.Value, fopts FieldOptions, bare bool, es *encodeState) error {
+				return cdc.encodeReflectBinaryList(w, info, plan, rv, fopts, bare, es)
+			}
 		}
 
 	case reflect.Slice:
 		if info.Type.Elem().Kind() == reflect.Uint8 {
-			err = cdc.encodeReflectBinaryByteSlice(w, info, rv, fopts)
+			encode = func(w io.Writer, rv reflect.Value, fopts FieldOptions, _ bool, _ *encodeState) error {
+				return cdc.encodeReflectBinaryByteSlice(w, info, rv, fopts)
+			}
 		} else {
-			err = cdc.encodeReflectBinaryList(w, info, rv, fopts, bare)
+			encode = func(w io.Writer, rv reflect.Value, fopts FieldOptions, bare bool, es *encodeState) error {
+				return cdc.encodeReflectBinaryList(w, info, plan, rv, fopts, bare, es)
+			}
 		}
 
 	case reflect.Struct:
-		err = cdc.encodeReflectBinaryStruct(w, info, rv, fopts, bare)
+		encode = func(w io.Writer, rv reflect.Value, fopts FieldOptions, bare bool, es *encodeState) error {
+			return cdc.encodeReflectBinaryStruct(w, info, plan, rv, fopts, bare, es)
+		}
+
+	case reflect.Map:
+		encode = func(w io.Writer, rv reflect.Value, fopts FieldOptions, bare bool, es *encodeState) error {
+			return cdc.encodeReflectBinaryMap(w, info, plan, rv, fopts, bare, es)
+		}
 
 	//----------------------------------------
 	// Signed
 
 	case reflect.Int64:
-		if fopts.BinFixed64 {
-			err = EncodeInt64(w, rv.Int())
-		} else {
-			err = EncodeUvarint(w, uint64(rv.Int()))
+		encode = func(w io.Writer, rv reflect.Value, fopts FieldOptions, _ bool, _ *encodeState) error {
+			if fopts.BinFixed64 {
+				return EncodeInt64(w, rv.Int())
+			}
+			return EncodeUvarint(w, uint64(rv.Int()))
 		}
 
 	case reflect.Int32:
-		if fopts.BinFixed32 {
-			err = EncodeInt32(w, int32(rv.Int()))
-		} else {
-			err = EncodeUvarint(w, uint64(rv.Int()))
+		encode = func(w io.Writer, rv reflect.Value, fopts FieldOptions, _ bool, _ *encodeState) error {
+			if fopts.BinFixed32 {
+				return EncodeInt32(w, int32(rv.Int()))
+			}
+			return EncodeUvarint(w, uint64(rv.Int()))
 		}
 
 	case reflect.Int16:
-		err = EncodeInt16(w, int16(rv.Int()))
+		encode = func(w io.Writer, rv reflect.Value, _ FieldOptions, _ bool, _ *encodeState) error {
+			return EncodeInt16(w, int16(rv.Int()))
+		}
 
 	case reflect.Int8:
-		err = EncodeInt8(w, int8(rv.Int()))
+		encode = func(w io.Writer, rv reflect.Value, _ FieldOptions, _ bool, _ *encodeState) error {
+			return EncodeInt8(w, int8(rv.Int()))
+		}
 
 	case reflect.Int:
-		err = EncodeUvarint(w, uint64(rv.Int()))
+		encode = func(w io.Writer, rv reflect.Value, _ FieldOptions, _ bool, _ *encodeState) error {
+			return EncodeUvarint(w, uint64(rv.Int()))
+		}
 
 	//----------------------------------------
 	// Unsigned
 
 	case reflect.Uint64:
-		if fopts.BinFixed64 {
-			err = EncodeUint64(w, rv.Uint())
-		} else {
-			err = EncodeUvarint(w, rv.Uint())
+		encode = func(w io.Writer, rv reflect.Value, fopts FieldOptions, _ bool, _ *encodeState) error {
+			if fopts.BinFixed64 {
+				return EncodeUint64(w, rv.Uint())
+			}
+			return EncodeUvarint(w, rv.Uint())
 		}
 
 	case reflect.Uint32:
-		if fopts.BinFixed32 {
-			err = EncodeUint32(w, uint32(rv.Uint()))
-		} else {
-			err = EncodeUvarint(w, rv.Uint())
+		encode = func(w io.Writer, rv reflect.Value, fopts FieldOptions, _ bool, _ *encodeState) error {
+			if fopts.BinFixed32 {
+				return EncodeUint32(w, uint32(rv.Uint()))
+			}
+			return EncodeUvarint(w, rv.Uint())
 		}
 
 	case reflect.Uint16:
-		err = EncodeUint16(w, uint16(rv.Uint()))
+		encode = func(w io.Writer, rv reflect.Value, _ FieldOptions, _ bool, _ *encodeState) error {
+			return EncodeUint16(w, uint16(rv.Uint()))
+		}
 
 	case reflect.Uint8:
-		err = EncodeUint8(w, uint8(rv.Uint()))
+		encode = func(w io.Writer, rv reflect.Value, _ FieldOptions, _ bool, _ *encodeState) error {
+			return EncodeUint8(w, uint8(rv.Uint()))
+		}
 
 	case reflect.Uint:
-		err = EncodeUvarint(w, rv.Uint())
+		encode = func(w io.Writer, rv reflect.Value, _ FieldOptions, _ bool, _ *encodeState) error {
+			return EncodeUvarint(w, rv.Uint())
+		}
 
 	//----------------------------------------
 	// Misc
 
 	case reflect.Bool:
-		err = EncodeBool(w, rv.Bool())
+		encode = func(w io.Writer, rv reflect.Value, _ FieldOptions, _ bool, _ *encodeState) error {
+			return EncodeBool(w, rv.Bool())
+		}
 
 	case reflect.Float64:
-		if !fopts.Unsafe {
-			err = errors.New("amino float* support requires `amino:\"unsafe\"`")
-			return
+		encode = func(w io.Writer, rv reflect.Value, fopts FieldOptions, _ bool, _ *encodeState) error {
+			if !fopts.Unsafe {
+				return errors.New("amino float* support requires `amino:\"unsafe\"`")
+			}
+			return EncodeFloat64(w, rv.Float())
 		}
-		err = EncodeFloat64(w, rv.Float())
 
 	case reflect.Float32:
-		if !fopts.Unsafe {
-			err = errors.New("amino float* support requires `amino:\"unsafe\"`")
-			return
+		encode = func(w io.Writer, rv reflect.Value, fopts FieldOptions, _ bool, _ *encodeState) error {
+			if !fopts.Unsafe {
+				return errors.New("amino float* support requires `amino:\"unsafe\"`")
+			}
+			return EncodeFloat32(w, float32(rv.Float()))
 		}
-		err = EncodeFloat32(w, float32(rv.Float()))
 
 	case reflect.String:
-		err = EncodeString(w, rv.String())
+		encode = func(w io.Writer, rv reflect.Value, _ FieldOptions, _ bool, _ *encodeState) error {
+			return EncodeString(w, rv.String())
+		}
 
 	//----------------------------------------
 	// Default
 
 	default:
-		panic(fmt.Sprintf("unsupported type %v", info.Type.Kind()))
+		encode = func(io.Writer, reflect.Value, FieldOptions, bool, *encodeState) error {
+			panic(fmt.Sprintf("unsupported type %v", info.Type.Kind()))
+		}
 	}
 
-	return
+	// Use generated code if any, see cmd/aminogen.  Generated code doesn't
+	// handle `amino:"write_empty"` of the struct itself.
+	if info.IsAminoBinaryMarshaler {
+		var encodeReflect = encode
+		encode = func(w io.Writer, rv reflect.Value, fopts FieldOptions, bare bool, es *encodeState) error {
+			if !fopts.WriteEmpty && rv.CanInterface() {
+				return cdc.encodeGeneratedBinary(w, info, rv, fopts, bare)
+			}
+			return encodeReflect(w, rv, fopts, bare, es)
+		}
+	}
+	return encode
 }
 
-func (cdc *Codec) encodeReflectBinaryInterface(w io.Writer, iinfo *TypeInfo, rv reflect.Value, fopts FieldOptions, bare bool) (err error) {
+func (cdc *Codec) encodeReflectBinaryInterface(w io.Writer, iinfo *TypeInfo, rv reflect.Value, fopts FieldOptions, bare bool, es *encodeState) (err error) {
 	if printLog {
 		fmt.Println("(e) encodeReflectBinaryInterface")
 		defer func() {
 			fmt.Printf("(e) -> err: %v\n", err)
 		}()
 	}
+	var sv = es.take()
 
 	// Special case when rv is nil, write 0x00 to denote an empty byteslice.
 	if rv.IsNil() {
@@ -178,16 +337,32 @@ func (cdc *Codec) encodeReflectBinaryInterface(w io.Writer, iinfo *TypeInfo, rv
 		return
 	}
 
+	// Write unknown concrete types as they were read.
+	if rv.Elem().Type() == unknownConcreteType {
+		var bz []byte
+		bz, err = rv.Elem().Interface().(UnknownConcrete).binaryBytes()
+		if err != nil {
+			return
+		}
+		if bare {
+			_, err = w.Write(bz)
+		} else {
+			err = EncodeByteSlice(w, bz)
+		}
+		return
+	}
+
 	// Get concrete non-pointer reflect value & type.
 	var crv, isPtr, isNilPtr = derefPointers(rv.Elem())
-	if isPtr && crv.Kind() == reflect.Interface {
-		// See "MARKER: No interface-pointers" in codec.go
-		panic("should not happen")
-	}
 	if isNilPtr {
 		panic(fmt.Sprintf("Illegal nil-pointer of type %v for registered interface %v. "+
 			"For compatibility with other languages, nil-pointer interface values are forbidden.", crv.Type(), iinfo.Type))
 	}
+	if isPtr && crv.Kind() == reflect.Interface && crv.IsNil() {
+		// Of an interface-pointer registered as concrete, see RegisterConcrete.
+		err = fmt.Errorf("cannot encode pointer to nil %v as %v", crv.Type(), iinfo.Type)
+		return
+	}
 	var crt = crv.Type()
 
 	// Get *TypeInfo for concrete type.
@@ -197,47 +372,53 @@ func (cdc *Codec) encodeReflectBinaryInterface(w io.Writer, iinfo *TypeInfo, rv
 		return
 	}
 	if !cinfo.Registered {
-		err = fmt.Errorf("cannot encode unregistered concrete type %v", crt)
+		err = fmt.Errorf("cannot encode unregistered concrete type %v as %v, see RegisterConcrete", crt, iinfo.Type)
 		return
 	}
 
 	// For Proto3 compatibility, encode interfaces as ByteLength.
-	buf := bytes.NewBuffer(nil)
+	if !bare {
+		err = EncodeUvarint(w, uint64(sv.size))
+		if err != nil {
+			return
+		}
+	}
 
 	// Write disambiguation bytes if needed.
-	needDisamb := false
-	if iinfo.AlwaysDisambiguate {
-		needDisamb = true
-	} else if len(iinfo.Implementers[cinfo.Prefix]) > 1 {
-		needDisamb = true
-	}
-	if needDisamb {
-		_, err = buf.Write(append([]byte{0x00}, cinfo.Disamb[:]...))
+	if cdc.needDisamb(iinfo, cinfo) {
+		_, err = w.Write(append([]byte{0x00}, cinfo.Disamb[:]...))
 		if err != nil {
 			return
 		}
 	}
 
 	// Write prefix bytes.
-	_, err = buf.Write(cinfo.Prefix.Bytes())
+	_, err = w.Write(cinfo.Prefix.Bytes())
 	if err != nil {
 		return
 	}
 
 	// Write actual concrete value.
-	err = cdc.encodeReflectBinary(buf, cinfo, crv, fopts, true)
-	if err != nil {
-		return
-	}
+	err = cdc.encodeReflectBinary(w, cinfo, crv, fopts, true, es)
+	return
+}
 
-	if bare {
-		// Write byteslice without byte-length prefixing.
-		_, err = w.Write(buf.Bytes())
+// Returns whether values of cinfo in interface iinfo are written with
+// disambiguation bytes.
+func (cdc *Codec) needDisamb(iinfo, cinfo *TypeInfo) bool {
+	if iinfo.AlwaysDisambiguate {
+		return true
+	}
+	// See getTypeInfoFromPrefixRlock.
+	var n int
+	if cdc.getSnapshot() != nil {
+		n = len(iinfo.Implementers[cinfo.Prefix])
 	} else {
-		// Write byte-length prefixed byteslice.
-		err = EncodeByteSlice(w, buf.Bytes())
+		cdc.mtx.RLock()
+		n = len(iinfo.Implementers[cinfo.Prefix])
+		cdc.mtx.RUnlock()
 	}
-	return
+	return n > 1
 }
 
 func (cdc *Codec) encodeReflectBinaryByteArray(w io.Writer, info *TypeInfo, rv reflect.Value, fopts FieldOptions) (err error) {
@@ -261,7 +442,7 @@ func (cdc *Codec) encodeReflectBinaryByteArray(w io.Writer, info *TypeInfo, rv r
 	return
 }
 
-func (cdc *Codec) encodeReflectBinaryList(w io.Writer, info *TypeInfo, rv reflect.Value, fopts FieldOptions, bare bool) (err error) {
+func (cdc *Codec) encodeReflectBinaryList(w io.Writer, info *TypeInfo, plan *binaryPlan, rv reflect.Value, fopts FieldOptions, bare bool, es *encodeState) (err error) {
 	if printLog {
 		fmt.Println("(e) encodeReflectBinaryList")
 		defer func() {
@@ -272,14 +453,17 @@ func (cdc *Codec) encodeReflectBinaryList(w io.Writer, info *TypeInfo, rv reflec
 	if ert.Kind() == reflect.Uint8 {
 		panic("should not happen")
 	}
-	einfo, err := cdc.getTypeInfoWlock(ert)
-	if err != nil {
-		return
-	}
+	einfo := plan.elemInfo
+	var sv = es.take()
 
-	// Proto3 byte-length prefixing incurs alloc cost on the encoder.
-	// Here we incur it for unpacked form for ease of dev.
-	buf := bytes.NewBuffer(nil)
+	// Proto3 byte-length prefixing incurs a cost on the encoder, which
+	// computes the length before writing the elements.
+	if !bare {
+		err = EncodeUvarint(w, uint64(sv.size))
+		if err != nil {
+			return
+		}
+	}
 
 	// If elem is not already a ByteLength type, write in packed form.
 	// This is a Proto wart due to Proto backwards compatibility issues.
@@ -291,7 +475,7 @@ func (cdc *Codec) encodeReflectBinaryList(w io.Writer, info *TypeInfo, rv reflec
 			// Get dereferenced element value (or zero).
 			var erv, _, _ = derefPointersZero(rv.Index(i))
 			// Write the element value.
-			err = cdc.encodeReflectBinary(buf, einfo, erv, fopts, false)
+			err = cdc.encodeReflectBinary(w, einfo, erv, fopts, false, es)
 			if err != nil {
 				return
 			}
@@ -303,7 +487,7 @@ func (cdc *Codec) encodeReflectBinaryList(w io.Writer, info *TypeInfo, rv reflec
 		// Write elems in unpacked form.
 		for i := 0; i < rv.Len(); i++ {
 			// Write elements as repeated fields of the parent struct.
-			err = encodeFieldNumberAndTyp3(buf, fopts.BinFieldNum, Typ3ByteLength)
+			err = encodeFieldNumberAndTyp3(w, fopts.BinFieldNum, Typ3ByteLength)
 			if err != nil {
 				return
 			}
@@ -322,7 +506,7 @@ func (cdc *Codec) encodeReflectBinaryList(w io.Writer, info *TypeInfo, rv reflec
 					return errors.New("nil struct pointers not supported when empty_elements field tag is set")
 				}
 				// Nothing to encode, so the length is 0.
-				err = EncodeByte(buf, byte(0x00))
+				err = EncodeByte(w, byte(0x00))
 				if err != nil {
 					return
 				}
@@ -331,21 +515,33 @@ func (cdc *Codec) encodeReflectBinaryList(w io.Writer, info *TypeInfo, rv reflec
 				// In case of any inner lists in unpacked form.
 				efopts := fopts
 				efopts.BinFieldNum = 1
-				err = cdc.encodeReflectBinary(buf, einfo, erv, efopts, false)
+				if plan.wrapElems {
+					err = cdc.encodeReflectBinaryWrapped(w, einfo, erv, efopts, es)
+				} else {
+					err = cdc.encodeReflectBinary(w, einfo, erv, efopts, false, es)
+				}
 				if err != nil {
 					return
 				}
 			}
 		}
 	}
+	return
+}
 
-	if bare {
-		// Write byteslice without byte-length prefixing.
-		_, err = w.Write(buf.Bytes())
-	} else {
-		// Write byte-length prefixed byteslice.
-		err = EncodeByteSlice(w, buf.Bytes())
+// Writes the list rv in packed form as field 1 of a length-prefixed message,
+// for lists of lists, see isPackedList.
+func (cdc *Codec) encodeReflectBinaryWrapped(w io.Writer, info *TypeInfo, rv reflect.Value, fopts FieldOptions, es *encodeState) (err error) {
+	var sv = es.take()
+	err = EncodeUvarint(w, uint64(sv.size))
+	if err != nil {
+		return
 	}
+	err = encodeFieldNumberAndTyp3(w, 1, Typ3ByteLength)
+	if err != nil {
+		return
+	}
+	err = cdc.encodeReflectBinary(w, info, rv, fopts, false, es)
 	return
 }
 
@@ -368,7 +564,64 @@ func (cdc *Codec) encodeReflectBinaryByteSlice(w io.Writer, info *TypeInfo, rv r
 	return
 }
 
-func (cdc *Codec) encodeReflectBinaryStruct(w io.Writer, info *TypeInfo, rv reflect.Value, fopts FieldOptions, bare bool) (err error) {
+// Maps are encoded like Proto3 maps, as a list of key/value entry structs,
+// where the key is field 1 and the value is field 2.  To keep the encoding
+// canonical, entries are sorted by the encoded bytes of their keys, see
+// sizeReflectBinaryMap.
+func (cdc *Codec) encodeReflectBinaryMap(w io.Writer, info *TypeInfo, plan *binaryPlan, rv reflect.Value, fopts FieldOptions, bare bool, es *encodeState) (err error) {
+	if printLog {
+		fmt.Println("(e) encodeReflectBinaryMap")
+		defer func() {
+			fmt.Printf("(e) -> err: %v\n", err)
+		}()
+	}
+	vinfo := plan.elemInfo
+	_, vopts := mapEntryOptions(fopts)
+	vkey := newFieldKey(vopts.BinFieldNum, vinfo.Type, vopts)
+	var sv = es.take()
+
+	if !bare {
+		err = EncodeUvarint(w, uint64(sv.size))
+		if err != nil {
+			return
+		}
+	}
+
+	// Write entries in unpacked form, as repeated fields of the parent struct.
+	for _, entry := range sv.entries {
+		err = encodeFieldNumberAndTyp3(w, fopts.BinFieldNum, Typ3ByteLength)
+		if err != nil {
+			return
+		}
+		err = EncodeUvarint(w, uint64(len(entry.key)+entry.size))
+		if err != nil {
+			return
+		}
+		_, err = w.Write(entry.key)
+		if err != nil {
+			return
+		}
+		err = cdc.encodeReflectBinaryMapEntryField(w, vkey, vinfo, entry.value, vopts, es)
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+// Writes the key or value of a map entry, just like a struct field.
+func (cdc *Codec) encodeReflectBinaryMapEntryField(w io.Writer, key []byte, info *TypeInfo, rv reflect.Value, fopts FieldOptions, es *encodeState) (err error) {
+	var drv, isDefault = isDefaultValue(rv)
+	if isDefault {
+		return
+	}
+	if isUnpackedList(rv.Type(), fopts) {
+		return cdc.encodeReflectBinary(w, info, drv, fopts, true, es)
+	}
+	return cdc.writeFieldIfNotEmpty(w, key, fopts, false, es)
+}
+
+func (cdc *Codec) encodeReflectBinaryStruct(w io.Writer, info *TypeInfo, plan *binaryPlan, rv reflect.Value, fopts FieldOptions, bare bool, es *encodeState) (err error) {
 	if printLog {
 		fmt.Println("(e) encodeReflectBinaryBinaryStruct")
 		defer func() {
@@ -376,59 +629,106 @@ func (cdc *Codec) encodeReflectBinaryStruct(w io.Writer, info *TypeInfo, rv refl
 		}()
 	}
 
-	// Proto3 incurs a cost in writing non-root structs.
-	// Here we incur it for root structs as well for ease of dev.
-	buf := bytes.NewBuffer(nil)
+	// Proto3 incurs a cost in writing non-root structs, whose length is
+	// computed before writing the fields.
+	var sv = es.take()
+	if !bare {
+		err = EncodeUvarint(w, uint64(sv.size))
+		if err != nil {
+			return
+		}
+	}
 
 	switch info.Type {
 
 	case timeType:
 		// Special case: time.Time
-		err = EncodeTime(buf, rv.Interface().(time.Time))
+		err = EncodeTime(w, rv.Interface().(time.Time))
 		if err != nil {
 			return
 		}
 
 	default:
-		for _, field := range info.Fields {
-			// Get type info for field.
-			var finfo *TypeInfo
-			finfo, err = cdc.getTypeInfoWlock(field.Type)
+		// Unknown fields to write in field number order, see UnknownFields.
+		var unknown []byte
+		if info.UnknownFieldsIndex >= 0 {
+			unknown = rv.Field(info.UnknownFieldsIndex).Bytes()
+		}
+		for i := range info.Fields {
+			var field = &info.Fields[i]
+			// Write unknown fields that precede this one.
+			unknown, err = writeFieldsBefore(w, unknown, field.BinFieldNum)
 			if err != nil {
 				return
 			}
-			// Get dereferenced field value and info.
-			var frv = rv.Field(field.Index)
-			var frvIsPtr = frv.Kind() == reflect.Ptr
-			var dfrv, isDefault = isDefaultValue(frv)
-			if isDefault && !fopts.WriteEmpty {
-				// Do not encode default value fields
-				// (except when `amino:"write_empty"` is set).
-				continue
-			}
-			if field.UnpackedList {
-				// Write repeated field entries for each list item.
-				err = cdc.encodeReflectBinaryList(buf, finfo, dfrv, field.FieldOptions, true)
-				if err != nil {
-					return
-				}
-			} else {
-				// write empty if explicitly set or if this is a pointer:
-				writeEmpty := fopts.WriteEmpty || frvIsPtr
-				err = cdc.writeFieldIfNotEmpty(buf, field.BinFieldNum, finfo, fopts, field.FieldOptions, dfrv, writeEmpty, false)
-				if err != nil {
-					return
-				}
+			err = cdc.encodeReflectBinaryField(w, field, &plan.fields[i], rv.Field(field.Index), fopts, es)
+			if err != nil {
+				return
 			}
 		}
+		// Write any remaining unknown fields.
+		_, err = writeFieldsBefore(w, unknown, maxFieldNum+1)
+		if err != nil {
+			return
+		}
 	}
+	return
+}
 
-	if bare {
-		// Write byteslice without byte-length prefixing.
-		_, err = w.Write(buf.Bytes())
-	} else {
-		// Write byte-length prefixed byteslice.
-		err = EncodeByteSlice(w, buf.Bytes())
+// Writes the field of a struct with options fopts, unless the field has the
+// default value.
+func (cdc *Codec) encodeReflectBinaryField(w io.Writer, field *FieldInfo, fplan *fieldPlan, frv reflect.Value, fopts FieldOptions, es *encodeState) (err error) {
+	var finfo = fplan.info
+	// Get dereferenced field value and info.
+	var dfrv, isDefault = isDefaultValue(frv)
+	if isDefault && !fopts.WriteEmpty {
+		// Do not encode default value fields
+		// (except when `amino:"write_empty"` is set).
+		return
+	}
+	if field.UnpackedList {
+		// Write repeated field entries for each list item (or map entry).
+		return cdc.encodeReflectBinary(w, finfo, dfrv, field.FieldOptions, true, es)
+	}
+	return cdc.writeFieldIfNotEmpty(w, fplan.key, field.FieldOptions, false, es)
+}
+
+// Writes the encoded fields of bz with field numbers less than num, and
+// returns the remaining fields.  Fields numbered num are not allowed, since
+// num is taken by a known field.
+func writeFieldsBefore(w io.Writer, bz []byte, num uint32) (rest []byte, err error) {
+	var n int
+	n, err = splitFieldsBefore(bz, num)
+	if err != nil {
+		return
+	}
+	_, err = w.Write(bz[:n])
+	return bz[n:], err
+}
+
+// Returns the length of the encoded fields of bz with field numbers less than
+// num, see writeFieldsBefore.
+func splitFieldsBefore(bz []byte, num uint32) (n int, err error) {
+	var _n, fnum = 0, uint32(0)
+	var typ3 Typ3
+	for n < len(bz) {
+		fnum, typ3, _n, err = decodeFieldNumberAndTyp3(bz[n:])
+		if err != nil {
+			return
+		}
+		if fnum == num {
+			err = fmt.Errorf("unknown field # %v conflicts with a known field", fnum)
+			return
+		}
+		if fnum > num {
+			break
+		}
+		n += _n
+		_n, err = consumeAny(typ3, bz[n:])
+		if err != nil {
+			return
+		}
+		n += _n
 	}
 	return
 }
@@ -441,7 +741,7 @@ func encodeFieldNumberAndTyp3(w io.Writer, num uint32, typ Typ3) (err error) {
 	if (typ & 0xF8) != 0 {
 		panic(fmt.Sprintf("invalid Typ3 byte %v", typ))
 	}
-	if num > (1<<29 - 1) {
+	if num > maxFieldNum {
 		panic(fmt.Sprintf("invalid field number %v", num))
 	}
 
@@ -449,41 +749,32 @@ func encodeFieldNumberAndTyp3(w io.Writer, num uint32, typ Typ3) (err error) {
 	var value64 = (uint64(num) << 3) | uint64(typ)
 
 	// Write uvarint value for field and Typ3.
-	var buf [10]byte
-	n := binary.PutUvarint(buf[:], value64)
-	_, err = w.Write(buf[0:n])
-	return
+	return EncodeUvarint(w, value64)
 }
 
+// Writes the field key and the value, unless the value would be written as
+// a single 0x00 byte (e.g. an empty struct) and the field isn't written
+// empty, as decided by sizeFieldIfNotEmpty.
 func (cdc *Codec) writeFieldIfNotEmpty(
-	buf *bytes.Buffer,
-	fieldNum uint32,
-	finfo *TypeInfo,
-	structsFopts FieldOptions, // the wrapping struct's FieldOptions if any
+	w io.Writer,
+	key []byte, // the field's number and typ3, see fieldKey
 	fieldOpts FieldOptions, // the field's FieldOptions
-	derefedVal reflect.Value,
-	isWriteEmpty bool,
 	bare bool,
+	es *encodeState,
 ) error {
-	lBeforeKey := buf.Len()
-	// Write field key (number and type).
-	err := encodeFieldNumberAndTyp3(buf, fieldNum, typeToTyp3(finfo.Type, fieldOpts))
+	var sv = es.take()
+	if sv.size == 0 {
+		es.skip(sv)
+		return nil
+	}
+	// The value is the repr for repr types, see sizeFieldIfNotEmpty.
+	plan, err := cdc.getBinaryPlan(sv.info)
 	if err != nil {
 		return err
 	}
-	lBeforeValue := buf.Len()
-
-	// Write field value from rv.
-	err = cdc.encodeReflectBinary(buf, finfo, derefedVal, fieldOpts, bare)
+	_, err = w.Write(key)
 	if err != nil {
 		return err
 	}
-	lAfterValue := buf.Len()
-
-	if !isWriteEmpty && lBeforeValue == lAfterValue-1 && buf.Bytes()[buf.Len()-1] == 0x00 {
-		// rollback typ3/fieldnum and last byte if
-		// not a pointer and empty:
-		buf.Truncate(lBeforeKey)
-	}
-	return nil
+	return plan.encode(w, sv.rv, fieldOpts, bare, es)
 }
@@ -0,0 +1,166 @@
+package amino
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"reflect"
+)
+
+//----------------------------------------
+// Binary plans
+
+// A binaryPlan is what the binary encoder and decoder need to know about a
+// type beyond its TypeInfo: the type infos of its children, the keys of its
+// fields, and the functions that encode, size and decode its values.  It is
+// compiled once per type, the first time a value of the type is used, so
+// that the hot paths neither take the Codec's lock nor repeat these lookups
+// for every value.
+type binaryPlan struct {
+	encode binaryEncoder
+	decode binaryDecoder
+	size   binarySizer
+
+	reprInfo   *TypeInfo   // Of AminoMarshalReprType, if any.
+	unreprInfo *TypeInfo   // Of AminoUnmarshalReprType, if any.
+	elemInfo   *TypeInfo   // Of list elements (except bytes) and map values.
+	keyInfo    *TypeInfo   // Of map keys.
+	fields     []fieldPlan // Of struct fields, in the order of StructInfo.Fields.
+
+	// Whether values are written as their bare encoding prefixed with its
+	// length, unless bare.
+	prefixed bool
+
+	// Whether list elements are lists in packed form, which are wrapped in
+	// a message as field 1, see isPackedList.
+	wrapElems bool
+
+	// Whether values are numbers, bools, strings or bytes, which are decoded
+	// without calling decodeReflectBinary again (unlike repr types).
+	scalar bool
+}
+
+type fieldPlan struct {
+	info *TypeInfo // Of the (dereferenced) field type.
+	key  []byte    // Field number and typ3, or nil if the type is unsupported.
+}
+
+// Encodes rv, see encodeReflectBinary.  rv must have been sized with es.
+type binaryEncoder func(w io.Writer, rv reflect.Value, fopts FieldOptions, bare bool, es *encodeState) error
+
+// Returns the length of the encoding of rv, see sizeReflectBinary.  es may
+// be nil if rv is not encoded afterwards.
+type binarySizer func(rv reflect.Value, fopts FieldOptions, bare bool, es *encodeState) (int, error)
+
+// Decodes bz into rv, see decodeReflectBinary.  Pointers are already
+// dereferenced.
+type binaryDecoder func(bz []byte, rv reflect.Value, fopts FieldOptions, bare bool, ds *decodeState) (int, error)
+
+// Returns the plan of info, compiling it if needed.  Plans are only stored
+// once compiled successfully, so that e.g. registering a missing interface
+// fixes later calls.
+func (cdc *Codec) getBinaryPlan(info *TypeInfo) (*binaryPlan, error) {
+	if plan, ok := info.binaryPlan.Load().(*binaryPlan); ok {
+		return plan, nil
+	}
+	plan, err := cdc.newBinaryPlan(info)
+	if err != nil {
+		return nil, err
+	}
+	// Concurrent compilations yield equivalent plans, either may win.
+	info.binaryPlan.Store(plan)
+	return plan, nil
+}
+
+func (cdc *Codec) newBinaryPlan(info *TypeInfo) (plan *binaryPlan, err error) {
+	plan = new(binaryPlan)
+	var rt = info.Type
+	if info.IsAminoMarshaler {
+		plan.reprInfo, err = cdc.getTypeInfoWlock(info.AminoMarshalReprType)
+		if err != nil {
+			return
+		}
+	}
+	if info.IsAminoUnmarshaler {
+		plan.unreprInfo, err = cdc.getTypeInfoWlock(info.AminoUnmarshalReprType)
+		if err != nil {
+			return
+		}
+	}
+	switch rt.Kind() {
+	case reflect.Array, reflect.Slice:
+		if rt.Elem().Kind() != reflect.Uint8 {
+			plan.elemInfo, err = cdc.getTypeInfoWlock(rt.Elem())
+			plan.prefixed = !info.IsAminoMarshaler
+			plan.wrapElems = err == nil && isPackedList(plan.elemInfo)
+		}
+	case reflect.Map:
+		plan.keyInfo, plan.elemInfo, err = cdc.getMapEntryInfos(info)
+		plan.prefixed = !info.IsAminoMarshaler
+	case reflect.Struct:
+		plan.prefixed = !info.IsAminoMarshaler
+		plan.fields = make([]fieldPlan, len(info.Fields))
+		for i := range info.Fields {
+			var field = &info.Fields[i]
+			var finfo *TypeInfo
+			finfo, err = cdc.getTypeInfoWlock(field.Type)
+			if err != nil {
+				return
+			}
+			plan.fields[i] = fieldPlan{
+				info: finfo,
+				key:  newFieldKey(field.BinFieldNum, finfo.Type, field.FieldOptions),
+			}
+		}
+	}
+	if err != nil {
+		return
+	}
+	switch rt.Kind() {
+	case reflect.Interface, reflect.Map, reflect.Struct:
+	case reflect.Array, reflect.Slice:
+		plan.scalar = rt.Elem().Kind() == reflect.Uint8 && !info.IsAminoUnmarshaler
+	default:
+		plan.scalar = !info.IsAminoUnmarshaler
+	}
+	plan.encode = cdc.newBinaryEncoder(info, plan)
+	plan.decode = cdc.newBinaryDecoder(info, plan)
+	if info.IsEnum && info.Strict {
+		// Check the decoded value, see RegisterEnum.
+		var decode = plan.decode
+		plan.decode = func(bz []byte, rv reflect.Value, fopts FieldOptions, bare bool, ds *decodeState) (n int, err error) {
+			n, err = decode(bz, rv, fopts, bare, ds)
+			if err == nil {
+				err = checkEnumDefined(info, rv)
+			}
+			return
+		}
+	}
+	plan.size = cdc.newBinarySizer(info, plan)
+	return
+}
+
+// Returns the encoded key of field num, of (dereferenced) type rt.
+func newFieldKey(num uint32, rt reflect.Type, fopts FieldOptions) []byte {
+	switch rt.Kind() {
+	case reflect.Chan, reflect.Func, reflect.Complex64, reflect.Complex128,
+		reflect.Uintptr, reflect.UnsafePointer:
+		// Unsupported, but only fails if encoded, see typeToTyp3.
+		return nil
+	}
+	return fieldKey(num, typeToTyp3(rt, fopts))
+}
+
+// Returns the encoded key of field num of type typ, see
+// encodeFieldNumberAndTyp3.
+func fieldKey(num uint32, typ Typ3) []byte {
+	if (typ & 0xF8) != 0 {
+		panic(fmt.Sprintf("invalid Typ3 byte %v", typ))
+	}
+	if num > maxFieldNum {
+		panic(fmt.Sprintf("invalid field number %v", num))
+	}
+	var buf [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(buf[:], (uint64(num)<<3)|uint64(typ))
+	return append([]byte(nil), buf[:n]...)
+}
@@ -0,0 +1,521 @@
+package amino
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+)
+
+//----------------------------------------
+// cdc.sizeReflectBinary
+
+// Returns the length of the encoding of rv, as written by
+// encodeReflectBinary, without encoding it.  The size* functions mirror the
+// encodeReflectBinary* functions, and must be kept in sync with them.  What
+// they compute is recorded in es for encoding rv afterwards, unless es is nil.
+// CONTRACT: rv is not a pointer
+// CONTRACT: rv is valid.
+func (cdc *Codec) sizeReflectBinary(info *TypeInfo, rv reflect.Value, fopts FieldOptions, bare bool, es *encodeState) (n int, err error) {
+	if rv.Kind() == reflect.Ptr {
+		panic("not allowed to be called with a reflect.Ptr")
+	}
+	if !rv.IsValid() {
+		panic("not allowed to be called with invalid / zero Value")
+	}
+
+	var plan *binaryPlan
+	plan, err = cdc.getBinaryPlan(info)
+	if err != nil {
+		return
+	}
+	return plan.size(rv, fopts, bare, es)
+}
+
+// Returns the function that sizes values of type info, see binaryPlan.
+func (cdc *Codec) newBinarySizer(info *TypeInfo, plan *binaryPlan) binarySizer {
+
+	// Handle override if rv implements MarshalAmino.
+	if info.IsAminoMarshaler {
+		return func(rv reflect.Value, fopts FieldOptions, bare bool, es *encodeState) (int, error) {
+			i := es.push()
+			rrv, err := toReprObject(rv)
+			if err != nil {
+				return 0, err
+			}
+			n, err := cdc.sizeReflectBinary(plan.reprInfo, rrv, fopts, bare, es)
+			es.set(i, sizedValue{rv: rrv})
+			return n, err
+		}
+	}
+
+	var size binarySizer
+	switch info.Type.Kind() {
+
+	//----------------------------------------
+	// Complex
+
+	case reflect.Interface:
+		size = func(rv reflect.Value, fopts FieldOptions, bare bool, es *encodeState) (int, error) {
+			return cdc.sizeReflectBinaryInterface(info, rv, fopts, bare, es)
+		}
+
+	case reflect.Array:
+		if info.Type.Elem().Kind() == reflect.Uint8 {
+			var length = info.Type.Len()
+			size = func(reflect.Value, FieldOptions, bool, *encodeState) (int, error) {
+				return UvarintSize(uint64(length)) + length, nil
+			}
+		} else {
+			size = func(rv reflect.Value, fopts FieldOptions, bare bool, es *encodeState) (int, error) {
+				return cdc.sizeReflectBinaryList(info, plan, rv, fopts, bare, es)
+			}
+		}
+
+	case reflect.Slice:
+		if info.Type.Elem().Kind() == reflect.Uint8 {
+			size = func(rv reflect.Value, _ FieldOptions, _ bool, _ *encodeState) (int, error) {
+				return UvarintSize(uint64(rv.Len())) + rv.Len(), nil
+			}
+		} else {
+			size = func(rv reflect.Value, fopts FieldOptions, bare bool, es *encodeState) (int, error) {
+				return cdc.sizeReflectBinaryList(info, plan, rv, fopts, bare, es)
+			}
+		}
+
+	case reflect.Struct:
+		size = func(rv reflect.Value, fopts FieldOptions, bare bool, es *encodeState) (int, error) {
+			return cdc.sizeReflectBinaryStruct(info, plan, rv, fopts, bare, es)
+		}
+
+	case reflect.Map:
+		size = func(rv reflect.Value, fopts FieldOptions, bare bool, es *encodeState) (int, error) {
+			return cdc.sizeReflectBinaryMap(plan, rv, fopts, bare, es)
+		}
+
+	//----------------------------------------
+	// Signed
+
+	case reflect.Int64:
+		size = func(rv reflect.Value, fopts FieldOptions, _ bool, _ *encodeState) (int, error) {
+			if fopts.BinFixed64 {
+				return 8, nil
+			}
+			return UvarintSize(uint64(rv.Int())), nil
+		}
+
+	case reflect.Int32:
+		size = func(rv reflect.Value, fopts FieldOptions, _ bool, _ *encodeState) (int, error) {
+			if fopts.BinFixed32 {
+				return 4, nil
+			}
+			return UvarintSize(uint64(rv.Int())), nil
+		}
+
+	case reflect.Int16, reflect.Int8:
+		size = func(rv reflect.Value, _ FieldOptions, _ bool, _ *encodeState) (int, error) {
+			return VarintSize(rv.Int()), nil
+		}
+
+	case reflect.Int:
+		size = func(rv reflect.Value, _ FieldOptions, _ bool, _ *encodeState) (int, error) {
+			return UvarintSize(uint64(rv.Int())), nil
+		}
+
+	//----------------------------------------
+	// Unsigned
+
+	case reflect.Uint64:
+		size = func(rv reflect.Value, fopts FieldOptions, _ bool, _ *encodeState) (int, error) {
+			if fopts.BinFixed64 {
+				return 8, nil
+			}
+			return UvarintSize(rv.Uint()), nil
+		}
+
+	case reflect.Uint32:
+		size = func(rv reflect.Value, fopts FieldOptions, _ bool, _ *encodeState) (int, error) {
+			if fopts.BinFixed32 {
+				return 4, nil
+			}
+			return UvarintSize(rv.Uint()), nil
+		}
+
+	case reflect.Uint16, reflect.Uint8, reflect.Uint:
+		size = func(rv reflect.Value, _ FieldOptions, _ bool, _ *encodeState) (int, error) {
+			return UvarintSize(rv.Uint()), nil
+		}
+
+	//----------------------------------------
+	// Misc
+
+	case reflect.Bool:
+		size = func(reflect.Value, FieldOptions, bool, *encodeState) (int, error) {
+			return 1, nil
+		}
+
+	case reflect.Float64:
+		size = func(_ reflect.Value, fopts FieldOptions, _ bool, _ *encodeState) (int, error) {
+			if !fopts.Unsafe {
+				return 0, errors.New("amino float* support requires `amino:\"unsafe\"`")
+			}
+			return 8, nil
+		}
+
+	case reflect.Float32:
+		size = func(_ reflect.Value, fopts FieldOptions, _ bool, _ *encodeState) (int, error) {
+			if !fopts.Unsafe {
+				return 0, errors.New("amino float* support requires `amino:\"unsafe\"`")
+			}
+			return 4, nil
+		}
+
+	case reflect.String:
+		size = func(rv reflect.Value, _ FieldOptions, _ bool, _ *encodeState) (int, error) {
+			return UvarintSize(uint64(rv.Len())) + rv.Len(), nil
+		}
+
+	//----------------------------------------
+	// Default
+
+	default:
+		size = func(reflect.Value, FieldOptions, bool, *encodeState) (int, error) {
+			panic(fmt.Sprintf("unsupported type %v", info.Type.Kind()))
+		}
+	}
+
+	// Use generated code if any, see cmd/aminogen.
+	if info.IsAminoBinaryMarshaler {
+		var sizeReflect = size
+		size = func(rv reflect.Value, fopts FieldOptions, bare bool, es *encodeState) (int, error) {
+			if !fopts.WriteEmpty && rv.CanInterface() {
+				n, err := rv.Interface().(AminoBinaryMarshaler).AminoSize(cdc)
+				if err != nil || bare {
+					return n, err
+				}
+				return UvarintSize(uint64(n)) + n, nil
+			}
+			return sizeReflect(rv, fopts, bare, es)
+		}
+	}
+	return size
+}
+
+func (cdc *Codec) sizeReflectBinaryInterface(iinfo *TypeInfo, rv reflect.Value, fopts FieldOptions, bare bool, es *encodeState) (n int, err error) {
+	var i = es.push()
+
+	// Special case when rv is nil, 0x00 denotes an empty byteslice.
+	if rv.IsNil() {
+		es.set(i, sizedValue{})
+		return 1, nil
+	}
+
+	// Unknown concrete types are written as they were read.
+	if rv.Elem().Type() == unknownConcreteType {
+		n, err = rv.Elem().Interface().(UnknownConcrete).binarySize()
+		es.set(i, sizedValue{size: n})
+		if err == nil && !bare {
+			n += UvarintSize(uint64(n))
+		}
+		return
+	}
+
+	// Get concrete non-pointer reflect value & type.
+	var crv, isPtr, isNilPtr = derefPointers(rv.Elem())
+	if isNilPtr {
+		panic(fmt.Sprintf("Illegal nil-pointer of type %v for registered interface %v. "+
+			"For compatibility with other languages, nil-pointer interface values are forbidden.", crv.Type(), iinfo.Type))
+	}
+	if isPtr && crv.Kind() == reflect.Interface && crv.IsNil() {
+		// Of an interface-pointer registered as concrete, see RegisterConcrete.
+		err = fmt.Errorf("cannot encode pointer to nil %v as %v", crv.Type(), iinfo.Type)
+		return
+	}
+	var crt = crv.Type()
+
+	// Get *TypeInfo for concrete type.
+	var cinfo *TypeInfo
+	cinfo, err = cdc.getTypeInfoWlock(crt)
+	if err != nil {
+		return
+	}
+	if !cinfo.Registered {
+		err = fmt.Errorf("cannot encode unregistered concrete type %v as %v, see RegisterConcrete", crt, iinfo.Type)
+		return
+	}
+
+	// Disambiguation bytes (escaped with 0x00) if needed, and prefix bytes.
+	if cdc.needDisamb(iinfo, cinfo) {
+		n += 1 + DisambBytesLen
+	}
+	n += PrefixBytesLen
+
+	// Concrete value.
+	var _n int
+	_n, err = cdc.sizeReflectBinary(cinfo, crv, fopts, true, es)
+	if err != nil {
+		return
+	}
+	n += _n
+	es.set(i, sizedValue{size: n})
+
+	if !bare {
+		n += UvarintSize(uint64(n))
+	}
+	return
+}
+
+func (cdc *Codec) sizeReflectBinaryList(info *TypeInfo, plan *binaryPlan, rv reflect.Value, fopts FieldOptions, bare bool, es *encodeState) (n int, err error) {
+	ert := info.Type.Elem()
+	einfo := plan.elemInfo
+	var i = es.push()
+
+	var _n int
+	typ3 := typeToTyp3(einfo.Type, fopts)
+	if typ3 != Typ3ByteLength {
+		// Elems in packed form.
+		for j := 0; j < rv.Len(); j++ {
+			var erv, _, _ = derefPointersZero(rv.Index(j))
+			_n, err = cdc.sizeReflectBinary(einfo, erv, fopts, false, es)
+			if err != nil {
+				return
+			}
+			n += _n
+		}
+	} else { // typ3 == Typ3ByteLength
+		// NOTE: ert is for the element value, while einfo.Type is dereferenced.
+		isErtStructPointer := ert.Kind() == reflect.Ptr && einfo.Type.Kind() == reflect.Struct
+		keySize := fieldKeySize(fopts.BinFieldNum, Typ3ByteLength)
+
+		// Elems in unpacked form, as repeated fields of the parent struct.
+		for j := 0; j < rv.Len(); j++ {
+			n += keySize
+			var erv, isDefault = isDefaultValue(rv.Index(j))
+			if isDefault {
+				if isErtStructPointer && fopts.EmptyElements {
+					return 0, errors.New("nil struct pointers not supported when empty_elements field tag is set")
+				}
+				// Nothing to encode, so the length is 0.
+				n++
+			} else {
+				efopts := fopts
+				efopts.BinFieldNum = 1
+				var k = -1
+				if plan.wrapElems {
+					k = es.push()
+				}
+				_n, err = cdc.sizeReflectBinary(einfo, erv, efopts, false, es)
+				if err != nil {
+					return
+				}
+				if plan.wrapElems {
+					// See encodeReflectBinaryWrapped.
+					_n += fieldKeySize(1, Typ3ByteLength)
+					es.set(k, sizedValue{size: _n})
+					_n += UvarintSize(uint64(_n))
+				}
+				n += _n
+			}
+		}
+	}
+	es.set(i, sizedValue{size: n})
+
+	if !bare {
+		n += UvarintSize(uint64(n))
+	}
+	return
+}
+
+// Also sorts the entries of rv for encodeReflectBinaryMap.
+func (cdc *Codec) sizeReflectBinaryMap(plan *binaryPlan, rv reflect.Value, fopts FieldOptions, bare bool, es *encodeState) (n int, err error) {
+	kinfo, vinfo := plan.keyInfo, plan.elemInfo
+	kopts, vopts := mapEntryOptions(fopts)
+	kkey := fieldKey(kopts.BinFieldNum, typeToTyp3(kinfo.Type, kopts))
+	vkeySize := len(newFieldKey(vopts.BinFieldNum, vinfo.Type, vopts))
+	keySize := fieldKeySize(fopts.BinFieldNum, Typ3ByteLength)
+	var i = es.push()
+
+	// Encode the keys, which are small, in one buffer.
+	var keys = new(bytes.Buffer)
+	var kes = newEncodeState()
+	var ends = make([]int, 0, rv.Len())
+	var values = make([]reflect.Value, 0, rv.Len())
+	for _, krv := range rv.MapKeys() {
+		kes.reset()
+		_, err = cdc.sizeReflectBinaryMapEntryField(len(kkey), kinfo, krv, kopts, kes)
+		if err != nil {
+			return
+		}
+		err = cdc.encodeReflectBinaryMapEntryField(keys, kkey, kinfo, krv, kopts, kes)
+		if err != nil {
+			return
+		}
+		ends = append(ends, keys.Len())
+		values = append(values, rv.MapIndex(krv))
+	}
+	var entries = make([]mapEntry, len(ends))
+	for j, end := range ends {
+		var start = 0
+		if j > 0 {
+			start = ends[j-1]
+		}
+		entries[j] = mapEntry{key: keys.Bytes()[start:end], value: values[j]}
+	}
+	sort.Slice(entries, func(j, k int) bool {
+		return bytes.Compare(entries[j].key, entries[k].key) < 0
+	})
+
+	// Entries in unpacked form, as repeated fields of the parent struct.
+	for j := range entries {
+		var entry = &entries[j]
+		entry.size, err = cdc.sizeReflectBinaryMapEntryField(vkeySize, vinfo, entry.value, vopts, es)
+		if err != nil {
+			return
+		}
+		var size = len(entry.key) + entry.size
+		n += keySize + UvarintSize(uint64(size)) + size
+	}
+	es.set(i, sizedValue{size: n, entries: entries})
+
+	if !bare {
+		n += UvarintSize(uint64(n))
+	}
+	return
+}
+
+// See encodeReflectBinaryMapEntryField.
+func (cdc *Codec) sizeReflectBinaryMapEntryField(keySize int, info *TypeInfo, rv reflect.Value, fopts FieldOptions, es *encodeState) (n int, err error) {
+	var rvIsPtr = rv.Kind() == reflect.Ptr
+	var drv, isDefault = isDefaultValue(rv)
+	if isDefault {
+		return
+	}
+	if isUnpackedList(rv.Type(), fopts) {
+		return cdc.sizeReflectBinary(info, drv, fopts, true, es)
+	}
+	return cdc.sizeFieldIfNotEmpty(keySize, info, fopts, drv, rvIsPtr, false, es)
+}
+
+func (cdc *Codec) sizeReflectBinaryStruct(info *TypeInfo, plan *binaryPlan, rv reflect.Value, fopts FieldOptions, bare bool, es *encodeState) (n int, err error) {
+	var _n int
+	var i = es.push()
+	switch info.Type {
+
+	case timeType:
+		// Special case: time.Time
+		n, err = timeSize(rv.Interface().(time.Time))
+		if err != nil {
+			return
+		}
+
+	default:
+		// Unknown fields are written in between, see writeFieldsBefore.
+		var unknown []byte
+		if info.UnknownFieldsIndex >= 0 {
+			unknown = rv.Field(info.UnknownFieldsIndex).Bytes()
+		}
+		for j := range info.Fields {
+			var field = &info.Fields[j]
+			_n, err = splitFieldsBefore(unknown, field.BinFieldNum)
+			if err != nil {
+				return
+			}
+			n += _n
+			unknown = unknown[_n:]
+			_n, err = cdc.sizeReflectBinaryField(field, &plan.fields[j], rv.Field(field.Index), fopts, es)
+			if err != nil {
+				return
+			}
+			n += _n
+		}
+		_n, err = splitFieldsBefore(unknown, maxFieldNum+1)
+		if err != nil {
+			return
+		}
+		n += _n
+	}
+	es.set(i, sizedValue{size: n})
+
+	if !bare {
+		n += UvarintSize(uint64(n))
+	}
+	return
+}
+
+// See encodeReflectBinaryField.
+func (cdc *Codec) sizeReflectBinaryField(field *FieldInfo, fplan *fieldPlan, frv reflect.Value, fopts FieldOptions, es *encodeState) (n int, err error) {
+	var frvIsPtr = frv.Kind() == reflect.Ptr
+	var dfrv, isDefault = isDefaultValue(frv)
+	if isDefault && !fopts.WriteEmpty {
+		return
+	}
+	if field.UnpackedList {
+		return cdc.sizeReflectBinary(fplan.info, dfrv, field.FieldOptions, true, es)
+	}
+	// write empty if explicitly set or if this is a pointer:
+	writeEmpty := fopts.WriteEmpty || frvIsPtr
+	return cdc.sizeFieldIfNotEmpty(len(fplan.key), fplan.info, field.FieldOptions, dfrv, writeEmpty, false, es)
+}
+
+// Returns the length of the field key and the value, or 0 if the value would
+// be written as a single 0x00 byte (e.g. an empty struct) and isWriteEmpty is
+// false.  This decides for writeFieldIfNotEmpty.
+func (cdc *Codec) sizeFieldIfNotEmpty(keySize int, finfo *TypeInfo, fieldOpts FieldOptions, derefedVal reflect.Value, isWriteEmpty bool, bare bool, es *encodeState) (n int, err error) {
+	var i = es.push()
+	// Handle the repr here, so that an empty repr is omitted too.
+	for finfo.IsAminoMarshaler {
+		derefedVal, err = toReprObject(derefedVal)
+		if err != nil {
+			return
+		}
+		finfo, err = cdc.getTypeInfoWlock(finfo.AminoMarshalReprType)
+		if err != nil {
+			return
+		}
+	}
+	n, err = cdc.sizeReflectBinary(finfo, derefedVal, fieldOpts, bare, es)
+	if err != nil {
+		return
+	}
+	if !isWriteEmpty && n == 1 {
+		// The field is omitted if that one byte is 0x00, which is the case
+		// for the empty ByteLength types (except a non-nil Raw) and for
+		// default values.
+		var isZero = typeToTyp3(finfo.Type, fieldOpts) == Typ3ByteLength && finfo.Type != rawType
+		if !isZero {
+			_, isZero = isDefaultValue(derefedVal)
+		}
+		if isZero {
+			n = 0
+		}
+	}
+	if n > 0 {
+		n += keySize
+	}
+	es.set(i, sizedValue{size: n, info: finfo, rv: derefedVal})
+	return
+}
+
+// Returns the length of the encoding of t, see EncodeTime.
+func timeSize(t time.Time) (n int, err error) {
+	s := t.Unix()
+	if s != 0 {
+		if s < minSeconds || s >= maxSeconds {
+			return 0, InvalidTimeErr(fmt.Sprintf("seconds have to be >= %d and < %d, got: %d",
+				minSeconds, maxSeconds, s))
+		}
+		n += 1 + UvarintSize(uint64(s))
+	}
+	ns := int32(t.Nanosecond())
+	if ns != 0 {
+		n += 1 + UvarintSize(uint64(ns))
+	}
+	return
+}
+
+// Returns the length of the key of field num with type typ, see fieldKey.
+func fieldKeySize(num uint32, typ Typ3) int {
+	return UvarintSize(uint64(num)<<3 | uint64(typ))
+}
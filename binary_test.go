@@ -2,6 +2,8 @@ package amino_test
 
 import (
 	"fmt"
+	"reflect"
+	"sync"
 	"testing"
 	"time"
 
@@ -272,23 +274,114 @@ func TestUnmarshalMapBinary(t *testing.T) {
 	obj := new(map[string]int)
 	cdc := amino.NewCodec()
 
-	// Binary doesn't support decoding to a map...
+	// Invalid bytes result in an error...
 	binBytes := []byte(`dontcare`)
-	assert.Panics(t, func() {
-		err := cdc.UnmarshalBinaryBare(binBytes, &obj)
-		assert.Fail(t, "should have paniced but got err: %v", err)
-	})
+	err := cdc.UnmarshalBinaryBare(binBytes, &obj)
+	assert.Error(t, err)
+	err = cdc.UnmarshalBinaryBare(binBytes, obj)
+	assert.Error(t, err)
 
-	assert.Panics(t, func() {
-		err := cdc.UnmarshalBinaryBare(binBytes, obj)
-		require.Error(t, err)
-	})
+	// ... while maps can be encoded and decoded.
+	*obj = map[string]int{"a": 1, "b": 2}
+	bz, err := cdc.MarshalBinaryBare(obj)
+	require.NoError(t, err)
+	var obj2 map[string]int
+	err = cdc.UnmarshalBinaryBare(bz, &obj2)
+	require.NoError(t, err)
+	assert.Equal(t, *obj, obj2)
+}
 
-	// ... nor encoding it.
-	assert.Panics(t, func() {
-		bz, err := cdc.MarshalBinaryBare(obj)
-		assert.Fail(t, "should have paniced but got bz: %X err: %v", bz, err)
-	})
+func TestMapBinary(t *testing.T) {
+	type Inner struct {
+		A string
+	}
+	type MapsStruct struct {
+		StrInt     map[string]int
+		IntStr     map[int64]string
+		UintBytes  map[uint32][]byte
+		BoolFixed  map[bool]int64 `binary:"fixed64"`
+		StrStruct  map[string]Inner
+		StrPtr     map[string]*Inner
+		StrList    map[string][]Inner
+		StrTime    map[string]time.Time
+		StrMap     map[string]map[string]int
+		ListOfMaps []map[int8]string
+	}
+
+	cdc := amino.NewCodec()
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	s := MapsStruct{
+		StrInt:     map[string]int{"": 1, "a": 0, "b": 2},
+		IntStr:     map[int64]string{-1: "minus one", 0: "zero", 300: "three hundred"},
+		UintBytes:  map[uint32][]byte{1: []byte("one"), 2: nil},
+		BoolFixed:  map[bool]int64{false: -1, true: 1},
+		StrStruct:  map[string]Inner{"x": {"y"}, "z": {}},
+		StrPtr:     map[string]*Inner{"x": {"y"}, "z": {}},
+		StrList:    map[string][]Inner{"x": {{"y"}, {}}},
+		StrTime:    map[string]time.Time{"now": now},
+		StrMap:     map[string]map[string]int{"x": {"y": 1}},
+		ListOfMaps: []map[int8]string{{-1: "a"}, {1: "b"}},
+	}
+	bz, err := cdc.MarshalBinaryBare(s)
+	require.NoError(t, err)
+
+	var s2 MapsStruct
+	err = cdc.UnmarshalBinaryBare(bz, &s2)
+	require.NoError(t, err)
+	assert.Equal(t, s, s2)
+
+	// The encoding doesn't depend on the map iteration order.
+	for i := 0; i < 10; i++ {
+		bz2, err := cdc.MarshalBinaryBare(s2)
+		require.NoError(t, err)
+		assert.Equal(t, bz, bz2)
+	}
+
+	// Empty and nil maps are not encoded.
+	bz, err = cdc.MarshalBinaryBare(MapsStruct{StrInt: map[string]int{}})
+	require.NoError(t, err)
+	assert.Empty(t, bz)
+}
+
+func TestMapBinaryEncoding(t *testing.T) {
+	type MapStruct struct {
+		A int
+		M map[string]uint8
+		B int
+	}
+
+	cdc := amino.NewCodec()
+	bz, err := cdc.MarshalBinaryBare(MapStruct{A: 1, M: map[string]uint8{"b": 2, "a": 1, "": 3}, B: 2})
+	require.NoError(t, err)
+	assert.Equal(t, []byte{
+		0x08, 0x01, // A
+		0x12, 0x02, 0x10, 0x03, // M[""]: key omitted
+		0x12, 0x05, 0x0a, 0x01, 'a', 0x10, 0x01, // M["a"]
+		0x12, 0x05, 0x0a, 0x01, 'b', 0x10, 0x02, // M["b"]
+		0x18, 0x02, // B
+	}, bz)
+
+	// Duplicate or unsorted keys are rejected.
+	var ms MapStruct
+	err = cdc.UnmarshalBinaryBare([]byte{
+		0x12, 0x05, 0x0a, 0x01, 'a', 0x10, 0x01,
+		0x12, 0x05, 0x0a, 0x01, 'a', 0x10, 0x02,
+	}, &ms)
+	assert.Error(t, err, "duplicate keys")
+	err = cdc.UnmarshalBinaryBare([]byte{
+		0x12, 0x05, 0x0a, 0x01, 'b', 0x10, 0x02,
+		0x12, 0x05, 0x0a, 0x01, 'a', 0x10, 0x01,
+	}, &ms)
+	assert.Error(t, err, "unsorted keys")
+	err = cdc.UnmarshalBinaryBare([]byte{
+		0x12, 0x02, 0x10, 0x03,
+		0x12, 0x02, 0x10, 0x03,
+	}, &ms)
+	assert.Error(t, err, "duplicate default keys")
+
+	// Unsupported key types result in an error.
+	_, err = cdc.MarshalBinaryBare(struct{ M map[[2]int]int }{map[[2]int]int{{1, 2}: 3}})
+	assert.Error(t, err)
 }
 
 func TestUnmarshalFuncBinary(t *testing.T) {
@@ -315,3 +408,497 @@ func TestUnmarshalFuncBinary(t *testing.T) {
 		assert.Fail(t, "should have paniced but got bz: %X err: %v", bz, err)
 	})
 }
+
+func TestExplicitFieldNumbers(t *testing.T) {
+	type V1 struct {
+		A string
+		B int64
+		C string
+	}
+	type V2 struct {
+		A string
+		_ struct{} `amino:"reserved=2"` // B was removed.
+		C string   `amino:"field=3"`
+		D int64    // Follows C, so 4.
+		E int64    `amino:"field=10"`
+	}
+
+	cdc := amino.NewCodec()
+
+	bz, err := cdc.MarshalBinaryBare(V2{A: "a", C: "c", D: 4, E: 10})
+	require.NoError(t, err)
+	assert.Equal(t, []byte{0x0a, 0x01, 'a', 0x1a, 0x01, 'c', 0x20, 0x04, 0x50, 0x0a}, bz)
+
+	var v2 V2
+	err = cdc.UnmarshalBinaryBare(bz, &v2)
+	require.NoError(t, err)
+	assert.Equal(t, V2{A: "a", C: "c", D: 4, E: 10}, v2)
+
+	// Bytes written by V1 can still be read by V2, the reserved field is skipped.
+	bz, err = cdc.MarshalBinaryBare(V1{A: "a", B: 2, C: "c"})
+	require.NoError(t, err)
+	v2 = V2{}
+	err = cdc.UnmarshalBinaryBare(bz, &v2)
+	require.NoError(t, err)
+	assert.Equal(t, V2{A: "a", C: "c"}, v2)
+}
+
+func TestInvalidFieldNumbers(t *testing.T) {
+	type Duplicate struct {
+		A string `amino:"field=2"`
+		B string `amino:"field=2"`
+	}
+	type Decreasing struct {
+		A string `amino:"field=2"`
+		B string `amino:"field=1"`
+	}
+	type ImplicitDuplicate struct {
+		A string
+		B string
+		C string `amino:"field=2"`
+	}
+	type Reserved struct {
+		_ struct{} `amino:"reserved=1|2"`
+		A string   `amino:"field=2"`
+	}
+	type Zero struct {
+		A string `amino:"field=0"`
+	}
+	type TooLarge struct {
+		A string `amino:"field=536870912"`
+	}
+	type NotANumber struct {
+		A string `amino:"field=one"`
+	}
+	type InvalidReserved struct {
+		_ struct{} `amino:"reserved=1|x"`
+		A string
+	}
+
+	cdc := amino.NewCodec()
+	for _, o := range []interface{}{
+		Duplicate{},
+		Decreasing{},
+		ImplicitDuplicate{},
+		Reserved{},
+		Zero{},
+		TooLarge{},
+		NotANumber{},
+		InvalidReserved{},
+	} {
+		_, err := cdc.MarshalBinaryBare(o)
+		assert.Error(t, err, "expected error for %T", o)
+		_, err = cdc.MarshalJSON(o)
+		assert.Error(t, err, "expected error for %T", o)
+	}
+
+	// Registration panics instead.
+	assert.Panics(t, func() { cdc.RegisterConcrete(NotANumber{}, "NotANumber", nil) })
+}
+
+func TestUnknownFields(t *testing.T) {
+	type Inner struct {
+		X int
+	}
+	type V2 struct {
+		A string
+		B Inner
+		C []Inner
+		D int64 `binary:"fixed64"`
+		E string
+		F []string
+	}
+	type V1 struct {
+		A       string
+		Unknown amino.UnknownFields
+		_       struct{} `amino:"reserved=2|3|4"`
+		E       string   `amino:"field=5"`
+	}
+
+	cdc := amino.NewCodec()
+	v2 := V2{A: "a", B: Inner{1}, C: []Inner{{2}, {}}, D: -4, E: "e", F: []string{"f", "g"}}
+	bz, err := cdc.MarshalBinaryBare(v2)
+	require.NoError(t, err)
+
+	// Decode with the older version, modify, and re-encode.
+	var v1 V1
+	err = cdc.UnmarshalBinaryBare(bz, &v1)
+	require.NoError(t, err)
+	assert.Equal(t, "a", v1.A)
+	assert.Equal(t, "e", v1.E)
+	assert.NotEmpty(t, v1.Unknown)
+	v1.A, v1.E = "aa", ""
+	bz, err = cdc.MarshalBinaryBare(v1)
+	require.NoError(t, err)
+
+	// Fields unknown to V1 are still there.
+	var v2b V2
+	err = cdc.UnmarshalBinaryBare(bz, &v2b)
+	require.NoError(t, err)
+	v2.A, v2.E = "aa", ""
+	assert.Equal(t, v2, v2b)
+	bz2, err := cdc.MarshalBinaryBare(v2)
+	require.NoError(t, err)
+	assert.Equal(t, bz2, bz)
+
+	// Decoding resets unknown fields, and they are not part of JSON.
+	err = cdc.UnmarshalBinaryBare(cdc.MustMarshalBinaryBare(V1{A: "a"}), &v1)
+	require.NoError(t, err)
+	assert.Nil(t, v1.Unknown)
+	v1.Unknown = amino.UnknownFields{0x10, 0x01}
+	jbz, err := cdc.MarshalJSON(v1)
+	require.NoError(t, err)
+	assert.Equal(t, `{"A":"a","E":""}`, string(jbz))
+
+	// Unknown fields must not conflict with known fields.
+	v1.Unknown = amino.UnknownFields{0x28, 0x01}
+	_, err = cdc.MarshalBinaryBare(v1)
+	assert.Error(t, err)
+}
+
+func TestUnmarshalBinaryBareStrict(t *testing.T) {
+	type S struct {
+		A int8
+		B uint32
+		C string
+	}
+
+	cdc := amino.NewCodec()
+	bz, err := cdc.MarshalBinaryBare(S{A: 1, C: "c"})
+	require.NoError(t, err)
+	assert.Equal(t, []byte{0x08, 0x02, 0x1a, 0x01, 'c'}, bz)
+
+	var s S
+	err = cdc.UnmarshalBinaryBareStrict(bz, &s)
+	require.NoError(t, err)
+	assert.Equal(t, S{A: 1, C: "c"}, s)
+
+	cases := []struct {
+		bz     []byte
+		offset int
+	}{
+		{[]byte{0x08, 0x82, 0x00, 0x1a, 0x01, 'c'}, 1},       // Non-minimal varint.
+		{[]byte{0x08, 0x00, 0x1a, 0x01, 'c'}, 0},             // Explicit default value.
+		{[]byte{0x08, 0x02, 0x10, 0x00, 0x1a, 0x01, 'c'}, 2}, // Explicit default value of B.
+		{[]byte{0x08, 0x02, 0x1a, 0x81, 0x00, 'c'}, 3},       // Non-minimal length prefix.
+		{[]byte{0x08, 0x02, 0x1a, 0x01, 'c', 0x20, 0x01}, 5}, // Unknown field.
+	}
+	for i, tc := range cases {
+		// Accepted by the lenient decoder.
+		err = cdc.UnmarshalBinaryBare(tc.bz, &s)
+		require.NoError(t, err, "case %v", i)
+
+		// Rejected by the strict decoder, which leaves s as is.
+		s = S{C: "x"}
+		err = cdc.UnmarshalBinaryBareStrict(tc.bz, &s)
+		require.Error(t, err, "case %v", i)
+		assert.Equal(t, amino.NonCanonicalErr{Offset: tc.offset}, err, "case %v", i)
+		assert.Equal(t, S{C: "x"}, s, "case %v", i)
+	}
+
+	// Fields that are always written, like arrays, must not be missing.
+	type Arr struct {
+		A [2]int8
+		B string
+	}
+	err = cdc.UnmarshalBinaryBareStrict([]byte{0x12, 0x01, 'b'}, new(Arr))
+	assert.Equal(t, amino.NonCanonicalErr{Offset: 0}, err)
+	err = cdc.UnmarshalBinaryBareStrict(cdc.MustMarshalBinaryBare(Arr{B: "b"}), new(Arr))
+	assert.NoError(t, err)
+
+	// Nested values and time are checked too.
+	type Nested struct {
+		S S
+		T time.Time
+	}
+	nbz, err := cdc.MarshalBinaryBare(Nested{S: S{B: 1}, T: time.Unix(1, 0).UTC()})
+	require.NoError(t, err)
+	assert.Equal(t, []byte{0x0a, 0x02, 0x10, 0x01, 0x12, 0x02, 0x08, 0x01}, nbz)
+	err = cdc.UnmarshalBinaryBareStrict(nbz, new(Nested))
+	assert.NoError(t, err)
+	err = cdc.UnmarshalBinaryBareStrict([]byte{0x0a, 0x04, 0x08, 0x00, 0x10, 0x01, 0x12, 0x02, 0x08, 0x01}, new(Nested))
+	assert.Equal(t, amino.NonCanonicalErr{Offset: 2}, err)
+	err = cdc.UnmarshalBinaryBareStrict([]byte{0x0a, 0x02, 0x10, 0x01, 0x12, 0x04, 0x08, 0x01, 0x10, 0x00}, new(Nested))
+	assert.Equal(t, amino.NonCanonicalErr{Offset: 6}, err)
+
+	// Out-of-range varints are rejected even by the lenient decoder.
+	err = cdc.UnmarshalBinaryBare([]byte{0x10, 0x80, 0x80, 0x80, 0x80, 0x10}, &s)
+	assert.Error(t, err)
+	err = cdc.UnmarshalBinaryBare([]byte{0x08, 0x80, 0x02}, &s)
+	assert.Error(t, err)
+
+	// The length prefix must be minimal too.
+	err = cdc.UnmarshalBinaryLengthPrefixedStrict(append([]byte{0x05}, bz...), &s)
+	assert.NoError(t, err)
+	err = cdc.UnmarshalBinaryLengthPrefixedStrict(append([]byte{0x85, 0x00}, bz...), &s)
+	assert.Equal(t, amino.NonCanonicalErr{Offset: 0}, err)
+	err = cdc.UnmarshalBinaryLengthPrefixedStrict(append([]byte{0x06}, append(bz, 0x00)...), &s)
+	assert.Error(t, err)
+}
+
+func TestBinaryConcurrent(t *testing.T) {
+	type Inner struct {
+		A string
+		B []int64
+	}
+	type Outer struct {
+		Inner  Inner
+		Inners []*Inner
+		Map    map[string]Inner
+		Time   time.Time
+	}
+
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	s := Outer{
+		Inner:  Inner{"a", []int64{1, -1}},
+		Inners: []*Inner{{A: "b"}, {B: []int64{2}}},
+		Map:    map[string]Inner{"c": {A: "d"}},
+		Time:   now,
+	}
+	bz, err := amino.NewCodec().MarshalBinaryLengthPrefixed(s)
+	require.NoError(t, err)
+
+	// Types are compiled on first use, concurrently here.
+	cdc := amino.NewCodec()
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			bz2, err := cdc.MarshalBinaryLengthPrefixed(s)
+			assert.NoError(t, err)
+			assert.Equal(t, bz, bz2)
+
+			var s2 Outer
+			err = cdc.UnmarshalBinaryLengthPrefixed(bz, &s2)
+			assert.NoError(t, err)
+			assert.Equal(t, s, s2)
+		}()
+	}
+	wg.Wait()
+}
+
+func TestAppendBinary(t *testing.T) {
+	type Inner struct {
+		A string
+		B int64
+	}
+	type Outer struct {
+		X  uint32
+		In Inner
+		L  []Inner
+	}
+
+	cdc := amino.NewCodec()
+	o := Outer{X: 5, In: Inner{"a", 300}, L: []Inner{{"b", 1}, {"c", -2}}}
+	bz, err := cdc.MarshalBinaryBare(o)
+	require.NoError(t, err)
+	lbz, err := cdc.MarshalBinaryLengthPrefixed(o)
+	require.NoError(t, err)
+
+	// The encoding is appended after the existing bytes.
+	dst := append(make([]byte, 0, 256), 0xFF)
+	dst, err = cdc.AppendBinaryBare(dst, o)
+	require.NoError(t, err)
+	assert.Equal(t, append([]byte{0xFF}, bz...), dst)
+	dst, err = cdc.AppendBinaryLengthPrefixed(dst, &o)
+	require.NoError(t, err)
+	assert.Equal(t, append(append([]byte{0xFF}, bz...), lbz...), dst)
+
+	// Reusing dst doesn't reallocate.
+	dst2, err := cdc.AppendBinaryBare(dst[:0], o)
+	require.NoError(t, err)
+	assert.Equal(t, bz, dst2)
+	assert.Equal(t, &dst[0], &dst2[0])
+
+	// On error, dst is returned unchanged.
+	type Unregistered struct{ I interface{ Foo() } }
+	dst, err = cdc.AppendBinaryBare([]byte{0xFF}, Unregistered{})
+	assert.Error(t, err)
+	assert.Equal(t, []byte{0xFF}, dst)
+}
+
+// Encodes as an integer, so a zero Celsius is omitted like a zero int.
+type Celsius struct{ Degrees int64 }
+
+func (c Celsius) MarshalAmino() (int64, error) { return c.Degrees, nil }
+
+func (c *Celsius) UnmarshalAmino(degrees int64) error {
+	c.Degrees = degrees
+	return nil
+}
+
+func TestSizeBinary(t *testing.T) {
+	type Msg interface{}
+	type MsgA struct{ A string }
+	type MsgB struct{ B []byte }
+	type Inner struct {
+		I int8
+		U uint16 `amino:"write_empty"`
+	}
+	type Outer struct {
+		Msg      Msg
+		Msgs     []Msg
+		Time     time.Time
+		TimePtr  *time.Time
+		Inners   []Inner
+		InnerPtr []*Inner
+		Ints     []int32 `binary:"fixed32"`
+		Map      map[string]Inner
+		Temp     Celsius
+		Temps    []Celsius
+		Bytes    [3]byte
+		Fixed    uint64  `binary:"fixed64"`
+		Float    float64 `amino:"unsafe"`
+		Unknown  amino.UnknownFields
+	}
+
+	cdc := amino.NewCodec()
+	cdc.RegisterInterface((*Msg)(nil), &amino.InterfaceOptions{AlwaysDisambiguate: true})
+	cdc.RegisterConcrete(MsgA{}, "amino_test/MsgA", nil)
+	cdc.RegisterConcrete(&MsgB{}, "amino_test/MsgB", nil)
+
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	cases := []interface{}{
+		Outer{},
+		Outer{
+			Msg:      MsgA{"a"},
+			Msgs:     []Msg{MsgA{}, &MsgB{[]byte("b")}, nil},
+			Time:     now,
+			TimePtr:  &time.Time{},
+			Inners:   []Inner{{}, {I: -1, U: 300}},
+			InnerPtr: []*Inner{nil, {I: 1}},
+			Ints:     []int32{0, -1, 1 << 20},
+			Map:      map[string]Inner{"": {}, "x": {I: 2}},
+			Temp:     Celsius{0},
+			Temps:    []Celsius{{0}, {-40}},
+			Bytes:    [3]byte{1},
+			Fixed:    1,
+			Float:    0.5,
+			Unknown:  []byte{0xFA, 0x07, 0x01, 0x00}, // Field 127, a byte slice.
+		},
+		MsgA{"a"},
+		&MsgB{},
+		Celsius{0},
+		Celsius{100},
+		int64(-1),
+		"",
+		[]Inner{{I: 1}},
+		[]*MsgA{{"a"}, nil},
+		map[int8]string{1: "a"},
+	}
+	for i, o := range cases {
+		bz, err := cdc.MarshalBinaryBare(o)
+		require.NoError(t, err, "case %v", i)
+		size, err := cdc.SizeBinaryBare(o)
+		require.NoError(t, err, "case %v", i)
+		assert.Equal(t, len(bz), size, "case %v", i)
+
+		bz, err = cdc.MarshalBinaryLengthPrefixed(o)
+		require.NoError(t, err, "case %v", i)
+		size, err = cdc.SizeBinaryLengthPrefixed(o)
+		require.NoError(t, err, "case %v", i)
+		assert.Equal(t, len(bz), size, "case %v", i)
+	}
+
+	// Errors are the same as when encoding.
+	_, err := cdc.SizeBinaryBare(struct{ T time.Time }{time.Date(10000, 1, 1, 0, 0, 0, 0, time.UTC)})
+	assert.Error(t, err)
+	_, err = cdc.SizeBinaryBare(struct{ M Msg }{struct{}{}})
+	assert.Error(t, err)
+}
+
+func TestNestedLists(t *testing.T) {
+	type Inner struct{ I int8 }
+	type Lists struct {
+		Ints    [][]int64
+		Strs    [][]string
+		Structs [][]Inner
+		Arr     [2][]int64
+		Deep    [][][]int64
+	}
+	cdc := amino.NewCodec()
+
+	// Each inner list is a message with the list as field 1.
+	bz, err := cdc.MarshalBinaryBare(struct{ L [][]int64 }{[][]int64{{1, 2}, {}, {3}}})
+	require.NoError(t, err)
+	assert.Equal(t, []byte{0x0A, 0x04, 0x0A, 0x02, 0x01, 0x02, 0x0A, 0x00, 0x0A, 0x03, 0x0A, 0x01, 0x03}, bz)
+
+	l := Lists{
+		Ints:    [][]int64{{1, -2}, {}, {3}},
+		Strs:    [][]string{{"a", ""}, {"c"}},
+		Structs: [][]Inner{{{1}, {2}}, {{3}}},
+		Arr:     [2][]int64{{4}, {5, 6}},
+		Deep:    [][][]int64{{{7}, {8, 9}}, {{10}}},
+	}
+	bz, err = cdc.MarshalBinaryBare(l)
+	require.NoError(t, err)
+	size, err := cdc.SizeBinaryBare(l)
+	require.NoError(t, err)
+	assert.Equal(t, len(bz), size)
+
+	var l2 Lists
+	err = cdc.UnmarshalBinaryBare(bz, &l2)
+	require.NoError(t, err)
+	l.Ints[1] = nil // Empty inner lists decode as nil.
+	assert.Equal(t, l, l2)
+
+	var v int64
+	err = cdc.QueryBinary(bz, reflect.TypeOf(l), "Deep[0][1][1]", &v)
+	require.NoError(t, err)
+	assert.Equal(t, int64(9), v)
+
+	// Top-level nested lists round-trip too.
+	for i, o := range []interface{}{
+		[][]int64{{1}, {2, 3}},
+		[][2]int64{{1, 2}, {3, 4}},
+		[][]string{{"x"}, {"y", "z"}},
+		[][]byte{{1}, {2, 3}},
+	} {
+		bz, err := cdc.MarshalBinaryBare(o)
+		require.NoError(t, err, "case %v", i)
+		ptr := reflect.New(reflect.TypeOf(o))
+		err = cdc.UnmarshalBinaryBare(bz, ptr.Interface())
+		require.NoError(t, err, "case %v", i)
+		assert.Equal(t, o, ptr.Elem().Interface(), "case %v", i)
+	}
+}
+
+func TestBinaryEnum(t *testing.T) {
+	var cdc = newEnumCodec(true)
+	var vt = VoteTypePrecommit
+	var b = Ballot{
+		Type:  VoteTypePrevote,
+		Types: []VoteType{VoteTypePrevote, VoteTypePrecommit},
+		Dir:   Direction(-1),
+		Ptr:   &vt,
+	}
+
+	// Enums are encoded like other integers.
+	bz, err := cdc.MarshalBinaryBare(b)
+	require.NoError(t, err)
+	assert.Equal(t, amino.NewCodec().MustMarshalBinaryBare(b), bz)
+	var b2 Ballot
+	err = cdc.UnmarshalBinaryBare(bz, &b2)
+	require.NoError(t, err)
+	assert.Equal(t, b, b2)
+
+	// Undefined values of strict enums fail to decode, except zero.
+	for _, o := range []Ballot{{Type: VoteType(3)}, {Types: []VoteType{1, 3}}} {
+		bz, err = cdc.MarshalBinaryBare(o)
+		require.NoError(t, err)
+		err = cdc.UnmarshalBinaryBare(bz, &b2)
+		if assert.IsType(t, &amino.DecodeError{}, err) {
+			assert.Equal(t, amino.ErrKindUndefinedEnum, err.(*amino.DecodeError).Kind)
+		}
+		err = newEnumCodec(false).UnmarshalBinaryBare(bz, &b2)
+		assert.NoError(t, err)
+		assert.Equal(t, o, b2)
+	}
+	bz, err = cdc.MarshalBinaryBare([]VoteType{0, 1})
+	require.NoError(t, err)
+	var vts []VoteType
+	err = cdc.UnmarshalBinaryBare(bz, &vts)
+	assert.NoError(t, err)
+	assert.Equal(t, []VoteType{0, 1}, vts)
+}
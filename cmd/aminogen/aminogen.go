@@ -0,0 +1,204 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// aminogen generates MarshalAminoBinary, AminoSize and UnmarshalAminoBinary
+// methods for the given struct types of a package, which the amino Codec then
+// uses instead of reflection.  See amino.Codec.GenerateBinary.
+//
+// Usage:
+//
+//	aminogen -type=Foo,Bar [-output=amino_gen.go] [dir]
+//
+// or in a Go file of the package:
+//
+//	//go:generate go run github.com/tendermint/go-amino/cmd/aminogen -type=Foo,Bar
+//
+// aminogen builds and runs a small program that imports the package, so the
+// package must build, and it must not be a main package.  Any previously
+// generated file is set aside while doing so, so that it need not be up to
+// date.
+func main() {
+	var typeNames, output string
+	flgs := flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	flgs.StringVar(&typeNames, "type", "", "Comma-separated list of struct type names; required.")
+	flgs.StringVar(&output, "output", "amino_gen.go", "Output file name, relative to the package directory.")
+	err := flgs.Parse(os.Args[1:])
+	if err != nil {
+		fatal(err)
+	}
+	if typeNames == "" {
+		fatal("Usage: aminogen -type=Foo,Bar [-output=amino_gen.go] [dir]")
+	}
+	var dir = "."
+	if flgs.NArg() > 0 {
+		dir = flgs.Arg(0)
+	}
+	output = filepath.Join(dir, output)
+
+	err = generate(dir, strings.Split(typeNames, ","), output)
+	if err != nil {
+		fatal(err)
+	}
+}
+
+func fatal(v interface{}) {
+	fmt.Fprintln(os.Stderr, "aminogen:", v)
+	os.Exit(1)
+}
+
+func generate(dir string, typeNames []string, output string) error {
+	// Find the package and check that the types exist.
+	importPath, err := goList(dir)
+	if err != nil {
+		return err
+	}
+	pkgName, err := checkStructTypes(dir, typeNames, output)
+	if err != nil {
+		return err
+	}
+	if pkgName == "main" {
+		return fmt.Errorf("cannot generate code for package main")
+	}
+
+	// Set aside the previously generated file, if any.
+	var orig = output + ".orig"
+	if _, err := os.Stat(output); err == nil {
+		if err := os.Rename(output, orig); err != nil {
+			return err
+		}
+		defer func() {
+			// Restore it on failure.
+			if _, err := os.Stat(output); os.IsNotExist(err) {
+				os.Rename(orig, output) // nolint: errcheck
+			} else {
+				os.Remove(orig) // nolint: errcheck
+			}
+		}()
+	}
+
+	// Write and run the program that generates the code.
+	tmp, err := ioutil.TempFile("", "aminogen")
+	if err != nil {
+		return err
+	}
+	tmp.Close()                 // nolint: errcheck
+	defer os.Remove(tmp.Name()) // nolint: errcheck
+	bootstrap := filepath.Join(dir, "aminogen_bootstrap.go")
+	src := bootstrapSource(importPath, pkgName, typeNames, tmp.Name())
+	if err := ioutil.WriteFile(bootstrap, src, 0644); err != nil {
+		return err
+	}
+	defer os.Remove(bootstrap) // nolint: errcheck
+	cmd := exec.Command("go", "run", filepath.Base(bootstrap))
+	cmd.Dir = dir
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return err
+	}
+
+	code, err := ioutil.ReadFile(tmp.Name())
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(output, code, 0644)
+}
+
+// Returns the import path of the package in dir.
+func goList(dir string) (string, error) {
+	var stderr bytes.Buffer
+	cmd := exec.Command("go", "list", "-f", "{{.ImportPath}}", ".")
+	cmd.Dir = dir
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("go list failed: %v\n%s", err, stderr.Bytes())
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// Parses the package in dir, except for output and tests, and checks that it
+// declares the struct types.  Returns the package name.
+func checkStructTypes(dir string, typeNames []string, output string) (string, error) {
+	var fset = token.NewFileSet()
+	var filter = func(fi os.FileInfo) bool {
+		return !strings.HasSuffix(fi.Name(), "_test.go") && fi.Name() != filepath.Base(output)
+	}
+	pkgs, err := parser.ParseDir(fset, dir, filter, 0)
+	if err != nil {
+		return "", err
+	}
+	if len(pkgs) != 1 {
+		return "", fmt.Errorf("expected one package in %v, found %v", dir, len(pkgs))
+	}
+	for _, pkg := range pkgs {
+		var structs = make(map[string]bool)
+		for _, file := range pkg.Files {
+			for _, decl := range file.Decls {
+				gd, ok := decl.(*ast.GenDecl)
+				if !ok || gd.Tok != token.TYPE {
+					continue
+				}
+				for _, spec := range gd.Specs {
+					ts := spec.(*ast.TypeSpec)
+					if _, ok := ts.Type.(*ast.StructType); ok {
+						structs[ts.Name.Name] = true
+					}
+				}
+			}
+		}
+		for _, name := range typeNames {
+			if !structs[name] {
+				return "", fmt.Errorf("no struct type %v in package %v", name, pkg.Name)
+			}
+		}
+		return pkg.Name, nil
+	}
+	panic("should not happen")
+}
+
+func bootstrapSource(importPath, pkgName string, typeNames []string, output string) []byte {
+	var objs = make([]string, len(typeNames))
+	for i, name := range typeNames {
+		objs[i] = fmt.Sprintf("pkg.%v{}", name)
+	}
+	return []byte(fmt.Sprintf(`// +build ignore
+
+package main
+
+import (
+	"fmt"
+	"os"
+
+	amino "github.com/tendermint/go-amino"
+	pkg %q
+)
+
+func main() {
+	f, err := os.Create(%q)
+	if err == nil {
+		err = amino.NewCodec().GenerateBinary(f, %q, %v)
+	}
+	if err == nil {
+		err = f.Close()
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+`, importPath, output, pkgName, strings.Join(objs, ", ")))
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"reflect"
+
+	amino "github.com/tendermint/go-amino"
+	"github.com/tendermint/go-amino/tests"
+)
+
+// aminoproto prints the proto3 schema of the types in
+// github.com/tendermint/go-amino/tests, as exported by Codec.ExportProto3.
+//
+// Applications can do the same for their own types by copying this command
+// and registering their own interfaces and concrete types instead.
+func main() {
+	var pkg string
+	flgs := flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	flgs.StringVar(&pkg, "pkg", "amino_tests", "The proto3 package name.")
+	err := flgs.Parse(os.Args[1:])
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	cdc := amino.NewCodec()
+	cdc.RegisterInterface((*tests.Interface1)(nil), nil)
+	cdc.RegisterInterface((*tests.Interface2)(nil), nil)
+	cdc.RegisterConcrete(tests.Concrete1{}, "tests.Concrete1", nil)
+	cdc.RegisterConcrete(tests.Concrete2{}, "tests.Concrete2", nil)
+	cdc.RegisterConcrete(tests.ConcreteTypeDef{}, "tests.ConcreteTypeDef", nil)
+	cdc.RegisterConcrete(tests.ConcreteWrappedBytes{}, "tests.ConcreteWrappedBytes", nil)
+	cdc.RegisterConcrete(&tests.InterfaceFieldsStruct{}, "tests.InterfaceFieldsStruct", nil)
+	for _, ptr := range append(tests.StructTypes, tests.DefTypes...) {
+		rt := reflect.TypeOf(ptr).Elem()
+		cdc.RegisterConcrete(reflect.Zero(rt).Interface(), "tests."+rt.Name(), nil)
+	}
+
+	err = cdc.ExportProto3(os.Stdout, pkg)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
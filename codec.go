@@ -6,8 +6,11 @@ import (
 	"fmt"
 	"io"
 	"reflect"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"unicode"
 
 	"github.com/pkg/errors"
@@ -60,6 +63,9 @@ type TypeInfo struct {
 	InterfaceInfo
 	ConcreteInfo
 	StructInfo
+	EnumInfo
+
+	binaryPlan atomic.Value // *binaryPlan, see getBinaryPlan.
 }
 
 type InterfaceInfo struct {
@@ -69,8 +75,10 @@ type InterfaceInfo struct {
 }
 
 type InterfaceOptions struct {
-	Priority           []string // Disamb priority.
-	AlwaysDisambiguate bool     // If true, include disamb for all types.
+	Priority             []string // Disamb priority.
+	AlwaysDisambiguate   bool     // If true, include disamb for all types.
+	AllowUnknownConcrete bool     // If true, decode unknown types to UnknownConcrete if it implements the interface.
+	Allow                []string // If set, names of the only concrete types to decode.
 }
 
 type ConcreteInfo struct {
@@ -90,16 +98,40 @@ type ConcreteInfo struct {
 	AminoMarshalReprType   reflect.Type // <ReprType>
 	IsAminoUnmarshaler     bool         // Implements UnmarshalAmino(<ReprObject>) (error).
 	AminoUnmarshalReprType reflect.Type // <ReprType>
+
+	// Set for structs with generated code, see cmd/aminogen.
+	IsAminoBinaryMarshaler   bool // Implements AminoBinaryMarshaler.
+	IsAminoBinaryUnmarshaler bool // Pointer implements AminoBinaryUnmarshaler.
 }
 
 type StructInfo struct {
-	Fields []FieldInfo // If a struct.
+	Fields             []FieldInfo // If a struct.
+	Reserved           []uint32    // Field numbers that must not be used, sorted.
+	UnknownFieldsIndex int         // Index of the UnknownFields field, or -1.
+}
+
+type EnumInfo struct {
+	IsEnum     bool              // Registered with RegisterEnum().
+	EnumNames  map[uint64]string // Names by value, see enumBits.
+	EnumValues map[string]uint64 // Values by name, see enumBits.
+	EnumOptions
+}
+
+type EnumOptions struct {
+	Strict bool // If true, undefined values other than zero fail to decode.
 }
 
 func (cinfo ConcreteInfo) GetDisfix() DisfixBytes {
 	return toDisfix(cinfo.Disamb, cinfo.Prefix)
 }
 
+// Returns whether top-level values of the type are wrapped with the registered
+// name, e.g. by MarshalBinaryBare.  Top-level values of an interface type are
+// not, even if the interface-pointer was registered, see RegisterConcrete.
+func (info *TypeInfo) isTopLevelRegistered() bool {
+	return info.Registered && info.Type.Kind() != reflect.Interface
+}
+
 type ConcreteOptions struct {
 }
 
@@ -117,33 +149,58 @@ type FieldOptions struct {
 	JSONOmitEmpty bool   // (JSON) omitempty
 	BinFixed64    bool   // (Binary) Encode as fixed64
 	BinFixed32    bool   // (Binary) Encode as fixed32
-	BinFieldNum   uint32 // (Binary) max 1<<29-1
+	BinFieldNum   uint32 // (Binary) max 1<<29-1, see `amino:"field=<num>"`
 
 	Unsafe        bool // e.g. if this field is a float.
 	WriteEmpty    bool // write empty structs and lists (default false except for pointers)
 	EmptyElements bool // Slice and Array elements are never nil, decode 0x00 as empty struct.
+
+	Allow []string // (Interface) names of the only concrete types to decode, see `amino:"allow=<name>|<name>..."`
 }
 
 //----------------------------------------
 // Codec
 
 type Codec struct {
-	mtx              sync.RWMutex
-	sealed           bool
-	typeInfos        map[reflect.Type]*TypeInfo
-	interfaceInfos   []*TypeInfo
-	concreteInfos    []*TypeInfo
-	disfixToTypeInfo map[DisfixBytes]*TypeInfo
-	nameToTypeInfo   map[string]*TypeInfo
+	mtx               sync.RWMutex
+	sealed            bool
+	typeInfos         map[reflect.Type]*TypeInfo
+	interfaceInfos    []*TypeInfo
+	concreteInfos     []*TypeInfo
+	disfixToTypeInfo  map[DisfixBytes]*TypeInfo
+	nameToTypeInfo    map[string]*TypeInfo
+	prefixToTypeInfos map[PrefixBytes][]*TypeInfo
+	decodeLimits      DecodeLimits
+	checkGenerated    bool
+	snapshot          atomic.Value // *codecSnapshot, once sealed.
+}
+
+// A codecSnapshot is an immutable copy of the state of a sealed Codec, so
+// that lookups don't need to take the lock.  Changes, i.e. newly discovered
+// unregistered types, are made under the lock and published as a new
+// snapshot.  Since a sealed codec has all the types it registers, these are
+// few, and kept apart from the (large) copy made by Seal.
+type codecSnapshot struct {
+	typeInfos         map[reflect.Type]*TypeInfo // As of Seal.
+	newTypeInfos      map[reflect.Type]*TypeInfo // Since Seal, copied on write.
+	disfixToTypeInfo  map[DisfixBytes]*TypeInfo
+	nameToTypeInfo    map[string]*TypeInfo
+	prefixToTypeInfos map[PrefixBytes][]*TypeInfo
+	decodeLimits      DecodeLimits
+	checkGenerated    bool
 }
 
 func NewCodec() *Codec {
 	cdc := &Codec{
-		sealed:           false,
-		typeInfos:        make(map[reflect.Type]*TypeInfo),
-		disfixToTypeInfo: make(map[DisfixBytes]*TypeInfo),
-		nameToTypeInfo:   make(map[string]*TypeInfo),
+		sealed:            false,
+		typeInfos:         make(map[reflect.Type]*TypeInfo),
+		disfixToTypeInfo:  make(map[DisfixBytes]*TypeInfo),
+		nameToTypeInfo:    make(map[string]*TypeInfo),
+		prefixToTypeInfos: make(map[PrefixBytes][]*TypeInfo),
 	}
+	// interface{} is implemented by all registered concrete types, see
+	// setTypeInfoNolock.
+	cdc.typeInfos[emptyInterfaceType] = cdc.newTypeInfoFromInterfaceType(emptyInterfaceType, nil)
 	return cdc
 }
 
@@ -163,6 +220,17 @@ func (cdc *Codec) RegisterInterface(ptr interface{}, iopts *InterfaceOptions) {
 	// Construct InterfaceInfo
 	var info = cdc.newTypeInfoFromInterfaceType(rt, iopts)
 
+	// interface{} is registered by NewCodec, only set its options.  Concrete
+	// types with conflicting prefix bytes are always disambiguated.
+	if rt == emptyInterfaceType {
+		cdc.mtx.Lock()
+		defer cdc.mtx.Unlock()
+		var existing = cdc.typeInfos[rt]
+		existing.Priority = info.Priority
+		existing.InterfaceOptions = info.InterfaceOptions
+		return
+	}
+
 	// Finally, check conflicts and register.
 	func() {
 		cdc.mtx.Lock()
@@ -206,6 +274,10 @@ func (cdc *Codec) RegisterInterface(ptr interface{}, iopts *InterfaceOptions) {
 // interface fields/elements to be encoded/decoded by go-amino.
 // Usage:
 // `amino.RegisterConcrete(MyStruct1{}, "com.tendermint/MyStruct1", nil)`
+//
+// Pointers to registered interfaces can be registered too, e.g.
+// `(*MyInterface)(nil)`, for *MyInterface values in other interfaces, which
+// are encoded as the prefix followed by the encoding of the interface value.
 func (cdc *Codec) RegisterConcrete(o interface{}, name string, copts *ConcreteOptions) {
 	cdc.assertNotSealed()
 
@@ -222,34 +294,222 @@ func (cdc *Codec) RegisterConcrete(o interface{}, name string, copts *ConcreteOp
 			// We can encode/decode pointer-pointers, but not register them.
 			panic(fmt.Sprintf("registering pointer-pointers not yet supported: *%v", rt))
 		}
-		if rt.Kind() == reflect.Interface {
-			// MARKER: No interface-pointers
-			panic(fmt.Sprintf("registering interface-pointers not yet supported: *%v", rt))
-		}
 		pointerPreferred = true
 	}
 
 	// Construct ConcreteInfo.
-	var info = cdc.newTypeInfoFromRegisteredConcreteType(rt, pointerPreferred, name, copts)
+	info, err := cdc.newTypeInfoFromRegisteredConcreteType(rt, pointerPreferred, name, copts)
+	if err != nil {
+		panic(err)
+	}
 
 	// Finally, check conflicts and register.
 	func() {
 		cdc.mtx.Lock()
 		defer cdc.mtx.Unlock()
 
-		cdc.addCheckConflictsWithConcreteNolock(info)
+		// Register an enum registered earlier, or the interface of an
+		// interface-pointer, in place, as the plans of other types may
+		// already refer to its type info.
+		var existing, inPlace = cdc.typeInfos[rt]
+		if rt.Kind() == reflect.Interface && !inPlace {
+			panic(fmt.Sprintf("cannot register interface-pointer *%v, register the interface first", rt))
+		}
+		inPlace = inPlace && !existing.Registered && (existing.IsEnum || rt.Kind() == reflect.Interface)
+		if !inPlace {
+			cdc.addCheckConflictsWithConcreteNolock(info)
+			cdc.setTypeInfoNolock(info)
+			return
+		}
+		var unregistered = existing.ConcreteInfo
+		defer func() {
+			if r := recover(); r != nil {
+				existing.ConcreteInfo = unregistered
+				panic(r)
+			}
+		}()
+		existing.ConcreteInfo = info.ConcreteInfo
+		cdc.addCheckConflictsWithConcreteNolock(existing)
+		cdc.setConcreteInfoNolock(existing)
+		cdc.addToSnapshotNolock(existing)
+	}()
+}
+
+// This function should be used to register integer types with named values,
+// to encode them in Amino:JSON as their names rather than as numbers.  The
+// Amino:binary encoding is not affected.  Register enums before using them,
+// and before registering them with RegisterConcrete, if at all.
+// Usage:
+// `amino.RegisterEnum(VoteType(0), map[VoteType]string{VoteTypePrevote: "Prevote"}, nil)`
+func (cdc *Codec) RegisterEnum(o interface{}, names interface{}, eopts *EnumOptions) {
+	cdc.assertNotSealed()
+
+	// Get reflect.Type.
+	rt := reflect.TypeOf(o)
+	switch rt.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+	default:
+		panic(fmt.Sprintf("RegisterEnum expects an integer type, got %v", rt))
+	}
+	nrv := reflect.ValueOf(names)
+	if nrv.Kind() != reflect.Map || nrv.Type().Key() != rt || nrv.Type().Elem().Kind() != reflect.String {
+		panic(fmt.Sprintf("RegisterEnum expects names of type map[%v]string, got %T", rt, names))
+	}
+
+	// Construct EnumInfo.
+	var info, err = cdc.newTypeInfoUnregistered(rt)
+	if err != nil {
+		panic(err)
+	}
+	if info.IsAminoMarshaler || info.IsAminoUnmarshaler {
+		panic(fmt.Sprintf("cannot register %v as an enum, it implements (Un)MarshalAmino", rt))
+	}
+	info.EnumInfo.IsEnum = true
+	info.EnumInfo.EnumNames = make(map[uint64]string, nrv.Len())
+	info.EnumInfo.EnumValues = make(map[string]uint64, nrv.Len())
+	for _, krv := range nrv.MapKeys() {
+		var name = nrv.MapIndex(krv).String()
+		if !isEnumName(name) {
+			panic(fmt.Sprintf("invalid name %q for %v, must be a letter or _ followed by letters, digits or _", name, rt))
+		}
+		if _, ok := info.EnumValues[name]; ok {
+			panic(fmt.Sprintf("duplicate name %q for %v", name, rt))
+		}
+		info.EnumNames[enumBits(krv)] = name
+		info.EnumValues[name] = enumBits(krv)
+	}
+	if eopts != nil {
+		info.EnumOptions = *eopts
+	}
+
+	// Finally, register.
+	func() {
+		cdc.mtx.Lock()
+		defer cdc.mtx.Unlock()
+
+		// Codecs already using the type info, e.g. in plans of other types,
+		// wouldn't see the names.
+		if _, ok := cdc.typeInfos[rt]; ok {
+			panic(fmt.Sprintf("cannot register %v as an enum, it was already used or registered: "+
+				"RegisterEnum must be called before the type is used", rt))
+		}
 		cdc.setTypeInfoNolock(info)
 	}()
 }
 
+// Seal prevents further registrations.  Lookups in a sealed codec don't lock,
+// so seal codecs that are used concurrently.
 func (cdc *Codec) Seal() *Codec {
 	cdc.mtx.Lock()
 	defer cdc.mtx.Unlock()
 
 	cdc.sealed = true
+	cdc.updateSnapshotNolock()
+	return cdc
+}
+
+// Returns the snapshot of a sealed codec, or nil.
+func (cdc *Codec) getSnapshot() *codecSnapshot {
+	snap, _ := cdc.snapshot.Load().(*codecSnapshot)
+	return snap
+}
+
+// Returns the type info for rt, see getTypeInfoWlock.
+func (snap *codecSnapshot) getTypeInfo(rt reflect.Type) (info *TypeInfo, ok bool) {
+	if info, ok = snap.typeInfos[rt]; !ok {
+		info, ok = snap.newTypeInfos[rt]
+	}
+	return
+}
+
+// Publishes a copy of the current state, if sealed.
+func (cdc *Codec) updateSnapshotNolock() {
+	if !cdc.sealed {
+		return
+	}
+	if old := cdc.getSnapshot(); old != nil {
+		// Only the settings changed.
+		snap := *old
+		snap.decodeLimits = cdc.decodeLimits
+		snap.checkGenerated = cdc.checkGenerated
+		cdc.snapshot.Store(&snap)
+		return
+	}
+	cdc.snapshot.Store(cdc.newSnapshotNolock())
+}
+
+// Returns a copy of the current state.
+func (cdc *Codec) newSnapshotNolock() *codecSnapshot {
+	snap := &codecSnapshot{
+		typeInfos:         make(map[reflect.Type]*TypeInfo, len(cdc.typeInfos)),
+		disfixToTypeInfo:  make(map[DisfixBytes]*TypeInfo, len(cdc.disfixToTypeInfo)),
+		nameToTypeInfo:    make(map[string]*TypeInfo, len(cdc.nameToTypeInfo)),
+		prefixToTypeInfos: make(map[PrefixBytes][]*TypeInfo, len(cdc.prefixToTypeInfos)),
+		decodeLimits:      cdc.decodeLimits,
+		checkGenerated:    cdc.checkGenerated,
+	}
+	for rt, info := range cdc.typeInfos {
+		snap.typeInfos[rt] = info
+	}
+	for df, info := range cdc.disfixToTypeInfo {
+		snap.disfixToTypeInfo[df] = info
+	}
+	for name, info := range cdc.nameToTypeInfo {
+		snap.nameToTypeInfo[name] = info
+	}
+	for pb, infos := range cdc.prefixToTypeInfos {
+		snap.prefixToTypeInfos[pb] = infos
+	}
+	return snap
+}
+
+// SetDecodeLimits sets the limits for all decoding done by this codec, unless
+// overridden for a single call, e.g. with UnmarshalBinaryBareWithLimits.
+func (cdc *Codec) SetDecodeLimits(limits DecodeLimits) *Codec {
+	cdc.mtx.Lock()
+	defer cdc.mtx.Unlock()
+
+	cdc.decodeLimits = limits
+	cdc.updateSnapshotNolock()
+	return cdc
+}
+
+func (cdc *Codec) getDecodeLimits() DecodeLimits {
+	if snap := cdc.getSnapshot(); snap != nil {
+		return snap.decodeLimits
+	}
+
+	cdc.mtx.RLock()
+	defer cdc.mtx.RUnlock()
+
+	return cdc.decodeLimits
+}
+
+// SetCheckGenerated sets whether the output of generated code (see
+// cmd/aminogen) is checked against the reflection-based implementation.  When
+// set, encoding and decoding fail if they disagree.  This is slow, and meant
+// for tests.
+func (cdc *Codec) SetCheckGenerated(check bool) *Codec {
+	cdc.mtx.Lock()
+	defer cdc.mtx.Unlock()
+
+	cdc.checkGenerated = check
+	cdc.updateSnapshotNolock()
 	return cdc
 }
 
+func (cdc *Codec) getCheckGenerated() bool {
+	if snap := cdc.getSnapshot(); snap != nil {
+		return snap.checkGenerated
+	}
+
+	cdc.mtx.RLock()
+	defer cdc.mtx.RUnlock()
+
+	return cdc.checkGenerated
+}
+
 // PrintTypes writes all registered types in a markdown-style table.
 // The table's header is:
 //
@@ -348,33 +608,77 @@ func (cdc *Codec) setTypeInfoNolock(info *TypeInfo) {
 	if info.Type.Kind() == reflect.Interface {
 		cdc.interfaceInfos = append(cdc.interfaceInfos, info)
 	} else if info.Registered {
-		cdc.concreteInfos = append(cdc.concreteInfos, info)
-		disfix := info.GetDisfix()
-		if existing, ok := cdc.disfixToTypeInfo[disfix]; ok {
-			panic(fmt.Sprintf("disfix <%X> already registered for %v", disfix, existing.Type))
-		}
-		if existing, ok := cdc.nameToTypeInfo[info.Name]; ok {
-			panic(fmt.Sprintf("name <%s> already registered for %v", info.Name, existing.Type))
-		}
-		cdc.disfixToTypeInfo[disfix] = info
-		cdc.nameToTypeInfo[info.Name] = info
-		//cdc.prefixToTypeInfos[prefix] =
-		//	append(cdc.prefixToTypeInfos[prefix], info)
+		cdc.setConcreteInfoNolock(info)
 	}
+	cdc.addToSnapshotNolock(info)
 }
 
-func (cdc *Codec) getTypeInfoWlock(rt reflect.Type) (info *TypeInfo, err error) {
-	// We do not use defer cdc.mtx.Unlock() here due to performance overhead of
-	// defer in go1.11 (and prior versions). Ensure new code paths unlock the
-	// mutex.
-	cdc.mtx.Lock() // requires wlock because we might set.
+// Adds the registered concrete type info to the lookups by name and prefix.
+func (cdc *Codec) setConcreteInfoNolock(info *TypeInfo) {
+	disfix := info.GetDisfix()
+	if existing, ok := cdc.disfixToTypeInfo[disfix]; ok {
+		panic(fmt.Sprintf("disfix <%X> already registered for %v", disfix, existing.Type))
+	}
+	if existing, ok := cdc.nameToTypeInfo[info.Name]; ok {
+		panic(fmt.Sprintf("name <%s> already registered for %v", info.Name, existing.Type))
+	}
+	cdc.concreteInfos = append(cdc.concreteInfos, info)
+	cdc.disfixToTypeInfo[disfix] = info
+	cdc.nameToTypeInfo[info.Name] = info
+	cdc.prefixToTypeInfos[info.Prefix] =
+		append(cdc.prefixToTypeInfos[info.Prefix], info)
+	var einfo = cdc.typeInfos[emptyInterfaceType]
+	einfo.Implementers[info.Prefix] =
+		append(einfo.Implementers[info.Prefix], info)
+}
 
+// Publishes a copy of the current state with info added, if sealed.
+func (cdc *Codec) addToSnapshotNolock(info *TypeInfo) {
+	var old = cdc.getSnapshot()
+	if old == nil {
+		return
+	}
+	if info.Registered || info.Type.Kind() == reflect.Interface {
+		// Only when registering races with Seal.
+		cdc.snapshot.Store(cdc.newSnapshotNolock())
+		return
+	}
+	snap := *old
+	snap.newTypeInfos = make(map[reflect.Type]*TypeInfo, len(old.newTypeInfos)+1)
+	for rt, info := range old.newTypeInfos {
+		snap.newTypeInfos[rt] = info
+	}
+	snap.newTypeInfos[info.Type] = info
+	cdc.snapshot.Store(&snap)
+}
+
+func (cdc *Codec) getTypeInfoWlock(rt reflect.Type) (info *TypeInfo, err error) {
 	// Dereference pointer type.
 	for rt.Kind() == reflect.Ptr {
 		rt = rt.Elem()
 	}
 
-	info, ok := cdc.typeInfos[rt]
+	// Most lookups find the type info, which only requires the rlock, or no
+	// lock at all once sealed.
+	var ok bool
+	if snap := cdc.getSnapshot(); snap != nil {
+		info, ok = snap.getTypeInfo(rt)
+	} else {
+		cdc.mtx.RLock()
+		info, ok = cdc.typeInfos[rt]
+		cdc.mtx.RUnlock()
+	}
+	if ok {
+		return info, nil
+	}
+
+	// We do not use defer cdc.mtx.Unlock() here due to performance overhead of
+	// defer in go1.11 (and prior versions). Ensure new code paths unlock the
+	// mutex.
+	cdc.mtx.Lock() // requires wlock because we might set.
+
+	// Check again, another goroutine may have set it.
+	info, ok = cdc.typeInfos[rt]
 	if !ok {
 		if rt.Kind() == reflect.Interface {
 			err = fmt.Errorf("Unregistered interface %v", rt)
@@ -382,7 +686,11 @@ func (cdc *Codec) getTypeInfoWlock(rt reflect.Type) (info *TypeInfo, err error)
 			return
 		}
 
-		info = cdc.newTypeInfoUnregistered(rt)
+		info, err = cdc.newTypeInfoUnregistered(rt)
+		if err != nil {
+			cdc.mtx.Unlock()
+			return
+		}
 		cdc.setTypeInfoNolock(info)
 	}
 	cdc.mtx.Unlock()
@@ -392,111 +700,392 @@ func (cdc *Codec) getTypeInfoWlock(rt reflect.Type) (info *TypeInfo, err error)
 // iinfo: TypeInfo for the interface for which we must decode a
 // concrete type with prefix bytes pb.
 func (cdc *Codec) getTypeInfoFromPrefixRlock(iinfo *TypeInfo, pb PrefixBytes) (info *TypeInfo, err error) {
-	// We do not use defer cdc.mtx.Unlock() here due to performance overhead of
-	// defer in go1.11 (and prior versions). Ensure new code paths unlock the
-	// mutex.
-	cdc.mtx.RLock()
+	// Implementers only change when registering, which a sealed codec
+	// doesn't allow.
+	var infos []*TypeInfo
+	var ok bool
+	if cdc.getSnapshot() != nil {
+		infos, ok = iinfo.Implementers[pb]
+	} else {
+		// We do not use defer cdc.mtx.Unlock() here due to performance
+		// overhead of defer in go1.11 (and prior versions).
+		cdc.mtx.RLock()
+		infos, ok = iinfo.Implementers[pb]
+		cdc.mtx.RUnlock()
+	}
 
-	infos, ok := iinfo.Implementers[pb]
 	if !ok {
-		err = fmt.Errorf("unrecognized prefix bytes %X", pb)
-		cdc.mtx.RUnlock()
+		// Tell apart registered types that don't implement the interface.
+		if cinfo, _ := cdc.getConcreteInfoFromPrefixRlock(pb); cinfo != nil {
+			if err = checkConcreteAllowed(iinfo, cinfo, FieldOptions{}); err != nil {
+				return
+			}
+		}
+		err = kindErrorf(ErrKindUnknownPrefix, "unrecognized prefix bytes %X", pb)
 		return
 	}
 	if len(infos) > 1 {
-		err = fmt.Errorf("conflicting concrete types registered for %X: e.g. %v and %v", pb, infos[0].Type, infos[1].Type)
+		err = kindErrorf(ErrKindUnknownPrefix, "conflicting concrete types registered for %X: e.g. %v and %v", pb, infos[0].Type, infos[1].Type)
+		return
+	}
+	info = infos[0]
+	return
+}
+
+// Like getTypeInfoFromPrefixRlock, but for any registered concrete type, not
+// only the implementers of an interface.
+func (cdc *Codec) getConcreteInfoFromPrefixRlock(pb PrefixBytes) (info *TypeInfo, err error) {
+	var infos []*TypeInfo
+	var ok bool
+	if snap := cdc.getSnapshot(); snap != nil {
+		infos, ok = snap.prefixToTypeInfos[pb]
+	} else {
+		// We do not use defer cdc.mtx.Unlock() here due to performance
+		// overhead of defer in go1.11 (and prior versions).
+		cdc.mtx.RLock()
+		infos, ok = cdc.prefixToTypeInfos[pb]
 		cdc.mtx.RUnlock()
+	}
+
+	if !ok {
+		err = kindErrorf(ErrKindUnknownPrefix, "unrecognized prefix bytes %X", pb)
+		return
+	}
+	if len(infos) > 1 {
+		err = kindErrorf(ErrKindUnknownPrefix, "conflicting concrete types registered for %X: e.g. %v and %v", pb, infos[0].Type, infos[1].Type)
 		return
 	}
 	info = infos[0]
-	cdc.mtx.RUnlock()
 	return
 }
 
 func (cdc *Codec) getTypeInfoFromDisfixRlock(df DisfixBytes) (info *TypeInfo, err error) {
-	// We do not use defer cdc.mtx.Unlock() here due to performance overhead of
-	// defer in go1.11 (and prior versions). Ensure new code paths unlock the
-	// mutex.
-	cdc.mtx.RLock()
+	var ok bool
+	if snap := cdc.getSnapshot(); snap != nil {
+		info, ok = snap.disfixToTypeInfo[df]
+	} else {
+		// We do not use defer cdc.mtx.Unlock() here due to performance
+		// overhead of defer in go1.11 (and prior versions).
+		cdc.mtx.RLock()
+		info, ok = cdc.disfixToTypeInfo[df]
+		cdc.mtx.RUnlock()
+	}
 
-	info, ok := cdc.disfixToTypeInfo[df]
 	if !ok {
-		err = fmt.Errorf("unrecognized disambiguation+prefix bytes %X", df)
-		cdc.mtx.RUnlock()
+		err = kindErrorf(ErrKindUnknownPrefix, "unrecognized disambiguation+prefix bytes %X", df)
 		return
 	}
-	cdc.mtx.RUnlock()
 	return
 }
 
 func (cdc *Codec) getTypeInfoFromNameRlock(name string) (info *TypeInfo, err error) {
-	// We do not use defer cdc.mtx.Unlock() here due to performance overhead of
-	// defer in go1.11 (and prior versions). Ensure new code paths unlock the
-	// mutex.
-	cdc.mtx.RLock()
+	var ok bool
+	if snap := cdc.getSnapshot(); snap != nil {
+		info, ok = snap.nameToTypeInfo[name]
+	} else {
+		// We do not use defer cdc.mtx.Unlock() here due to performance
+		// overhead of defer in go1.11 (and prior versions).
+		cdc.mtx.RLock()
+		info, ok = cdc.nameToTypeInfo[name]
+		cdc.mtx.RUnlock()
+	}
 
-	info, ok := cdc.nameToTypeInfo[name]
 	if !ok {
-		err = fmt.Errorf("unrecognized concrete type name %s", name)
-		cdc.mtx.RUnlock()
+		err = kindErrorf(ErrKindUnknownPrefix, "unrecognized concrete type name %s", name)
+		return
+	}
+	return
+}
+
+// Returns an error if the concrete type cinfo may not be decoded into a value
+// of the interface iinfo, with field options fopts: if it doesn't implement
+// the interface, or if it isn't in the Allow list of the interface or field.
+func checkConcreteAllowed(iinfo, cinfo *TypeInfo, fopts FieldOptions) error {
+	var crt = cinfo.Type
+	if cinfo.PointerPreferred {
+		crt = cinfo.PtrToType
+	}
+	if !crt.Implements(iinfo.Type) {
+		return kindErrorf(ErrKindNotAllowed, "%v (%v) does not implement %v", cinfo.Name, crt, iinfo.Type)
+	}
+	if !isNameAllowed(iinfo.Allow, cinfo.Name) || !isNameAllowed(fopts.Allow, cinfo.Name) {
+		return kindErrorf(ErrKindNotAllowed, "%v (%v) is not allowed for %v", cinfo.Name, crt, iinfo.Type)
+	}
+	return nil
+}
+
+// Returns true if values of unregistered concrete types can be decoded to
+// UnknownConcrete for the interface.
+func allowsUnknownConcrete(iinfo *TypeInfo) bool {
+	return iinfo.AllowUnknownConcrete && unknownConcreteType.Implements(iinfo.Type)
+}
+
+// Returns true if allow is empty or contains name.
+func isNameAllowed(allow []string, name string) bool {
+	if len(allow) == 0 {
+		return true
+	}
+	for _, allowed := range allow {
+		if allowed == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns the bits of the value of enum rv, for EnumInfo.
+func enumBits(rv reflect.Value) uint64 {
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return uint64(rv.Int())
+	default:
+		return rv.Uint()
+	}
+}
+
+// Sets enum rv to the value with the given bits, see enumBits.
+func setEnumBits(rv reflect.Value, bits uint64) {
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		rv.SetInt(int64(bits))
+	default:
+		rv.SetUint(bits)
+	}
+}
+
+// Enum names are proto3 identifiers, so they can't be mistaken for numbers in
+// Amino:JSON.
+func isEnumName(name string) bool {
+	for i, c := range name {
+		switch {
+		case c == '_', 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z':
+		case i > 0 && '0' <= c && c <= '9':
+		default:
+			return false
+		}
+	}
+	return name != ""
+}
+
+// Returns an error if the value of enum rv is undefined and not zero, and the
+// enum is strict.
+func checkEnumDefined(info *TypeInfo, rv reflect.Value) error {
+	var bits = enumBits(rv)
+	if !info.Strict || bits == 0 {
+		return nil
+	}
+	if _, ok := info.EnumNames[bits]; !ok {
+		return kindErrorf(ErrKindUndefinedEnum, "undefined value %v of enum %v", rv, info.Type)
+	}
+	return nil
+}
+
+// Copies bz to rv, a slice or array of its length whose elements are of a
+// named byte type, e.g. an enum, which reflect.Copy doesn't support.  Values
+// of strict enums are checked.
+func (cdc *Codec) copyNamedBytes(rv reflect.Value, bz []byte) error {
+	einfo, err := cdc.getTypeInfoWlock(rv.Type().Elem())
+	if err != nil {
+		return err
+	}
+	for i, b := range bz {
+		erv := rv.Index(i)
+		erv.SetUint(uint64(b))
+		if einfo.IsEnum {
+			if err = checkEnumDefined(einfo, erv); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Returns the type infos of the key (field 1) and the value (field 2) of
+// entries of the map type info.
+func (cdc *Codec) getMapEntryInfos(info *TypeInfo) (kinfo, vinfo *TypeInfo, err error) {
+	kt := info.Type.Key()
+	switch kt.Kind() {
+	case reflect.Bool, reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+	default:
+		err = fmt.Errorf("unsupported map key type %v, must be a bool, integer or string", kt)
 		return
 	}
-	cdc.mtx.RUnlock()
+	kinfo, err = cdc.getTypeInfoWlock(kt)
+	if err != nil {
+		return
+	}
+	vinfo, err = cdc.getTypeInfoWlock(info.Type.Elem())
+	return
+}
+
+// Returns the field options of the key and the value of map entries, for a
+// map field with options fopts.  Field options such as `binary:"fixed64"` of
+// the map field apply to the value.
+func mapEntryOptions(fopts FieldOptions) (kopts, vopts FieldOptions) {
+	kopts = FieldOptions{BinFieldNum: 1}
+	vopts = fopts
+	vopts.BinFieldNum = 2
 	return
 }
 
-func (cdc *Codec) parseStructInfo(rt reflect.Type) (sinfo StructInfo) {
+func (cdc *Codec) parseStructInfo(rt reflect.Type) (sinfo StructInfo, err error) {
 	if rt.Kind() != reflect.Struct {
 		panic("should not happen")
 	}
 
 	var infos = make([]FieldInfo, 0, rt.NumField())
+	var reserved []uint32
+	var unknownFieldsIndex = -1
 	for i := 0; i < rt.NumField(); i++ {
 		var field = rt.Field(i)
 		var ftype = field.Type
-		var unpackedList = false
+		if field.Name == "_" {
+			// e.g. _ struct{} `amino:"reserved=3|4"`
+			var nums []uint32
+			nums, err = parseReservedFieldNums(field)
+			if err != nil {
+				return
+			}
+			reserved = append(reserved, nums...)
+			continue
+		}
 		if !isExported(field) {
 			continue // field is unexported
 		}
-		skip, fopts := cdc.parseFieldOptions(field)
+		if ftype == unknownFieldsType {
+			if unknownFieldsIndex >= 0 {
+				err = errors.Errorf("%v has more than one amino.UnknownFields field", rt)
+				return
+			}
+			unknownFieldsIndex = i
+			continue
+		}
+		skip, fopts, err := cdc.parseFieldOptions(field)
+		if err != nil {
+			return sinfo, err
+		}
 		if skip {
 			continue // e.g. json:"-"
 		}
-		if ftype.Kind() == reflect.Array || ftype.Kind() == reflect.Slice {
-			if ftype.Elem().Kind() == reflect.Uint8 {
-				// These get handled by our optimized methods,
-				// encodeReflectBinaryByte[Slice/Array].
-				unpackedList = false
+		// NOTE: BinFieldNum starts with 1.
+		// Fields without `amino:"field=<num>"` follow the previous field.
+		if fopts.BinFieldNum == 0 {
+			if len(infos) == 0 {
+				fopts.BinFieldNum = 1
 			} else {
-				etype := ftype.Elem()
-				for etype.Kind() == reflect.Ptr {
-					etype = etype.Elem()
-				}
-				typ3 := typeToTyp3(etype, fopts)
-				if typ3 == Typ3ByteLength {
-					unpackedList = true
-				}
+				fopts.BinFieldNum = infos[len(infos)-1].BinFieldNum + 1
 			}
 		}
-		// NOTE: This is going to change a bit.
-		// NOTE: BinFieldNum starts with 1.
-		fopts.BinFieldNum = uint32(len(infos) + 1)
 		fieldInfo := FieldInfo{
 			Name:         field.Name, // Mostly for debugging.
 			Index:        i,
 			Type:         ftype,
 			ZeroValue:    reflect.Zero(ftype),
-			UnpackedList: unpackedList,
+			UnpackedList: isUnpackedList(ftype, fopts),
 			FieldOptions: fopts,
 		}
 		checkUnsafe(fieldInfo)
 		infos = append(infos, fieldInfo)
 	}
-	sinfo = StructInfo{infos}
+	sort.Slice(reserved, func(i, j int) bool { return reserved[i] < reserved[j] })
+	sinfo = StructInfo{Fields: infos, Reserved: reserved, UnknownFieldsIndex: unknownFieldsIndex}
+	err = validateFieldNums(rt, sinfo)
+	return
+}
+
+// Returns true if values of type rt are encoded as repeated fields of the
+// parent struct, i.e. lists of ByteLength elements, and maps (as a list of
+// key/value entries).
+func isUnpackedList(rt reflect.Type, fopts FieldOptions) bool {
+	switch rt.Kind() {
+	case reflect.Map:
+		return true
+	case reflect.Array, reflect.Slice:
+		if rt.Elem().Kind() == reflect.Uint8 {
+			// These get handled by our optimized methods,
+			// encodeReflectBinaryByte[Slice/Array].
+			return false
+		}
+		ert := rt.Elem()
+		for ert.Kind() == reflect.Ptr {
+			ert = ert.Elem()
+		}
+		return typeToTyp3(ert, fopts) == Typ3ByteLength
+	default:
+		return false
+	}
+}
+
+// Returns true if values of the (dereferenced) type info are lists written in
+// packed form, i.e. lists of elements that aren't ByteLength.  Proto3 has no
+// lists of lists, so as elements of lists, these are written like a message
+// with the list as field 1.  Other lists are already written that way.
+func isPackedList(info *TypeInfo) bool {
+	var rt = info.Type
+	if info.IsAminoMarshaler || (rt.Kind() != reflect.Array && rt.Kind() != reflect.Slice) {
+		return false
+	}
+	return rt.Elem().Kind() != reflect.Uint8 && !isUnpackedList(rt, FieldOptions{})
+}
+
+// Field numbers must be valid, strictly increasing in declaration order
+// (fields are encoded in that order), and must not be reserved.
+func validateFieldNums(rt reflect.Type, sinfo StructInfo) error {
+	for i, field := range sinfo.Fields {
+		if field.BinFieldNum > maxFieldNum {
+			return errors.Errorf("invalid field number %v for %v.%v, max is %v",
+				field.BinFieldNum, rt, field.Name, maxFieldNum)
+		}
+		if i > 0 {
+			prev := sinfo.Fields[i-1]
+			if field.BinFieldNum == prev.BinFieldNum {
+				return errors.Errorf("duplicate field number %v for %v.%v and %v.%v",
+					field.BinFieldNum, rt, prev.Name, rt, field.Name)
+			}
+			if field.BinFieldNum < prev.BinFieldNum {
+				return errors.Errorf("field number %v of %v.%v must be greater than %v of preceding field %v",
+					field.BinFieldNum, rt, field.Name, prev.BinFieldNum, prev.Name)
+			}
+		}
+		for _, num := range sinfo.Reserved {
+			if field.BinFieldNum == num {
+				return errors.Errorf("field number %v of %v.%v is reserved",
+					field.BinFieldNum, rt, field.Name)
+			}
+		}
+	}
+	return nil
+}
+
+// Parses `amino:"reserved=<num>|<num>..."` of a blank field.
+func parseReservedFieldNums(field reflect.StructField) (nums []uint32, err error) {
+	for _, aminoTag := range strings.Split(field.Tag.Get("amino"), ",") {
+		if !strings.HasPrefix(aminoTag, "reserved=") {
+			continue
+		}
+		for _, numStr := range strings.Split(strings.TrimPrefix(aminoTag, "reserved="), "|") {
+			var num uint32
+			num, err = parseFieldNum(numStr)
+			if err != nil {
+				return
+			}
+			nums = append(nums, num)
+		}
+	}
 	return
 }
 
-func (cdc *Codec) parseFieldOptions(field reflect.StructField) (skip bool, fopts FieldOptions) {
+func parseFieldNum(numStr string) (uint32, error) {
+	num, err := strconv.ParseUint(numStr, 10, 32)
+	if err != nil || num == 0 || num > maxFieldNum {
+		return 0, errors.Errorf("invalid field number %q, must be in [1, %v]", numStr, maxFieldNum)
+	}
+	return uint32(num), nil
+}
+
+func (cdc *Codec) parseFieldOptions(field reflect.StructField) (skip bool, fopts FieldOptions, err error) {
 	binTag := field.Tag.Get("binary")
 	aminoTag := field.Tag.Get("amino")
 	jsonTag := field.Tag.Get("json")
@@ -542,13 +1131,29 @@ func (cdc *Codec) parseFieldOptions(field reflect.StructField) (skip bool, fopts
 		if aminoTag == "empty_elements" {
 			fopts.EmptyElements = true
 		}
+		if strings.HasPrefix(aminoTag, "allow=") {
+			fopts.Allow = strings.Split(strings.TrimPrefix(aminoTag, "allow="), "|")
+			for _, name := range fopts.Allow {
+				if name == "" {
+					err = errors.Errorf("invalid amino allow tag for %v", field.Name)
+					return
+				}
+			}
+		}
+		if strings.HasPrefix(aminoTag, "field=") {
+			fopts.BinFieldNum, err = parseFieldNum(strings.TrimPrefix(aminoTag, "field="))
+			if err != nil {
+				err = errors.Wrapf(err, "invalid amino field tag for %v", field.Name)
+				return
+			}
+		}
 	}
 
 	return
 }
 
 // Constructs a *TypeInfo automatically, not from registration.
-func (cdc *Codec) newTypeInfoUnregistered(rt reflect.Type) *TypeInfo {
+func (cdc *Codec) newTypeInfoUnregistered(rt reflect.Type) (*TypeInfo, error) {
 	if rt.Kind() == reflect.Ptr {
 		panic("unexpected pointer type") // should not happen.
 	}
@@ -562,7 +1167,11 @@ func (cdc *Codec) newTypeInfoUnregistered(rt reflect.Type) *TypeInfo {
 	info.ZeroValue = reflect.Zero(rt)
 	info.ZeroProto = reflect.Zero(rt).Interface()
 	if rt.Kind() == reflect.Struct {
-		info.StructInfo = cdc.parseStructInfo(rt)
+		sinfo, err := cdc.parseStructInfo(rt)
+		if err != nil {
+			return nil, err
+		}
+		info.StructInfo = sinfo
 	}
 	if rm, ok := rt.MethodByName("MarshalAmino"); ok {
 		info.ConcreteInfo.IsAminoMarshaler = true
@@ -572,7 +1181,11 @@ func (cdc *Codec) newTypeInfoUnregistered(rt reflect.Type) *TypeInfo {
 		info.ConcreteInfo.IsAminoUnmarshaler = true
 		info.ConcreteInfo.AminoUnmarshalReprType = unmarshalAminoReprType(rm)
 	}
-	return info
+	if rt.Kind() == reflect.Struct && rt != timeType {
+		info.ConcreteInfo.IsAminoBinaryMarshaler = rt.Implements(aminoBinaryMarshalerType)
+		info.ConcreteInfo.IsAminoBinaryUnmarshaler = reflect.PtrTo(rt).Implements(aminoBinaryUnmarshalerType)
+	}
+	return info, nil
 }
 
 func (cdc *Codec) newTypeInfoFromInterfaceType(rt reflect.Type, iopts *InterfaceOptions) *TypeInfo {
@@ -599,13 +1212,19 @@ func (cdc *Codec) newTypeInfoFromInterfaceType(rt reflect.Type, iopts *Interface
 	return info
 }
 
-func (cdc *Codec) newTypeInfoFromRegisteredConcreteType(rt reflect.Type, pointerPreferred bool, name string, copts *ConcreteOptions) *TypeInfo {
-	if rt.Kind() == reflect.Interface ||
-		rt.Kind() == reflect.Ptr {
-		panic(fmt.Sprintf("expected non-interface non-pointer concrete type, got %v", rt))
+func (cdc *Codec) newTypeInfoFromRegisteredConcreteType(rt reflect.Type, pointerPreferred bool, name string, copts *ConcreteOptions) (*TypeInfo, error) {
+	if rt.Kind() == reflect.Ptr {
+		panic(fmt.Sprintf("expected non-pointer concrete type, got %v", rt))
 	}
 
-	var info = cdc.newTypeInfoUnregistered(rt)
+	var info *TypeInfo
+	var err error
+	if rt.Kind() == reflect.Interface {
+		// Only the ConcreteInfo is used, see RegisterConcrete.
+		info = cdc.newTypeInfoFromInterfaceType(rt, nil)
+	} else if info, err = cdc.newTypeInfoUnregistered(rt); err != nil {
+		return nil, err
+	}
 	info.ConcreteInfo.Registered = true
 	info.ConcreteInfo.PointerPreferred = pointerPreferred
 	info.ConcreteInfo.Name = name
@@ -614,7 +1233,7 @@ func (cdc *Codec) newTypeInfoFromRegisteredConcreteType(rt reflect.Type, pointer
 	if copts != nil {
 		info.ConcreteOptions = *copts
 	}
-	return info
+	return info, nil
 }
 
 // Find all conflicting prefixes for concrete types
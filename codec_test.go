@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"encoding/binary"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -250,3 +251,157 @@ func TestCodecSeal(t *testing.T) {
 	assert.Panics(t, func() { cdc.RegisterInterface((*Bar)(nil), nil) })
 	assert.Panics(t, func() { cdc.RegisterConcrete(int(0), "int", nil) })
 }
+
+func TestCodecSealConcurrent(t *testing.T) {
+
+	type Foo interface{}
+	type Bar struct{ A string }
+	type Baz struct{ Foo Foo }
+	type Qux struct {
+		Foos []Foo
+		Baz  *Baz
+	}
+
+	cdc := amino.NewCodec()
+	cdc.RegisterInterface((*Foo)(nil), nil)
+	cdc.RegisterConcrete(Bar{}, "amino_test/Bar", nil)
+	cdc.Seal()
+
+	// Baz and Qux aren't registered, and are discovered while sealed.
+	q := Qux{Foos: []Foo{Bar{"a"}, Bar{"b"}}, Baz: &Baz{Bar{"c"}}}
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			bz, err := cdc.MarshalBinaryBare(q)
+			assert.NoError(t, err)
+			var q2 Qux
+			err = cdc.UnmarshalBinaryBare(bz, &q2)
+			assert.NoError(t, err)
+			assert.Equal(t, q, q2)
+
+			bz, err = cdc.MarshalJSON(q)
+			assert.NoError(t, err)
+			var q3 Qux
+			err = cdc.UnmarshalJSON(bz, &q3)
+			assert.NoError(t, err)
+			assert.Equal(t, q, q3)
+		}()
+	}
+	wg.Wait()
+}
+
+func TestEmptyInterface(t *testing.T) {
+
+	type Foo interface{}
+	type Bar struct{ A string }
+	type Baz struct{ B int64 }
+	type Qux struct {
+		Any  interface{}
+		Anys []interface{}
+		Map  map[string]interface{}
+	}
+	type NamedQux struct {
+		Any  Foo
+		Anys []Foo
+		Map  map[string]Foo
+	}
+
+	cdc := amino.NewCodec()
+	cdc.RegisterInterface((*Foo)(nil), nil)
+	cdc.RegisterConcrete(Bar{}, "amino_test/Bar", nil)
+	cdc.RegisterConcrete(&Baz{}, "amino_test/Baz", nil)
+
+	q := Qux{
+		Any:  Bar{"a"},
+		Anys: []interface{}{&Baz{1}, nil, Bar{"b"}},
+		Map:  map[string]interface{}{"bar": Bar{"c"}, "baz": &Baz{2}},
+	}
+	nq := NamedQux{
+		Any:  Bar{"a"},
+		Anys: []Foo{&Baz{1}, nil, Bar{"b"}},
+		Map:  map[string]Foo{"bar": Bar{"c"}, "baz": &Baz{2}},
+	}
+
+	// interface{} is encoded like any registered interface.
+	bz, err := cdc.MarshalBinaryBare(q)
+	require.NoError(t, err)
+	assert.Equal(t, cdc.MustMarshalBinaryBare(nq), bz)
+	var q2 Qux
+	require.NoError(t, cdc.UnmarshalBinaryBare(bz, &q2))
+	assert.Equal(t, q, q2)
+
+	bz, err = cdc.MarshalJSON(q)
+	require.NoError(t, err)
+	assert.Equal(t, string(cdc.MustMarshalJSON(nq)), string(bz))
+	var q3 Qux
+	require.NoError(t, cdc.UnmarshalJSON(bz, &q3))
+	assert.Equal(t, q, q3)
+
+	// Unregistered concrete types.
+	_, err = cdc.MarshalBinaryBare(Qux{Any: "foo"})
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "unregistered concrete type string")
+	}
+	_, err = cdc.MarshalJSON(Qux{Any: 1})
+	assert.Error(t, err)
+	err = cdc.UnmarshalBinaryBare([]byte{0x0a, 0x04, 0x01, 0x02, 0x03, 0x04}, new(Qux))
+	if assert.IsType(t, &amino.DecodeError{}, err) {
+		assert.Equal(t, amino.ErrKindUnknownPrefix, err.(*amino.DecodeError).Kind)
+	}
+	err = cdc.UnmarshalJSON([]byte(`{"Any":{"type":"amino_test/Nope","value":{}}}`), new(Qux))
+	if assert.IsType(t, &amino.DecodeError{}, err) {
+		assert.Equal(t, amino.ErrKindUnknownPrefix, err.(*amino.DecodeError).Kind)
+	}
+
+	// The options of interface{} can be set.
+	cdc.RegisterInterface((*interface{})(nil), &amino.InterfaceOptions{AlwaysDisambiguate: true})
+	bz, err = cdc.MarshalBinaryBare(Qux{Any: Bar{"a"}})
+	require.NoError(t, err)
+	var disamb, prefix = amino.NameToDisfix("amino_test/Bar")
+	assert.Equal(t, append([]byte{0x00}, disamb[:]...), bz[2:6])
+	assert.Equal(t, prefix[:], bz[6:10])
+
+	cdc.Seal()
+	q2 = Qux{}
+	require.NoError(t, cdc.UnmarshalBinaryBare(bz, &q2))
+	assert.Equal(t, Qux{Any: Bar{"a"}}, q2)
+}
+
+func TestEmptyInterfaceRegisterConcurrent(t *testing.T) {
+
+	type Bar struct{ A string }
+	type Baz struct{ B int64 }
+	type Quux struct{ C bool }
+	type Corge struct{ D []byte }
+	type Qux struct {
+		Any interface{}
+	}
+
+	cdc := amino.NewCodec()
+	cdc.RegisterConcrete(Bar{}, "amino_test/Bar", nil)
+
+	// Registering concrete types doesn't race with encoding interface{}
+	// values, see go test -race.
+	q := Qux{Any: Bar{"a"}}
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				bz, err := cdc.MarshalBinaryBare(q)
+				assert.NoError(t, err)
+				var q2 Qux
+				err = cdc.UnmarshalBinaryBare(bz, &q2)
+				assert.NoError(t, err)
+				assert.Equal(t, q, q2)
+			}
+		}()
+	}
+	cdc.RegisterConcrete(Baz{}, "amino_test/Baz", nil)
+	cdc.RegisterConcrete(Quux{}, "amino_test/Quux", nil)
+	cdc.RegisterConcrete(Corge{}, "amino_test/Corge", nil)
+	wg.Wait()
+}
@@ -0,0 +1,224 @@
+package amino
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+//----------------------------------------
+// DecodeError
+
+// DecodeErrorKind classifies a DecodeError.
+type DecodeErrorKind uint8
+
+const (
+	ErrKindOther         = DecodeErrorKind(0)
+	ErrKindUnknownPrefix = DecodeErrorKind(1) // Or, for JSON, the type name.
+	ErrKindWrongTyp3     = DecodeErrorKind(2)
+	ErrKindFieldOrder    = DecodeErrorKind(3)
+	ErrKindOverflow      = DecodeErrorKind(4)
+	ErrKindEOF           = DecodeErrorKind(5)
+	ErrKindNotAllowed    = DecodeErrorKind(6) // Concrete type not allowed in an interface.
+	ErrKindUndefinedEnum = DecodeErrorKind(7) // Undefined enum name, or value if strict.
+)
+
+func (kind DecodeErrorKind) String() string {
+	switch kind {
+	case ErrKindOther:
+		return "Other"
+	case ErrKindUnknownPrefix:
+		return "UnknownPrefix"
+	case ErrKindWrongTyp3:
+		return "WrongTyp3"
+	case ErrKindFieldOrder:
+		return "FieldOrder"
+	case ErrKindOverflow:
+		return "Overflow"
+	case ErrKindEOF:
+		return "EOF"
+	case ErrKindNotAllowed:
+		return "NotAllowed"
+	case ErrKindUndefinedEnum:
+		return "UndefinedEnum"
+	default:
+		return fmt.Sprintf("DecodeErrorKind(%d)", kind)
+	}
+}
+
+// DecodeError is returned when the input of UnmarshalBinary* or UnmarshalJSON
+// cannot be decoded.
+type DecodeError struct {
+	Kind DecodeErrorKind
+	Type reflect.Type // Type of the value that failed to decode.
+	// Path to the value from the decoded type, e.g. "Tx.Msgs[2].Amount.Denom".
+	// Interface values are not part of the path.
+	Path string
+	// Offset in the binary input of the value that failed to decode, or for
+	// structs, lists and maps, of the offending field or element.  For JSON,
+	// Offset is -1 and Pointer is set instead.
+	Offset  int
+	Pointer string // JSON pointer (RFC 6901) to the value, e.g. "/msgs/2".
+	Err     error  // The underlying error.
+}
+
+func (err *DecodeError) Error() string {
+	var at string
+	if err.Offset < 0 {
+		at = fmt.Sprintf("JSON pointer %q", err.Pointer)
+	} else {
+		at = fmt.Sprintf("byte offset %v", err.Offset)
+	}
+	return fmt.Sprintf("cannot decode %v (%v) at %v: %v", err.Path, err.Type, at, err.Err)
+}
+
+// Cause returns the underlying error, see github.com/pkg/errors.
+func (err *DecodeError) Cause() error {
+	return err.Err
+}
+
+// An error of a given kind, to be wrapped in a DecodeError.
+type kindError struct {
+	kind DecodeErrorKind
+	msg  string
+}
+
+func (err kindError) Error() string {
+	return err.msg
+}
+
+func kindErrorf(kind DecodeErrorKind, format string, args ...interface{}) error {
+	return kindError{kind, fmt.Sprintf(format, args...)}
+}
+
+// Returns the kind of an error that is not a DecodeError yet.
+func errorKind(err error) DecodeErrorKind {
+	switch err := errors.Cause(err).(type) {
+	case kindError:
+		return err.kind
+	case *json.UnmarshalTypeError:
+		if strings.HasPrefix(err.Value, "number ") {
+			return ErrKindOverflow
+		}
+	}
+	return ErrKindOther
+}
+
+//----------------------------------------
+// Field path tracking
+
+// An element of the path from the decoded type to the value being decoded.
+type pathElem struct {
+	name     string        // Go field name, or "" for list or map elements.
+	jsonName string        // JSON field name.
+	index    int           // List index.
+	key      reflect.Value // Map key, if valid.
+}
+
+func (ds *decodeState) pushField(field *FieldInfo) {
+	ds.path = append(ds.path, pathElem{name: field.Name, jsonName: field.JSONName})
+}
+
+func (ds *decodeState) pushIndex(i int) {
+	ds.path = append(ds.path, pathElem{index: i})
+}
+
+func (ds *decodeState) pushKey(krv reflect.Value) {
+	ds.path = append(ds.path, pathElem{key: krv})
+}
+
+func (ds *decodeState) pop() {
+	ds.path = ds.path[:len(ds.path)-1]
+}
+
+// Returns the offset of bz in the input.
+// CONTRACT: bz was sliced from ds.input without copying.
+func (ds *decodeState) offset(bz []byte) int {
+	return ds.base + cap(ds.input) - cap(bz)
+}
+
+// Wraps err in a DecodeError for a value of type rt at offset in the binary
+// input.  LimitExceededErrs and NonCanonicalErrs are returned as is, and
+// DecodeErrors are not wrapped again.
+func (ds *decodeState) binaryError(err error, rt reflect.Type, offset int) error {
+	switch err.(type) {
+	case *DecodeError, LimitExceededErr, NonCanonicalErr:
+		return err
+	}
+	return &DecodeError{
+		Kind:   errorKind(err),
+		Type:   rt,
+		Path:   ds.pathString(),
+		Offset: offset,
+		Err:    err,
+	}
+}
+
+// Like binaryError, but for JSON.
+func (ds *decodeState) jsonError(err error, rt reflect.Type) error {
+	switch err.(type) {
+	case *DecodeError, LimitExceededErr:
+		return err
+	}
+	return &DecodeError{
+		Kind:    errorKind(err),
+		Type:    rt,
+		Path:    ds.pathString(),
+		Offset:  -1,
+		Pointer: ds.jsonPointer(),
+		Err:     err,
+	}
+}
+
+func (ds *decodeState) pathString() string {
+	var sb strings.Builder
+	if ds.root != nil {
+		if name := ds.root.Name(); name != "" {
+			sb.WriteString(name)
+		} else {
+			sb.WriteString(ds.root.String())
+		}
+	}
+	for _, elem := range ds.path {
+		switch {
+		case elem.name != "":
+			sb.WriteString(".")
+			sb.WriteString(elem.name)
+		case elem.key.IsValid():
+			if elem.key.Kind() == reflect.String {
+				fmt.Fprintf(&sb, "[%q]", elem.key.String())
+			} else {
+				fmt.Fprintf(&sb, "[%v]", elem.key.Interface())
+			}
+		default:
+			fmt.Fprintf(&sb, "[%v]", elem.index)
+		}
+	}
+	return sb.String()
+}
+
+var jsonPointerEscaper = strings.NewReplacer("~", "~0", "/", "~1")
+
+func (ds *decodeState) jsonPointer() string {
+	var sb strings.Builder
+	for _, elem := range ds.path {
+		sb.WriteString("/")
+		switch {
+		case elem.name != "":
+			sb.WriteString(jsonPointerEscaper.Replace(elem.jsonName))
+		case elem.key.IsValid():
+			key, err := encodeJSONMapKey(elem.key)
+			if err != nil {
+				key = fmt.Sprintf("%v", elem.key.Interface())
+			}
+			sb.WriteString(jsonPointerEscaper.Replace(key))
+		default:
+			sb.WriteString(strconv.Itoa(elem.index))
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,146 @@
+package amino
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"reflect"
+)
+
+//----------------------------------------
+// DecodeLimits
+
+// DecodeLimits bounds the resources used to decode untrusted input.  A zero
+// value for any of the limits means that it is not enforced.
+//
+// The limits can be set for all decoding done by a Codec with
+// Codec.SetDecodeLimits, or for a single call with the *WithLimits functions,
+// e.g. UnmarshalBinaryBareWithLimits.
+type DecodeLimits struct {
+	// Max nesting depth of structs, lists, maps and interfaces.
+	MaxDepth int
+	// Max number of elements of a single list (slice) or map.
+	MaxListLen int
+	// Max total number of bytes allocated for the decoded value, including
+	// strings, byte slices, list elements, map entries and pointers.  This is
+	// an estimate based on the sizes of the allocated types.
+	MaxAllocBytes int64
+	// Max length of a single string or byte slice.  In JSON, the length is
+	// checked before decoding, so escapes count as their encoded length.
+	MaxStringLen int
+}
+
+// LimitExceededErr is returned when decoding exceeds one of the DecodeLimits.
+type LimitExceededErr struct {
+	Limit string // Name of the exceeded limit, e.g. "MaxDepth".
+	Max   int64  // Value of the exceeded limit.
+}
+
+func (err LimitExceededErr) Error() string {
+	return fmt.Sprintf("amino decoding limit %v of %v exceeded", err.Limit, err.Max)
+}
+
+// decodeState keeps track of the resources used by a single decoding call,
+// and of the position in the input for DecodeErrors.
+type decodeState struct {
+	limits DecodeLimits
+	depth  int   // Current nesting depth.
+	alloc  int64 // Total bytes allocated so far.
+
+	root  reflect.Type // The decoded type.
+	path  []pathElem   // Path from root to the value being decoded.
+	input []byte       // The binary input, see offset().
+	base  int          // Offset of input, e.g. after a length prefix.
+
+	noGenerated bool // Don't use generated code, see SetCheckGenerated.
+	strict      bool // Only accept the canonical encoding, see UnmarshalBinaryBareStrict.
+}
+
+func newDecodeState(limits DecodeLimits) *decodeState {
+	return &decodeState{limits: limits}
+}
+
+// Call before decoding a struct, list, map or interface, and call leave()
+// when done.
+func (ds *decodeState) enter() error {
+	ds.depth++
+	if ds.limits.MaxDepth > 0 && ds.depth > ds.limits.MaxDepth {
+		return LimitExceededErr{"MaxDepth", int64(ds.limits.MaxDepth)}
+	}
+	return nil
+}
+
+func (ds *decodeState) leave() {
+	ds.depth--
+}
+
+// Accounts for size bytes to be allocated.
+func (ds *decodeState) allocate(size int64) error {
+	ds.alloc += size
+	if ds.limits.MaxAllocBytes > 0 && ds.alloc > ds.limits.MaxAllocBytes {
+		return LimitExceededErr{"MaxAllocBytes", ds.limits.MaxAllocBytes}
+	}
+	return nil
+}
+
+// Accounts for a new value of type rt, e.g. for a pointer.
+func (ds *decodeState) allocateType(rt reflect.Type) error {
+	return ds.allocate(int64(rt.Size()))
+}
+
+// Accounts for the i'th (0-based) element of type rt of a list or map.
+func (ds *decodeState) allocateElem(i int, rt reflect.Type) error {
+	if ds.limits.MaxListLen > 0 && i >= ds.limits.MaxListLen {
+		return LimitExceededErr{"MaxListLen", int64(ds.limits.MaxListLen)}
+	}
+	return ds.allocateType(rt)
+}
+
+// Accounts for a string or byte slice of the given length.
+func (ds *decodeState) allocateString(length int) error {
+	if ds.limits.MaxStringLen > 0 && length > ds.limits.MaxStringLen {
+		return LimitExceededErr{"MaxStringLen", int64(ds.limits.MaxStringLen)}
+	}
+	return ds.allocate(int64(length))
+}
+
+// Like allocateString, but for the JSON string literal bz before decoding it,
+// by an upper bound of the decoded length, as escapes only make the literal
+// longer.  For byte slices, the literal is base64.  Returns the bound.
+func (ds *decodeState) allocateJSONString(bz []byte, isBase64 bool) (length int, err error) {
+	bz = bytes.TrimSpace(bz)
+	if len(bz) < 2 || bz[0] != '"' {
+		// Not a string, which json.Unmarshal rejects.
+		return 0, nil
+	}
+	length = len(bz) - 2
+	if isBase64 {
+		length = base64.StdEncoding.DecodedLen(length) - (length - len(bytes.TrimRight(bz[1:len(bz)-1], "=")))
+	}
+	err = ds.allocateString(length)
+	return
+}
+
+// Like DecodeByteSlice, but accounts for the decoded string or byte slice
+// before copying it.
+func (ds *decodeState) decodeByteSlice(bz []byte) (bz2 []byte, n int, err error) {
+	var count uint64
+	count, _, err = DecodeUvarint(bz)
+	if err != nil {
+		return
+	}
+	if int(count) >= 0 {
+		// Otherwise DecodeByteSliceNoCopy returns an error.
+		if err = ds.allocateString(int(count)); err != nil {
+			return
+		}
+	}
+	var buf []byte
+	buf, n, err = DecodeByteSliceNoCopy(bz)
+	if err != nil {
+		return
+	}
+	bz2 = make([]byte, len(buf))
+	copy(bz2, buf)
+	return
+}
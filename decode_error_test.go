@@ -0,0 +1,156 @@
+package amino_test
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	amino "github.com/tendermint/go-amino"
+)
+
+type errCoin struct {
+	Denom  string `json:"denom"`
+	Amount int64  `json:"amount"`
+}
+
+type errMsg interface{}
+
+type errMsgSend struct {
+	Amount errCoin `json:"amount"`
+	Small  int8    `json:"small"`
+}
+
+type errTx struct {
+	Msgs []errMsg           `json:"msgs"`
+	Memo map[string]errCoin `json:"memo"`
+}
+
+func newErrCodec() *amino.Codec {
+	cdc := amino.NewCodec()
+	cdc.RegisterInterface((*errMsg)(nil), nil)
+	cdc.RegisterConcrete(errMsgSend{}, "test/MsgSend", nil)
+	return cdc
+}
+
+func requireDecodeError(t *testing.T, err error) *amino.DecodeError {
+	require.Error(t, err)
+	derr, ok := err.(*amino.DecodeError)
+	require.True(t, ok, "expected a *DecodeError, got %T: %v", err, err)
+	return derr
+}
+
+func TestDecodeErrorBinary(t *testing.T) {
+	cdc := newErrCodec()
+	tx := errTx{Msgs: []errMsg{
+		errMsgSend{Amount: errCoin{"uatom", 1}},
+		errMsgSend{Amount: errCoin{"stake", 2}},
+	}}
+	bz, err := cdc.MarshalBinaryBare(tx)
+	require.NoError(t, err)
+	var tx2 errTx
+	require.NoError(t, cdc.UnmarshalBinaryBare(bz, &tx2))
+
+	// Claim that the second denom is longer than it is.
+	var offset = bytes.Index(bz, []byte("stake")) - 1
+	var bz2 = append([]byte(nil), bz...)
+	bz2[offset] = 0x7f
+	err = cdc.UnmarshalBinaryBare(bz2, &tx2)
+	derr := requireDecodeError(t, err)
+	assert.Equal(t, amino.ErrKindEOF, derr.Kind)
+	assert.Equal(t, "errTx.Msgs[1].Amount.Denom", derr.Path)
+	assert.Equal(t, reflect.TypeOf(""), derr.Type)
+	assert.Equal(t, offset, derr.Offset)
+	assert.NotContains(t, err.Error(), fmt.Sprintf("%X", bz2))
+
+	// Offsets include the length prefix.
+	lbz, err := cdc.MarshalBinaryLengthPrefixed(tx)
+	require.NoError(t, err)
+	lbz[len(lbz)-len(bz)+offset] = 0x7f
+	err = cdc.UnmarshalBinaryLengthPrefixed(lbz, &tx2)
+	derr = requireDecodeError(t, err)
+	assert.Equal(t, len(lbz)-len(bz)+offset, derr.Offset)
+
+	// Unknown prefix bytes.
+	offset = bytes.Index(bz, cdc.MustMarshalBinaryBare(errMsgSend{})[:4])
+	bz2 = append([]byte(nil), bz...)
+	bz2[offset] ^= 0xff
+	derr = requireDecodeError(t, cdc.UnmarshalBinaryBare(bz2, &tx2))
+	assert.Equal(t, amino.ErrKindUnknownPrefix, derr.Kind)
+	assert.Equal(t, "errTx.Msgs[0]", derr.Path)
+	assert.Equal(t, offset, derr.Offset)
+
+	// Unknown or missing prefix bytes of a registered concrete type.
+	bz, err = cdc.MarshalBinaryBare(errMsgSend{Amount: errCoin{"uatom", 1}})
+	require.NoError(t, err)
+	bz[0] ^= 0xff
+	err = cdc.UnmarshalBinaryBare(bz, new(errMsgSend))
+	derr = requireDecodeError(t, err)
+	assert.Equal(t, amino.ErrKindUnknownPrefix, derr.Kind)
+	assert.Equal(t, "errMsgSend", derr.Path)
+	assert.Equal(t, 0, derr.Offset)
+	assert.NotContains(t, err.Error(), fmt.Sprintf("%X", bz))
+	derr = requireDecodeError(t, cdc.UnmarshalBinaryBare(bz[:3], new(errMsgSend)))
+	assert.Equal(t, amino.ErrKindEOF, derr.Kind)
+
+	// Overflow of an int8.
+	bz, err = cdc.MarshalBinaryBare(errMsgSend{Small: 1})
+	require.NoError(t, err)
+	bz = append(bz[:len(bz)-1], 0x80, 0x04) // zigzag(256)
+	derr = requireDecodeError(t, cdc.UnmarshalBinaryBare(bz, new(errMsgSend)))
+	assert.Equal(t, amino.ErrKindOverflow, derr.Kind)
+	assert.Equal(t, "errMsgSend.Small", derr.Path)
+	assert.Equal(t, len(bz)-2, derr.Offset)
+
+	// Wrong typ3 for Denom, and fields out of order.
+	bz = []byte{0x09, 0, 0, 0, 0, 0, 0, 0, 0}
+	derr = requireDecodeError(t, cdc.UnmarshalBinaryBare(bz, new(errCoin)))
+	assert.Equal(t, amino.ErrKindWrongTyp3, derr.Kind)
+	assert.Equal(t, "errCoin.Denom", derr.Path)
+	assert.Equal(t, 0, derr.Offset)
+
+	bz = []byte{0x10, 0x01, 0x0a, 0x00}
+	derr = requireDecodeError(t, cdc.UnmarshalBinaryBare(bz, new(errCoin)))
+	assert.Equal(t, amino.ErrKindFieldOrder, derr.Kind)
+	assert.Equal(t, 2, derr.Offset)
+
+	// Map values are identified by their key.
+	tx = errTx{Memo: map[string]errCoin{"a": {"uatom", 1}}}
+	bz, err = cdc.MarshalBinaryBare(tx)
+	require.NoError(t, err)
+	bz[bytes.Index(bz, []byte("uatom"))-1] = 0x7f
+	derr = requireDecodeError(t, cdc.UnmarshalBinaryBare(bz, &tx2))
+	assert.Equal(t, `errTx.Memo["a"].Denom`, derr.Path)
+}
+
+func TestDecodeErrorJSON(t *testing.T) {
+	cdc := newErrCodec()
+	jbz := []byte(`{"msgs":[
+		{"type":"test/MsgSend","value":{"amount":{"denom":"uatom","amount":"1"}}},
+		{"type":"test/MsgSend","value":{"amount":{"denom":5,"amount":"2"}}}
+	]}`)
+	var tx errTx
+	derr := requireDecodeError(t, cdc.UnmarshalJSON(jbz, &tx))
+	assert.Equal(t, amino.ErrKindOther, derr.Kind)
+	assert.Equal(t, "errTx.Msgs[1].Amount.Denom", derr.Path)
+	assert.Equal(t, "/msgs/1/amount/denom", derr.Pointer)
+	assert.Equal(t, -1, derr.Offset)
+	assert.Equal(t, reflect.TypeOf(""), derr.Type)
+
+	jbz = []byte(`{"msgs":[{"type":"test/Unknown","value":{}}]}`)
+	derr = requireDecodeError(t, cdc.UnmarshalJSON(jbz, &tx))
+	assert.Equal(t, amino.ErrKindUnknownPrefix, derr.Kind)
+	assert.Equal(t, "/msgs/0", derr.Pointer)
+
+	jbz = []byte(`{"type":"test/MsgSend","value":{"small":300}}`)
+	derr = requireDecodeError(t, cdc.UnmarshalJSON(jbz, new(errMsgSend)))
+	assert.Equal(t, amino.ErrKindOverflow, derr.Kind)
+	assert.Equal(t, "errMsgSend.Small", derr.Path)
+
+	jbz = []byte(`{"memo":{"a/b":{"denom":5}}}`)
+	derr = requireDecodeError(t, cdc.UnmarshalJSON(jbz, &tx))
+	assert.Equal(t, "/memo/a~1b/denom", derr.Pointer)
+}
@@ -0,0 +1,130 @@
+package amino_test
+
+import (
+	"bytes"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	amino "github.com/tendermint/go-amino"
+)
+
+type limitsTree struct {
+	Children []limitsTree
+}
+
+type limitsStruct struct {
+	Ints  []int64
+	Str   string
+	Bytes []byte
+	Map   map[string]int64
+	Tree  limitsTree
+}
+
+func newLimitsTree(depth int) limitsTree {
+	if depth <= 1 {
+		return limitsTree{}
+	}
+	return limitsTree{Children: []limitsTree{newLimitsTree(depth - 1)}}
+}
+
+func TestDecodeLimits(t *testing.T) {
+	cdc := amino.NewCodec()
+	s := limitsStruct{
+		Ints:  make([]int64, 10),
+		Str:   strings.Repeat("s", 10),
+		Bytes: bytes.Repeat([]byte("b"), 10),
+		Map:   map[string]int64{"a": 1, "b": 2, "c": 3},
+		Tree:  newLimitsTree(10),
+	}
+	s.Ints[9] = 1
+	bz, err := cdc.MarshalBinaryBare(s)
+	require.NoError(t, err)
+	jbz, err := cdc.MarshalJSON(s)
+	require.NoError(t, err)
+
+	cases := []struct {
+		limits amino.DecodeLimits
+		err    error
+	}{
+		{amino.DecodeLimits{}, nil},
+		{amino.DecodeLimits{MaxDepth: 20, MaxListLen: 10, MaxStringLen: 10, MaxAllocBytes: 10000}, nil},
+		{amino.DecodeLimits{MaxDepth: 18}, amino.LimitExceededErr{Limit: "MaxDepth", Max: 18}},
+		{amino.DecodeLimits{MaxListLen: 9}, amino.LimitExceededErr{Limit: "MaxListLen", Max: 9}},
+		{amino.DecodeLimits{MaxListLen: 2, MaxStringLen: 10}, amino.LimitExceededErr{Limit: "MaxListLen", Max: 2}},
+		{amino.DecodeLimits{MaxStringLen: 9}, amino.LimitExceededErr{Limit: "MaxStringLen", Max: 9}},
+		{amino.DecodeLimits{MaxAllocBytes: 50}, amino.LimitExceededErr{Limit: "MaxAllocBytes", Max: 50}},
+	}
+	for i, tc := range cases {
+		var s2 limitsStruct
+		err = cdc.UnmarshalBinaryBareWithLimits(bz, &s2, tc.limits)
+		assert.Equal(t, tc.err, err, "case %v (binary)", i)
+		if tc.err == nil {
+			assert.Equal(t, s, s2, "case %v (binary)", i)
+		}
+
+		var s3 limitsStruct
+		err = cdc.UnmarshalJSONWithLimits(jbz, &s3, tc.limits)
+		assert.Equal(t, tc.err, err, "case %v (JSON)", i)
+		if tc.err == nil {
+			assert.Equal(t, s, s3, "case %v (JSON)", i)
+		}
+	}
+
+	// The length of a string is checked before reading it.
+	var s4 limitsStruct
+	err = cdc.UnmarshalBinaryBareWithLimits([]byte{0x12, 0xff, 0xff, 0xff, 0xff, 0x0f}, &s4,
+		amino.DecodeLimits{MaxStringLen: 10})
+	assert.Equal(t, amino.LimitExceededErr{Limit: "MaxStringLen", Max: 10}, err)
+
+	// Likewise in JSON, before unmarshaling it.
+	for _, ptr := range []interface{}{new(string), new([]byte)} {
+		var long = []byte(`"` + strings.Repeat("s", 1<<20) + `"`)
+		var before, after runtime.MemStats
+		runtime.ReadMemStats(&before)
+		err = cdc.UnmarshalJSONWithLimits(long, ptr, amino.DecodeLimits{MaxStringLen: 10})
+		runtime.ReadMemStats(&after)
+		assert.Equal(t, amino.LimitExceededErr{Limit: "MaxStringLen", Max: 10}, err)
+		assert.True(t, after.TotalAlloc-before.TotalAlloc < 1<<19, "allocated %v bytes",
+			after.TotalAlloc-before.TotalAlloc)
+	}
+
+	// Escapes are counted by their encoded length.
+	var str string
+	err = cdc.UnmarshalJSONWithLimits([]byte(`"\u0073"`), &str, amino.DecodeLimits{MaxStringLen: 5})
+	assert.Equal(t, amino.LimitExceededErr{Limit: "MaxStringLen", Max: 5}, err)
+	err = cdc.UnmarshalJSONWithLimits([]byte(`"\u0073"`), &str, amino.DecodeLimits{MaxStringLen: 6})
+	assert.NoError(t, err)
+	assert.Equal(t, "s", str)
+}
+
+func TestCodecDecodeLimits(t *testing.T) {
+	cdc := amino.NewCodec()
+	bz, err := cdc.MarshalBinaryLengthPrefixed(newLimitsTree(10))
+	require.NoError(t, err)
+	jbz, err := cdc.MarshalJSON(newLimitsTree(10))
+	require.NoError(t, err)
+
+	limits := amino.DecodeLimits{MaxDepth: 5}
+	cdc.SetDecodeLimits(limits)
+	var tree limitsTree
+	lerr := amino.LimitExceededErr{Limit: "MaxDepth", Max: 5}
+	assert.Equal(t, lerr, cdc.UnmarshalBinaryLengthPrefixed(bz, &tree))
+	assert.Equal(t, lerr, cdc.UnmarshalBinaryBare(bz[1:], &tree))
+	assert.Equal(t, lerr, cdc.UnmarshalJSON(jbz, &tree))
+	_, err = cdc.UnmarshalBinaryLengthPrefixedReader(bytes.NewReader(bz), &tree, 0)
+	assert.Equal(t, lerr, err)
+
+	// The limits can be overridden per call.
+	err = cdc.UnmarshalBinaryLengthPrefixedWithLimits(bz, &tree, amino.DecodeLimits{})
+	assert.NoError(t, err)
+	assert.Equal(t, newLimitsTree(10), tree)
+
+	// The reader doesn't allocate more than MaxAllocBytes for the message.
+	cdc.SetDecodeLimits(amino.DecodeLimits{MaxAllocBytes: 10})
+	_, err = cdc.UnmarshalBinaryLengthPrefixedReader(bytes.NewReader([]byte{0xff, 0xff, 0x03}), &tree, 0)
+	assert.Equal(t, amino.LimitExceededErr{Limit: "MaxAllocBytes", Max: 10}, err)
+}
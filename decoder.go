@@ -18,7 +18,7 @@ func DecodeInt8(bz []byte) (i int8, n int, err error) {
 		return
 	}
 	if i64 < int64(math.MinInt8) || i64 > int64(math.MaxInt8) {
-		err = errors.New("EOF decoding int8")
+		err = kindErrorf(ErrKindOverflow, "overflow decoding int8")
 		return
 	}
 	i = int8(i64)
@@ -32,7 +32,7 @@ func DecodeInt16(bz []byte) (i int16, n int, err error) {
 		return
 	}
 	if i64 < int64(math.MinInt16) || i64 > int64(math.MaxInt16) {
-		err = errors.New("EOF decoding int16")
+		err = kindErrorf(ErrKindOverflow, "overflow decoding int16")
 		return
 	}
 	i = int16(i64)
@@ -42,7 +42,7 @@ func DecodeInt16(bz []byte) (i int16, n int, err error) {
 func DecodeInt32(bz []byte) (i int32, n int, err error) {
 	const size int = 4
 	if len(bz) < size {
-		err = errors.New("EOF decoding int32")
+		err = kindErrorf(ErrKindEOF, "EOF decoding int32")
 		return
 	}
 	i = int32(binary.LittleEndian.Uint32(bz[:size]))
@@ -53,7 +53,7 @@ func DecodeInt32(bz []byte) (i int32, n int, err error) {
 func DecodeInt64(bz []byte) (i int64, n int, err error) {
 	const size int = 8
 	if len(bz) < size {
-		err = errors.New("EOF decoding int64")
+		err = kindErrorf(ErrKindEOF, "EOF decoding int64")
 		return
 	}
 	i = int64(binary.LittleEndian.Uint64(bz[:size]))
@@ -65,12 +65,12 @@ func DecodeVarint(bz []byte) (i int64, n int, err error) {
 	i, n = binary.Varint(bz)
 	if n == 0 {
 		// buf too small
-		err = errors.New("buffer too small")
+		err = kindErrorf(ErrKindEOF, "buffer too small")
 	} else if n < 0 {
 		// value larger than 64 bits (overflow)
 		// and -n is the number of bytes read
 		n = -n
-		err = errors.New("EOF decoding varint")
+		err = kindErrorf(ErrKindOverflow, "overflow decoding varint")
 	}
 	return
 }
@@ -89,7 +89,7 @@ func DecodeUint8(bz []byte) (u uint8, n int, err error) {
 		return
 	}
 	if u64 > uint64(math.MaxUint8) {
-		err = errors.New("EOF decoding uint8")
+		err = kindErrorf(ErrKindOverflow, "overflow decoding uint8")
 		return
 	}
 	u = uint8(u64)
@@ -102,7 +102,7 @@ func DecodeUint16(bz []byte) (u uint16, n int, err error) {
 		return
 	}
 	if u64 > uint64(math.MaxUint16) {
-		err = errors.New("EOF decoding uint16")
+		err = kindErrorf(ErrKindOverflow, "overflow decoding uint16")
 		return
 	}
 	u = uint16(u64)
@@ -112,7 +112,7 @@ func DecodeUint16(bz []byte) (u uint16, n int, err error) {
 func DecodeUint32(bz []byte) (u uint32, n int, err error) {
 	const size int = 4
 	if len(bz) < size {
-		err = errors.New("EOF decoding uint32")
+		err = kindErrorf(ErrKindEOF, "EOF decoding uint32")
 		return
 	}
 	u = binary.LittleEndian.Uint32(bz[:size])
@@ -123,7 +123,7 @@ func DecodeUint32(bz []byte) (u uint32, n int, err error) {
 func DecodeUint64(bz []byte) (u uint64, n int, err error) {
 	const size int = 8
 	if len(bz) < size {
-		err = errors.New("EOF decoding uint64")
+		err = kindErrorf(ErrKindEOF, "EOF decoding uint64")
 		return
 	}
 	u = binary.LittleEndian.Uint64(bz[:size])
@@ -135,12 +135,12 @@ func DecodeUvarint(bz []byte) (u uint64, n int, err error) {
 	u, n = binary.Uvarint(bz)
 	if n == 0 {
 		// buf too small
-		err = errors.New("buffer too small")
+		err = kindErrorf(ErrKindEOF, "buffer too small")
 	} else if n < 0 {
 		// value larger than 64 bits (overflow)
 		// and -n is the number of bytes read
 		n = -n
-		err = errors.New("EOF decoding uvarint")
+		err = kindErrorf(ErrKindOverflow, "overflow decoding uvarint")
 	}
 	return
 }
@@ -151,7 +151,7 @@ func DecodeUvarint(bz []byte) (u uint64, n int, err error) {
 func DecodeBool(bz []byte) (b bool, n int, err error) {
 	const size int = 1
 	if len(bz) < size {
-		err = errors.New("EOF decoding bool")
+		err = kindErrorf(ErrKindEOF, "EOF decoding bool")
 		return
 	}
 	switch bz[0] {
@@ -170,7 +170,7 @@ func DecodeBool(bz []byte) (b bool, n int, err error) {
 func DecodeFloat32(bz []byte) (f float32, n int, err error) {
 	const size int = 4
 	if len(bz) < size {
-		err = errors.New("EOF decoding float32")
+		err = kindErrorf(ErrKindEOF, "EOF decoding float32")
 		return
 	}
 	i := binary.LittleEndian.Uint32(bz[:size])
@@ -183,7 +183,7 @@ func DecodeFloat32(bz []byte) (f float32, n int, err error) {
 func DecodeFloat64(bz []byte) (f float64, n int, err error) {
 	const size int = 8
 	if len(bz) < size {
-		err = errors.New("EOF decoding float64")
+		err = kindErrorf(ErrKindEOF, "EOF decoding float64")
 		return
 	}
 	i := binary.LittleEndian.Uint64(bz[:size])
@@ -253,7 +253,7 @@ func decodeSeconds(bz *[]byte) (int64, int, error) {
 		// skip: do not slide, no error, will read again
 		return 0, n, nil
 	default:
-		return 0, n, fmt.Errorf("expected field number 1 <Varint> or field number 2 <Varint> , got %v", fieldNum)
+		return 0, n, kindErrorf(ErrKindFieldOrder, "expected field number 1 <Varint> or field number 2 <Varint> , got %v", fieldNum)
 	}
 }
 
@@ -282,6 +282,18 @@ func decodeNanos(bz *[]byte, n *int) (int32, error) {
 }
 
 func DecodeByteSlice(bz []byte) (bz2 []byte, n int, err error) {
+	var buf []byte
+	buf, n, err = DecodeByteSliceNoCopy(bz)
+	if err != nil {
+		return
+	}
+	bz2 = make([]byte, len(buf))
+	copy(bz2, buf)
+	return
+}
+
+// Like DecodeByteSlice, but returns a slice of bz instead of a copy.
+func DecodeByteSliceNoCopy(bz []byte) (bz2 []byte, n int, err error) {
 	var count uint64
 	var _n int
 	count, _n, err = DecodeUvarint(bz)
@@ -289,15 +301,14 @@ func DecodeByteSlice(bz []byte) (bz2 []byte, n int, err error) {
 		return
 	}
 	if int(count) < 0 {
-		err = fmt.Errorf("invalid negative length %v decoding []byte", count)
+		err = kindErrorf(ErrKindOverflow, "invalid negative length %v decoding []byte", count)
 		return
 	}
 	if len(bz) < int(count) {
-		err = fmt.Errorf("insufficient bytes decoding []byte of length %v", count)
+		err = kindErrorf(ErrKindEOF, "insufficient bytes decoding []byte of length %v", count)
 		return
 	}
-	bz2 = make([]byte, count)
-	copy(bz2, bz[0:count])
+	bz2 = bz[:count]
 	n += int(count)
 	return
 }
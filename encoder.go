@@ -21,6 +21,9 @@ func EncodeInt16(w io.Writer, i int16) (err error) {
 }
 
 func EncodeInt32(w io.Writer, i int32) (err error) {
+	if bw, ok := w.(io.ByteWriter); ok {
+		return writeLittleEndian(bw, uint64(uint32(i)), 4)
+	}
 	var buf [4]byte
 	binary.LittleEndian.PutUint32(buf[:], uint32(i))
 	_, err = w.Write(buf[:])
@@ -28,6 +31,9 @@ func EncodeInt32(w io.Writer, i int32) (err error) {
 }
 
 func EncodeInt64(w io.Writer, i int64) (err error) {
+	if bw, ok := w.(io.ByteWriter); ok {
+		return writeLittleEndian(bw, uint64(i), 8)
+	}
 	var buf [8]byte
 	binary.LittleEndian.PutUint64(buf[:], uint64(i))
 	_, err = w.Write(buf[:])
@@ -35,6 +41,14 @@ func EncodeInt64(w io.Writer, i int64) (err error) {
 }
 
 func EncodeVarint(w io.Writer, i int64) (err error) {
+	if bw, ok := w.(io.ByteWriter); ok {
+		// Zigzag encoding, see binary.PutVarint.
+		var u = uint64(i) << 1
+		if i < 0 {
+			u = ^u
+		}
+		return writeUvarint(bw, u)
+	}
 	var buf [10]byte
 	n := binary.PutVarint(buf[:], i)
 	_, err = w.Write(buf[0:n])
@@ -63,6 +77,9 @@ func EncodeUint16(w io.Writer, u uint16) (err error) {
 }
 
 func EncodeUint32(w io.Writer, u uint32) (err error) {
+	if bw, ok := w.(io.ByteWriter); ok {
+		return writeLittleEndian(bw, uint64(u), 4)
+	}
 	var buf [4]byte
 	binary.LittleEndian.PutUint32(buf[:], u)
 	_, err = w.Write(buf[:])
@@ -70,6 +87,9 @@ func EncodeUint32(w io.Writer, u uint32) (err error) {
 }
 
 func EncodeUint64(w io.Writer, u uint64) (err error) {
+	if bw, ok := w.(io.ByteWriter); ok {
+		return writeLittleEndian(bw, u, 8)
+	}
 	var buf [8]byte
 	binary.LittleEndian.PutUint64(buf[:], u)
 	_, err = w.Write(buf[:])
@@ -80,12 +100,40 @@ func EncodeUint64(w io.Writer, u uint64) (err error) {
 // `binary:"fixed32"`, `binary:"fixed64"`, or `binary:"zigzag32"` `binary:"zigzag64"` tags.
 // It matches protobufs varint encoding.
 func EncodeUvarint(w io.Writer, u uint64) (err error) {
+	if bw, ok := w.(io.ByteWriter); ok {
+		return writeUvarint(bw, u)
+	}
 	var buf [10]byte
 	n := binary.PutUvarint(buf[:], u)
 	_, err = w.Write(buf[0:n])
 	return
 }
 
+// Writing byte by byte to e.g. a *bytes.Buffer avoids the buffer of
+// w.Write(buf[0:n]), which escapes to the heap.
+func writeUvarint(bw io.ByteWriter, u uint64) (err error) {
+	for u >= 0x80 {
+		err = bw.WriteByte(byte(u) | 0x80)
+		if err != nil {
+			return
+		}
+		u >>= 7
+	}
+	return bw.WriteByte(byte(u))
+}
+
+// Writes the n low bytes of u in little-endian order, see writeUvarint.
+func writeLittleEndian(bw io.ByteWriter, u uint64, n int) (err error) {
+	for i := 0; i < n; i++ {
+		err = bw.WriteByte(byte(u))
+		if err != nil {
+			return
+		}
+		u >>= 8
+	}
+	return
+}
+
 func UvarintSize(u uint64) int {
 	if u == 0 {
 		return 1
@@ -191,5 +239,14 @@ func ByteSliceSize(bz []byte) int {
 }
 
 func EncodeString(w io.Writer, s string) (err error) {
+	if sw, ok := w.(io.StringWriter); ok {
+		// Avoid copying s.
+		err = EncodeUvarint(w, uint64(len(s)))
+		if err != nil {
+			return
+		}
+		_, err = sw.WriteString(s)
+		return
+	}
 	return EncodeByteSlice(w, []byte(s))
 }
@@ -0,0 +1,223 @@
+package amino
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"reflect"
+)
+
+//----------------------------------------
+// Generated code
+
+// AminoBinaryMarshaler is implemented by structs with code generated by
+// cmd/aminogen, which the Codec then uses instead of reflection.
+type AminoBinaryMarshaler interface {
+	// Returns the encoding of the struct, as MarshalBinaryBare would for an
+	// unregistered type.
+	MarshalAminoBinary(cdc *Codec) ([]byte, error)
+	// Returns the length of the encoding of the struct.
+	AminoSize(cdc *Codec) (int, error)
+}
+
+// AminoBinaryUnmarshaler is implemented by pointers to structs with code
+// generated by cmd/aminogen.
+type AminoBinaryUnmarshaler interface {
+	// Decodes all of bz, as returned by MarshalAminoBinary.
+	UnmarshalAminoBinary(cdc *Codec, bz []byte) error
+}
+
+// Returned by generated code in check mode, see Codec.SetCheckGenerated.
+type generatedMismatchErr struct {
+	rt  reflect.Type
+	msg string
+}
+
+func (err generatedMismatchErr) Error() string {
+	return fmt.Sprintf("generated code for %v disagrees with reflection: %v", err.rt, err.msg)
+}
+
+// CONTRACT: info.IsAminoBinaryMarshaler and rv.CanInterface()
+func (cdc *Codec) encodeGeneratedBinary(w io.Writer, info *TypeInfo, rv reflect.Value, fopts FieldOptions, bare bool) (err error) {
+	var bz []byte
+	var gm = rv.Interface().(AminoBinaryMarshaler)
+	bz, err = gm.MarshalAminoBinary(cdc)
+	if cdc.getCheckGenerated() {
+		var buf, es = new(bytes.Buffer), newEncodeState()
+		var plan, rerr = cdc.getBinaryPlan(info)
+		if rerr == nil {
+			_, rerr = cdc.sizeReflectBinaryStruct(info, plan, rv, fopts, true, es)
+		}
+		if rerr == nil {
+			rerr = cdc.encodeReflectBinaryStruct(buf, info, plan, rv, fopts, true, es)
+		}
+		switch {
+		case (err == nil) != (rerr == nil):
+			return generatedMismatchErr{info.Type, fmt.Sprintf("got error %v, want %v", err, rerr)}
+		case err == nil && !bytes.Equal(bz, buf.Bytes()):
+			return generatedMismatchErr{info.Type, fmt.Sprintf("got %X, want %X", bz, buf.Bytes())}
+		}
+		if err == nil {
+			var size int
+			size, err = gm.AminoSize(cdc)
+			if err != nil {
+				return
+			}
+			if size != len(bz) {
+				return generatedMismatchErr{info.Type, fmt.Sprintf("got size %v, want %v", size, len(bz))}
+			}
+		}
+	}
+	if err != nil {
+		return
+	}
+
+	if bare {
+		// Write byteslice without byte-length prefixing.
+		_, err = w.Write(bz)
+	} else {
+		// Write byte-length prefixed byteslice.
+		err = EncodeByteSlice(w, bz)
+	}
+	return
+}
+
+// CONTRACT: info.IsAminoBinaryUnmarshaler and rv.CanAddr()
+func (cdc *Codec) decodeGeneratedBinary(bz []byte, info *TypeInfo, rv reflect.Value) (err error) {
+	var gu = rv.Addr().Interface().(AminoBinaryUnmarshaler)
+	err = gu.UnmarshalAminoBinary(cdc, bz)
+	if cdc.getCheckGenerated() {
+		var rrv = reflect.New(info.Type).Elem()
+		var ds = newDecodeState(DecodeLimits{})
+		ds.input, ds.noGenerated = bz, true
+		var plan, rerr = cdc.getBinaryPlan(info)
+		if rerr == nil {
+			_, rerr = cdc.decodeReflectBinaryStruct(bz, info, plan, rrv, FieldOptions{}, true, ds)
+		}
+		switch {
+		case (err == nil) != (rerr == nil):
+			return generatedMismatchErr{info.Type, fmt.Sprintf("got error %v, want %v", err, rerr)}
+		case err == nil && !reflect.DeepEqual(rv.Interface(), rrv.Interface()):
+			return generatedMismatchErr{info.Type, fmt.Sprintf("got %#v, want %#v", rv.Interface(), rrv.Interface())}
+		}
+	}
+	return
+}
+
+//----------------------------------------
+// Helpers for generated code
+
+// EncodeFieldKey writes the key of field num of type typ.
+func EncodeFieldKey(w io.Writer, num uint32, typ Typ3) error {
+	return encodeFieldNumberAndTyp3(w, num, typ)
+}
+
+// DecodeFieldKey skips any unknown fields numbered less than num, and then
+// reads the key of field num, which must be of type typ.  If the next field
+// has a greater number, found is false and the key is not read.
+// lastFieldNum is the number of the last field read, and is updated.
+func DecodeFieldKey(bz []byte, num uint32, typ Typ3, lastFieldNum *uint32) (found bool, n int, err error) {
+	var _n int
+	_n, err = consumeFieldsBefore(bz, num, lastFieldNum)
+	if slide(&bz, &n, _n) && err != nil {
+		return
+	}
+	if len(bz) == 0 {
+		return
+	}
+	var fnum, typ2 = uint32(0), Typ3(0x00)
+	fnum, typ2, _n, err = decodeFieldNumberAndTyp3(bz)
+	if err != nil {
+		return
+	}
+	if num < fnum {
+		return // Do not slide, the caller will read it again.
+	}
+	if fnum <= *lastFieldNum {
+		err = kindErrorf(ErrKindFieldOrder, "encountered fieldNum: %v, but we have already seen fnum: %v",
+			fnum, *lastFieldNum)
+		return
+	}
+	*lastFieldNum = fnum
+	if typ2 != typ {
+		err = kindErrorf(ErrKindWrongTyp3, "expected field type %v for # %v, got %v", typ, fnum, typ2)
+		return
+	}
+	slide(&bz, &n, _n)
+	found = true
+	return
+}
+
+// SkipUnknownFields skips the remaining fields of bz, which must all be
+// numbered greater than lastFieldNum.
+func SkipUnknownFields(bz []byte, lastFieldNum *uint32) (n int, err error) {
+	return consumeFieldsBefore(bz, maxFieldNum+1, lastFieldNum)
+}
+
+// EncodeBinaryField writes the field with the given (Go) index of the struct
+// pointed to by ptr, as the Codec would.  This lets generated code fall back
+// to reflection for fields that it doesn't handle.
+func (cdc *Codec) EncodeBinaryField(buf *bytes.Buffer, ptr interface{}, index int) error {
+	var rv = reflect.ValueOf(ptr).Elem()
+	var _, field, fplan, err = cdc.getFieldInfo(rv.Type(), index)
+	if err != nil {
+		return err
+	}
+	var es = newEncodeState()
+	_, err = cdc.sizeReflectBinaryField(field, fplan, rv.Field(index), FieldOptions{}, es)
+	if err != nil {
+		return err
+	}
+	return cdc.encodeReflectBinaryField(buf, field, fplan, rv.Field(index), FieldOptions{}, es)
+}
+
+// SizeBinaryField returns the length of what EncodeBinaryField would write.
+func (cdc *Codec) SizeBinaryField(ptr interface{}, index int) (int, error) {
+	var rv = reflect.ValueOf(ptr).Elem()
+	var _, field, fplan, err = cdc.getFieldInfo(rv.Type(), index)
+	if err != nil {
+		return 0, err
+	}
+	return cdc.sizeReflectBinaryField(field, fplan, rv.Field(index), FieldOptions{}, nil)
+}
+
+// DecodeBinaryField decodes the field with the given (Go) index of the struct
+// pointed to by ptr, like DecodeFieldKey followed by the value, and sets it to
+// the default value if the field isn't present.
+func (cdc *Codec) DecodeBinaryField(bz []byte, ptr interface{}, index int, lastFieldNum *uint32) (n int, err error) {
+	var rv = reflect.ValueOf(ptr).Elem()
+	var info, field, fplan = (*TypeInfo)(nil), (*FieldInfo)(nil), (*fieldPlan)(nil)
+	info, field, fplan, err = cdc.getFieldInfo(rv.Type(), index)
+	if err != nil {
+		return
+	}
+	var ds = newDecodeState(DecodeLimits{})
+	ds.root, ds.input = info.Type, bz
+	var _n int
+	_n, err = consumeFieldsBefore(bz, field.BinFieldNum, lastFieldNum)
+	if slide(&bz, &n, _n) && err != nil {
+		return
+	}
+	_n, err = cdc.decodeReflectBinaryField(bz, info, field, fplan, rv.Field(index), false, lastFieldNum, ds)
+	slide(&bz, &n, _n)
+	return
+}
+
+func (cdc *Codec) getFieldInfo(rt reflect.Type, index int) (info *TypeInfo, field *FieldInfo, fplan *fieldPlan, err error) {
+	info, err = cdc.getTypeInfoWlock(rt)
+	if err != nil {
+		return
+	}
+	var plan *binaryPlan
+	plan, err = cdc.getBinaryPlan(info)
+	if err != nil {
+		return
+	}
+	for i := range info.Fields {
+		if info.Fields[i].Index == index {
+			return info, &info.Fields[i], &plan.fields[i], nil
+		}
+	}
+	err = fmt.Errorf("%v has no amino field with index %v, regenerate its code?", rt, index)
+	return
+}
@@ -2,9 +2,11 @@ package amino
 
 import (
 	"bytes"
+	"encoding"
 	"encoding/json"
 	"fmt"
 	"reflect"
+	"strconv"
 
 	"github.com/pkg/errors"
 
@@ -14,14 +16,21 @@ import (
 //----------------------------------------
 // cdc.decodeReflectJSON
 
+// Errors are returned as DecodeErrors for the innermost failing value.
 // CONTRACT: rv.CanAddr() is true.
-func (cdc *Codec) decodeReflectJSON(bz []byte, info *TypeInfo, rv reflect.Value, fopts FieldOptions) (err error) {
+func (cdc *Codec) decodeReflectJSON(bz []byte, info *TypeInfo, rv reflect.Value, fopts FieldOptions, ds *decodeState) (err error) {
+	err = cdc.decodeReflectJSONValue(bz, info, rv, fopts, ds)
+	if err != nil {
+		err = ds.jsonError(err, info.Type)
+	}
+	return
+}
+
+// CONTRACT: rv.CanAddr() is true.
+func (cdc *Codec) decodeReflectJSONValue(bz []byte, info *TypeInfo, rv reflect.Value, fopts FieldOptions, ds *decodeState) (err error) {
 	if !rv.CanAddr() {
 		panic("rv not addressable")
 	}
-	if info.Type.Kind() == reflect.Interface && rv.Kind() == reflect.Ptr {
-		panic("should not happen")
-	}
 	if printLog {
 		spew.Printf("(D) decodeReflectJSON(bz: %s, info: %v, rv: %#v (%v), fopts: %v)\n",
 			bz, info, rv.Interface(), rv.Type(), fopts)
@@ -41,11 +50,19 @@ func (cdc *Codec) decodeReflectJSON(bz []byte, info *TypeInfo, rv reflect.Value,
 	// This works for pointer-pointers.
 	for rv.Kind() == reflect.Ptr {
 		if rv.IsNil() {
+			if err = ds.allocateType(rv.Type().Elem()); err != nil {
+				return
+			}
 			newPtr := reflect.New(rv.Type().Elem())
 			rv.Set(newPtr)
 		}
 		rv = rv.Elem()
 	}
+	// Pointers to interfaces (e.g. *Iface fields) are dereferenced above,
+	// but nothing else may be decoded as an interface.
+	if info.Type.Kind() == reflect.Interface && rv.Kind() != reflect.Interface {
+		panic("should not happen")
+	}
 
 	// Special case:
 	if rv.Type() == timeType {
@@ -75,7 +92,7 @@ func (cdc *Codec) decodeReflectJSON(bz []byte, info *TypeInfo, rv reflect.Value,
 		if err != nil {
 			return
 		}
-		err = cdc.decodeReflectJSON(bz, rinfo, rrv, fopts)
+		err = cdc.decodeReflectJSON(bz, rinfo, rrv, fopts, ds)
 		if err != nil {
 			return
 		}
@@ -89,25 +106,42 @@ func (cdc *Codec) decodeReflectJSON(bz []byte, info *TypeInfo, rv reflect.Value,
 		return
 	}
 
+	// Enums are written as their names, see RegisterEnum.  Numbers are
+	// accepted too, which is how undefined values are written.
+	if info.IsEnum && len(bz) > 0 && bz[0] == '"' {
+		var name string
+		if err = json.Unmarshal(bz, &name); err != nil {
+			return
+		}
+		if bits, ok := info.EnumValues[name]; ok {
+			setEnumBits(rv, bits)
+			return
+		}
+		if isEnumName(name) {
+			err = kindErrorf(ErrKindUndefinedEnum, "undefined name %q of enum %v", name, info.Type)
+			return
+		}
+	}
+
 	switch ikind := info.Type.Kind(); ikind {
 
 	//----------------------------------------
 	// Complex
 
 	case reflect.Interface:
-		err = cdc.decodeReflectJSONInterface(bz, info, rv, fopts)
+		err = cdc.decodeReflectJSONInterface(bz, info, rv, fopts, ds)
 
 	case reflect.Array:
-		err = cdc.decodeReflectJSONArray(bz, info, rv, fopts)
+		err = cdc.decodeReflectJSONArray(bz, info, rv, fopts, ds)
 
 	case reflect.Slice:
-		err = cdc.decodeReflectJSONSlice(bz, info, rv, fopts)
+		err = cdc.decodeReflectJSONSlice(bz, info, rv, fopts, ds)
 
 	case reflect.Struct:
-		err = cdc.decodeReflectJSONStruct(bz, info, rv, fopts)
+		err = cdc.decodeReflectJSONStruct(bz, info, rv, fopts, ds)
 
 	case reflect.Map:
-		err = cdc.decodeReflectJSONMap(bz, info, rv, fopts)
+		err = cdc.decodeReflectJSONMap(bz, info, rv, fopts, ds)
 
 	//----------------------------------------
 	// Signed, Unsigned
@@ -126,6 +160,9 @@ func (cdc *Codec) decodeReflectJSON(bz []byte, info *TypeInfo, rv reflect.Value,
 	case reflect.Int32, reflect.Int16, reflect.Int8,
 		reflect.Uint32, reflect.Uint16, reflect.Uint8:
 		err = invokeStdlibJSONUnmarshal(bz, rv, fopts)
+		if err == nil && info.IsEnum {
+			err = checkEnumDefined(info, rv)
+		}
 
 	//----------------------------------------
 	// Misc
@@ -135,8 +172,19 @@ func (cdc *Codec) decodeReflectJSON(bz []byte, info *TypeInfo, rv reflect.Value,
 			return errors.New("amino:JSON float* support requires `amino:\"unsafe\"`")
 		}
 		fallthrough
-	case reflect.Bool, reflect.String:
+	case reflect.Bool:
+		err = invokeStdlibJSONUnmarshal(bz, rv, fopts)
+
+	case reflect.String:
+		var length int
+		if length, err = ds.allocateJSONString(bz, false); err != nil {
+			return
+		}
 		err = invokeStdlibJSONUnmarshal(bz, rv, fopts)
+		if err == nil && rv.Len() > length {
+			// Invalid UTF-8 is decoded as the longer U+FFFD.
+			err = ds.allocateString(rv.Len())
+		}
 
 	//----------------------------------------
 	// Default
@@ -166,7 +214,7 @@ func invokeStdlibJSONUnmarshal(bz []byte, rv reflect.Value, fopts FieldOptions)
 }
 
 // CONTRACT: rv.CanAddr() is true.
-func (cdc *Codec) decodeReflectJSONInterface(bz []byte, iinfo *TypeInfo, rv reflect.Value, fopts FieldOptions) (err error) {
+func (cdc *Codec) decodeReflectJSONInterface(bz []byte, iinfo *TypeInfo, rv reflect.Value, fopts FieldOptions, ds *decodeState) (err error) {
 	if !rv.CanAddr() {
 		panic("rv not addressable")
 	}
@@ -176,28 +224,16 @@ func (cdc *Codec) decodeReflectJSONInterface(bz []byte, iinfo *TypeInfo, rv refl
 			fmt.Printf("(d) -> err: %v\n", err)
 		}()
 	}
-
-	/*
-		We don't make use of user-provided interface values because there are a
-		lot of edge cases.
-
-		* What if the type is mismatched?
-		* What if the JSON field entry is missing?
-		* Circular references?
-	*/
-	if !rv.IsNil() {
-		// We don't strictly need to set it nil, but lets keep it here for a
-		// while in case we forget, for defensive purposes.
-		rv.Set(iinfo.ZeroValue)
+	if err = ds.enter(); err != nil {
+		return
 	}
+	defer ds.leave()
 
 	// Consume type wrapper info.
 	name, bz, err := decodeInterfaceJSON(bz)
 	if err != nil {
 		return
 	}
-	// XXX: Check name against interface to make sure that it actually
-	// matches, and return an error if it doesn't.
 
 	// NOTE: Unlike decodeReflectBinaryInterface, we already dealt with nil in decodeReflectJSON.
 	// NOTE: We also "consumed" the interface wrapper by replacing `bz` above.
@@ -206,15 +242,36 @@ func (cdc *Codec) decodeReflectJSONInterface(bz []byte, iinfo *TypeInfo, rv refl
 	// NOTE: Unlike decodeReflectBinaryInterface, uses the full name string.
 	var cinfo *TypeInfo
 	cinfo, err = cdc.getTypeInfoFromNameRlock(name)
+	if err != nil && allowsUnknownConcrete(iinfo) {
+		// Keep the value as is, see UnknownConcrete.
+		if err = ds.allocate(int64(len(bz))); err != nil {
+			return
+		}
+		rv.Set(reflect.ValueOf(UnknownConcrete{Name: name, JSONValue: append([]byte(nil), bz...)}))
+		return
+	}
 	if err != nil {
 		return
 	}
+	if err = checkConcreteAllowed(iinfo, cinfo, fopts); err != nil {
+		return
+	}
+	if cinfo.Type.Kind() == reflect.Interface {
+		// The allowed names of the field don't apply to the value of an
+		// interface-pointer registered as concrete, see RegisterConcrete.
+		fopts.Allow = nil
+	}
 
 	// Construct the concrete type.
-	var crv, irvSet = constructConcreteType(cinfo)
+	if err = ds.allocateType(cinfo.Type); err != nil {
+		return
+	}
+	// Like in decodeReflectBinaryInterface, a non-nil rv pointing to the
+	// concrete type keeps its pointer, otherwise it is replaced.
+	var crv, irvSet, reused = reuseConcreteType(rv, cinfo)
 
 	// Decode into the concrete type.
-	err = cdc.decodeReflectJSON(bz, cinfo, crv, fopts)
+	err = cdc.decodeReflectJSON(bz, cinfo, crv, fopts, ds)
 	if err != nil {
 		rv.Set(irvSet) // Helps with debugging
 		return
@@ -223,12 +280,15 @@ func (cdc *Codec) decodeReflectJSONInterface(bz []byte, iinfo *TypeInfo, rv refl
 	// We need to set here, for when !PointerPreferred and the type
 	// is say, an array of bytes (e.g. [32]byte), then we must call
 	// rv.Set() *after* the value was acquired.
+	if reused.IsValid() {
+		reused.Set(crv)
+	}
 	rv.Set(irvSet)
 	return
 }
 
 // CONTRACT: rv.CanAddr() is true.
-func (cdc *Codec) decodeReflectJSONArray(bz []byte, info *TypeInfo, rv reflect.Value, fopts FieldOptions) (err error) {
+func (cdc *Codec) decodeReflectJSONArray(bz []byte, info *TypeInfo, rv reflect.Value, fopts FieldOptions, ds *decodeState) (err error) {
 	if !rv.CanAddr() {
 		panic("rv not addressable")
 	}
@@ -238,6 +298,10 @@ func (cdc *Codec) decodeReflectJSONArray(bz []byte, info *TypeInfo, rv reflect.V
 			fmt.Printf("(d) -> err: %v\n", err)
 		}()
 	}
+	if err = ds.enter(); err != nil {
+		return
+	}
+	defer ds.leave()
 	ert := info.Type.Elem()
 	length := info.Type.Len()
 
@@ -253,7 +317,11 @@ func (cdc *Codec) decodeReflectJSONArray(bz []byte, info *TypeInfo, rv reflect.V
 			err = fmt.Errorf("decodeReflectJSONArray: byte-length mismatch, got %v want %v",
 				len(buf), length)
 		}
-		reflect.Copy(rv, reflect.ValueOf(buf))
+		if ert == byteType {
+			reflect.Copy(rv, reflect.ValueOf(buf))
+		} else if err == nil {
+			err = cdc.copyNamedBytes(rv, buf)
+		}
 		return
 
 	default: // General case.
@@ -277,17 +345,19 @@ func (cdc *Codec) decodeReflectJSONArray(bz []byte, info *TypeInfo, rv reflect.V
 		for i := 0; i < length; i++ {
 			erv := rv.Index(i)
 			ebz := rawSlice[i]
-			err = cdc.decodeReflectJSON(ebz, einfo, erv, fopts)
+			ds.pushIndex(i)
+			err = cdc.decodeReflectJSON(ebz, einfo, erv, fopts, ds)
 			if err != nil {
 				return
 			}
+			ds.pop()
 		}
 		return
 	}
 }
 
 // CONTRACT: rv.CanAddr() is true.
-func (cdc *Codec) decodeReflectJSONSlice(bz []byte, info *TypeInfo, rv reflect.Value, fopts FieldOptions) (err error) {
+func (cdc *Codec) decodeReflectJSONSlice(bz []byte, info *TypeInfo, rv reflect.Value, fopts FieldOptions, ds *decodeState) (err error) {
 	if !rv.CanAddr() {
 		panic("rv not addressable")
 	}
@@ -297,12 +367,19 @@ func (cdc *Codec) decodeReflectJSONSlice(bz []byte, info *TypeInfo, rv reflect.V
 			fmt.Printf("(d) -> err: %v\n", err)
 		}()
 	}
+	if err = ds.enter(); err != nil {
+		return
+	}
+	defer ds.leave()
 
 	var ert = info.Type.Elem()
 
 	switch ert.Kind() {
 
 	case reflect.Uint8: // Special case: byte slice
+		if _, err = ds.allocateJSONString(bz, true); err != nil {
+			return
+		}
 		err = json.Unmarshal(bz, rv.Addr().Interface())
 		if err != nil {
 			return
@@ -315,6 +392,10 @@ func (cdc *Codec) decodeReflectJSONSlice(bz []byte, info *TypeInfo, rv reflect.V
 		// else {
 		// NOTE: Already set via json.Unmarshal() above.
 		// }
+		if ert != byteType && rv.Len() > 0 {
+			// Check the values of strict enums.
+			err = cdc.copyNamedBytes(rv, rv.Bytes())
+		}
 		return
 
 	default: // General case.
@@ -339,15 +420,22 @@ func (cdc *Codec) decodeReflectJSONSlice(bz []byte, info *TypeInfo, rv reflect.V
 		}
 
 		// Read into a new slice.
+		for i := 0; i < length; i++ {
+			if err = ds.allocateElem(i, ert); err != nil {
+				return
+			}
+		}
 		var esrt = reflect.SliceOf(ert) // TODO could be optimized.
 		var srv = reflect.MakeSlice(esrt, length, length)
 		for i := 0; i < length; i++ {
 			erv := srv.Index(i)
 			ebz := rawSlice[i]
-			err = cdc.decodeReflectJSON(ebz, einfo, erv, fopts)
+			ds.pushIndex(i)
+			err = cdc.decodeReflectJSON(ebz, einfo, erv, fopts, ds)
 			if err != nil {
 				return
 			}
+			ds.pop()
 		}
 
 		// TODO do we need this extra step?
@@ -357,7 +445,7 @@ func (cdc *Codec) decodeReflectJSONSlice(bz []byte, info *TypeInfo, rv reflect.V
 }
 
 // CONTRACT: rv.CanAddr() is true.
-func (cdc *Codec) decodeReflectJSONStruct(bz []byte, info *TypeInfo, rv reflect.Value, fopts FieldOptions) (err error) {
+func (cdc *Codec) decodeReflectJSONStruct(bz []byte, info *TypeInfo, rv reflect.Value, fopts FieldOptions, ds *decodeState) (err error) {
 	if !rv.CanAddr() {
 		panic("rv not addressable")
 	}
@@ -367,6 +455,10 @@ func (cdc *Codec) decodeReflectJSONStruct(bz []byte, info *TypeInfo, rv reflect.
 			fmt.Printf("(d) -> err: %v\n", err)
 		}()
 	}
+	if err = ds.enter(); err != nil {
+		return
+	}
+	defer ds.leave()
 
 	// Map all the fields(keys) to their blobs/bytes.
 	// NOTE: In decodeReflectBinaryStruct, we don't need to do this,
@@ -408,17 +500,19 @@ func (cdc *Codec) decodeReflectJSONStruct(bz []byte, info *TypeInfo, rv reflect.
 		}
 
 		// Decode into field rv.
-		err = cdc.decodeReflectJSON(valueBytes, finfo, frv, fopts)
+		ds.pushField(&field)
+		err = cdc.decodeReflectJSON(valueBytes, finfo, frv, field.FieldOptions, ds)
 		if err != nil {
 			return
 		}
+		ds.pop()
 	}
 
 	return nil
 }
 
 // CONTRACT: rv.CanAddr() is true.
-func (cdc *Codec) decodeReflectJSONMap(bz []byte, info *TypeInfo, rv reflect.Value, fopts FieldOptions) (err error) {
+func (cdc *Codec) decodeReflectJSONMap(bz []byte, info *TypeInfo, rv reflect.Value, fopts FieldOptions, ds *decodeState) (err error) {
 	if !rv.CanAddr() {
 		panic("rv not addressable")
 	}
@@ -428,6 +522,10 @@ func (cdc *Codec) decodeReflectJSONMap(bz []byte, info *TypeInfo, rv reflect.Val
 			fmt.Printf("(d) -> err: %v\n", err)
 		}()
 	}
+	if err = ds.enter(); err != nil {
+		return
+	}
+	defer ds.leave()
 
 	// Map all the fields(keys) to their blobs/bytes.
 	// NOTE: In decodeReflectBinaryMap, we don't need to do this,
@@ -439,10 +537,6 @@ func (cdc *Codec) decodeReflectJSONMap(bz []byte, info *TypeInfo, rv reflect.Val
 	}
 
 	var krt = rv.Type().Key()
-	if krt.Kind() != reflect.String {
-		err = fmt.Errorf("decodeReflectJSONMap: key type must be string") // TODO also support []byte and maybe others
-		return
-	}
 	var vinfo *TypeInfo
 	vinfo, err = cdc.getTypeInfoWlock(rv.Type().Elem())
 	if err != nil {
@@ -453,17 +547,30 @@ func (cdc *Codec) decodeReflectJSONMap(bz []byte, info *TypeInfo, rv reflect.Val
 	for key, valueBytes := range rawMap {
 
 		// Get map value rv.
+		if err = ds.allocateElem(mrv.Len(), krt); err != nil {
+			return
+		}
+		if err = ds.allocateType(mrv.Type().Elem()); err != nil {
+			return
+		}
 		vrv := reflect.New(mrv.Type().Elem()).Elem()
 
+		// Decode the key.
+		var krv reflect.Value
+		krv, err = decodeJSONMapKey(krt, key)
+		if err != nil {
+			return
+		}
+
 		// Decode valueBytes into vrv.
-		err = cdc.decodeReflectJSON(valueBytes, vinfo, vrv, fopts)
+		ds.pushKey(krv)
+		err = cdc.decodeReflectJSON(valueBytes, vinfo, vrv, fopts, ds)
 		if err != nil {
 			return
 		}
+		ds.pop()
 
 		// And set.
-		krv := reflect.New(reflect.TypeOf("")).Elem()
-		krv.SetString(key)
 		mrv.SetMapIndex(krv, vrv)
 	}
 	rv.Set(mrv)
@@ -512,3 +619,35 @@ func decodeInterfaceJSON(bz []byte) (name string, data []byte, err error) {
 func nullBytes(b []byte) bool {
 	return bytes.Equal(b, []byte(`null`))
 }
+
+// Returns the map key of type krt for JSON object key.
+// See encodeJSONMapKey.
+func decodeJSONMapKey(krt reflect.Type, key string) (krv reflect.Value, err error) {
+	krv = reflect.New(krt).Elem()
+	if isTextMapKey(krt) {
+		err = krv.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(key))
+		return
+	}
+	switch krt.Kind() {
+	case reflect.String:
+		krv.SetString(key)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		var i int64
+		i, err = strconv.ParseInt(key, 10, krt.Bits())
+		krv.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		var u uint64
+		u, err = strconv.ParseUint(key, 10, krt.Bits())
+		krv.SetUint(u)
+	case reflect.Bool:
+		var b bool
+		b, err = strconv.ParseBool(key)
+		krv.SetBool(b)
+	default:
+		err = fmt.Errorf("unsupported JSON map key type %v", krt)
+	}
+	if err != nil {
+		err = errors.Wrapf(err, "invalid JSON map key %q for %v", key, krt)
+	}
+	return
+}
@@ -1,10 +1,13 @@
 package amino
 
 import (
+	"encoding"
 	"encoding/json"
 	"fmt"
 	"io"
 	"reflect"
+	"sort"
+	"strconv"
 	"time"
 
 	"github.com/pkg/errors"
@@ -77,6 +80,14 @@ func (cdc *Codec) encodeReflectJSON(w io.Writer, info *TypeInfo, rv reflect.Valu
 		return
 	}
 
+	// Enums are written as their names, see RegisterEnum.  Undefined values
+	// are written as numbers.
+	if info.IsEnum {
+		if name, ok := info.EnumNames[enumBits(rv)]; ok {
+			return invokeStdlibJSONMarshal(w, name)
+		}
+	}
+
 	switch info.Type.Kind() {
 
 	//----------------------------------------
@@ -142,16 +153,45 @@ func (cdc *Codec) encodeReflectJSONInterface(w io.Writer, iinfo *TypeInfo, rv re
 		return
 	}
 
+	// Write unknown concrete types as they were read.
+	if rv.Elem().Type() == unknownConcreteType {
+		uc := rv.Elem().Interface().(UnknownConcrete)
+		if uc.Name == "" {
+			err = errors.Errorf("cannot encode amino.UnknownConcrete %X to JSON", uc.Prefix)
+			return
+		}
+		// The name was read from JSON, so it may need escaping.
+		err = writeStr(w, `{"type":`)
+		if err != nil {
+			return
+		}
+		err = invokeStdlibJSONMarshal(w, uc.Name)
+		if err != nil {
+			return
+		}
+		err = writeStr(w, `,"value":`)
+		if err != nil {
+			return
+		}
+		_, err = w.Write(uc.JSONValue)
+		if err != nil {
+			return
+		}
+		err = writeStr(w, `}`)
+		return
+	}
+
 	// Get concrete non-pointer reflect value & type.
 	var crv, isPtr, isNilPtr = derefPointers(rv.Elem())
-	if isPtr && crv.Kind() == reflect.Interface {
-		// See "MARKER: No interface-pointers" in codec.go
-		panic("should not happen")
-	}
 	if isNilPtr {
 		panic(fmt.Sprintf("Illegal nil-pointer of type %v for registered interface %v. "+
 			"For compatibility with other languages, nil-pointer interface values are forbidden.", crv.Type(), iinfo.Type))
 	}
+	if isPtr && crv.Kind() == reflect.Interface && crv.IsNil() {
+		// Of an interface-pointer registered as concrete, see RegisterConcrete.
+		err = errors.Errorf("cannot encode pointer to nil %v as %v", crv.Type(), iinfo.Type)
+		return
+	}
 	var crt = crv.Type()
 
 	// Get *TypeInfo for concrete type.
@@ -161,7 +201,7 @@ func (cdc *Codec) encodeReflectJSONInterface(w io.Writer, iinfo *TypeInfo, rv re
 		return
 	}
 	if !cinfo.Registered {
-		err = errors.Errorf("cannot encode unregistered concrete type %v", crt)
+		err = errors.Errorf("cannot encode unregistered concrete type %v as %v, see RegisterConcrete", crt, iinfo.Type)
 		return
 	}
 
@@ -215,9 +255,15 @@ func (cdc *Codec) encodeReflectJSONList(w io.Writer, info *TypeInfo, rv reflect.
 		bz := []byte(nil)
 		if rv.CanAddr() {
 			bz = rv.Slice(0, length).Bytes()
-		} else {
+		} else if ert == byteType {
 			bz = make([]byte, length)
 			reflect.Copy(reflect.ValueOf(bz), rv) // XXX: looks expensive!
+		} else {
+			// reflect.Copy requires identical types, e.g. for enums.
+			bz = make([]byte, length)
+			for i := range bz {
+				bz[i] = byte(rv.Index(i).Uint())
+			}
 		}
 		jsonBytes := []byte(nil)
 		jsonBytes, err = json.Marshal(bz) // base64 encode
@@ -356,16 +402,35 @@ func (cdc *Codec) encodeReflectJSONMap(w io.Writer, info *TypeInfo, rv reflect.V
 		}
 	}()
 
-	// Ensure that the map key type is a string.
-	if rv.Type().Key().Kind() != reflect.String {
-		err = errors.New("encodeReflectJSONMap: map key type must be a string")
-		return
+	// Get the key strings, and write entries sorted by key string
+	// so that the output is deterministic.
+	var keys = rv.MapKeys()
+	var keyStrs = make([]string, len(keys))
+	for i, krv := range keys {
+		keyStrs[i], err = encodeJSONMapKey(krv)
+		if err != nil {
+			return
+		}
+	}
+	var order = make([]int, len(keys))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(i, j int) bool {
+		return keyStrs[order[i]] < keyStrs[order[j]]
+	})
+	for i := 1; i < len(order); i++ {
+		if keyStrs[order[i-1]] == keyStrs[order[i]] {
+			err = errors.Errorf("JSON map keys %v and %v of %v both encode to %q",
+				keys[order[i-1]].Interface(), keys[order[i]].Interface(), info.Type, keyStrs[order[i]])
+			return
+		}
 	}
 
 	var writeComma = false
-	for _, krv := range rv.MapKeys() {
+	for _, i := range order {
 		// Get dereferenced object value and info.
-		var vrv, _, isNil = derefPointers(rv.MapIndex(krv))
+		var vrv, _, isNil = derefPointers(rv.MapIndex(keys[i]))
 
 		// Add a comma if we need to.
 		if writeComma {
@@ -376,7 +441,7 @@ func (cdc *Codec) encodeReflectJSONMap(w io.Writer, info *TypeInfo, rv reflect.V
 			writeComma = false
 		}
 		// Write field name.
-		err = invokeStdlibJSONMarshal(w, krv.Interface())
+		err = invokeStdlibJSONMarshal(w, keyStrs[i])
 		if err != nil {
 			return
 		}
@@ -418,6 +483,41 @@ func invokeMarshalJSON(w io.Writer, rv reflect.Value) error {
 	return err
 }
 
+// Returns the JSON object key for map key krv.  Keys of text types are
+// marshaled, keys of string kind are used as is, and integers and bools are
+// formatted.  See decodeJSONMapKey.
+func encodeJSONMapKey(krv reflect.Value) (string, error) {
+	if isTextMapKey(krv.Type()) {
+		// MarshalText may have a pointer receiver, and krv is not addressable.
+		var prv = reflect.New(krv.Type())
+		prv.Elem().Set(krv)
+		bz, err := prv.Interface().(encoding.TextMarshaler).MarshalText()
+		return string(bz), err
+	}
+	switch krv.Kind() {
+	case reflect.String:
+		return krv.String(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(krv.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(krv.Uint(), 10), nil
+	case reflect.Bool:
+		return strconv.FormatBool(krv.Bool()), nil
+	default:
+		return "", fmt.Errorf("unsupported JSON map key type %v", krv.Type())
+	}
+}
+
+// Returns whether map keys of type krt are encoded as text, i.e. whether krt
+// implements both encoding.TextMarshaler and encoding.TextUnmarshaler.  The
+// methods of krt and of a pointer to it are considered alike, so that keys
+// are encoded and decoded the same way.
+func isTextMapKey(krt reflect.Type) bool {
+	// The method set of *krt includes that of krt.
+	var prt = reflect.PtrTo(krt)
+	return prt.Implements(textMarshalerType) && prt.Implements(textUnmarshalerType)
+}
+
 func invokeStdlibJSONMarshal(w io.Writer, v interface{}) error {
 	// Note: Please don't stream out the output because that adds a newline
 	// using json.NewEncoder(w).Encode(data)
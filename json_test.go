@@ -43,7 +43,7 @@ func TestMarshalJSON(t *testing.T) {
 		{&oneExportedField{A: "Z"}, `{"A":"Z"}`, ""},         // #6
 		{[]string{"a", "bc"}, `["a","bc"]`, ""},              // #7
 		{[]interface{}{"a", "bc", 10, 10.93, 1e3},
-			``, "Unregistered"}, // #8
+			``, "unregistered concrete type string"}, // #8
 		{aPointerField{Foo: new(int), Name: "name"},
 			`{"Foo":"0","nm":"name"}`, ""}, // #9
 		{
@@ -91,7 +91,7 @@ func TestMarshalJSON(t *testing.T) {
 
 		// We don't yet support interface pointer registration i.e. `*interface{}`
 		{
-			interfacePtr("a"), "", "Unregistered interface interface {}",
+			interfacePtr("a"), "", "unregistered concrete type string",
 		}, // #20
 		{&fp{"Foo", 10}, "<FP-MARSHALJSON>", ""}, // #21
 		{(*fp)(nil), "null", ""},                 // #22
@@ -260,7 +260,7 @@ func TestUnmarshalJSON(t *testing.T) {
 		},
 		{ // #9
 			`[1, "2", ["foo", "bar"]]`,
-			new([]interface{}), nil, "Unregistered",
+			new([]interface{}), nil, "cannot parse disfix JSON wrapper",
 		},
 		{ // #10
 			`2.34`, floatPtr(2.34), nil, "float* support requires",
@@ -512,12 +512,9 @@ func TestMarshalJSONMap(t *testing.T) {
 		Map3nil   map[string]*SimpleStruct
 		Map3empty map[string]*SimpleStruct
 
-		/*
-			NOT SUPPORTED YET.  FIRST, DEFINE SPEC.
-			Map4      map[int]*SimpleStruct
-			Map4nil   map[int]*SimpleStruct
-			Map4empty map[int]*SimpleStruct
-		*/
+		Map4      map[int]*SimpleStruct
+		Map4nil   map[int]*SimpleStruct
+		Map4empty map[int]*SimpleStruct
 	}
 
 	ms := MapsStruct{
@@ -533,11 +530,9 @@ func TestMarshalJSONMap(t *testing.T) {
 		Map3nil:   (map[string]*SimpleStruct)(nil),
 		Map3empty: map[string]*SimpleStruct{},
 
-		/*
-			Map4:      map[int]*SimpleStruct{123: &SimpleStruct{Foo: 1, Bar: []byte("bar")}},
-			Map4nil:   (map[int]*SimpleStruct)(nil),
-			Map4empty: map[int]*SimpleStruct{},
-		*/
+		Map4:      map[int]*SimpleStruct{123: {Foo: 1, Bar: []byte("bar")}},
+		Map4nil:   (map[int]*SimpleStruct)(nil),
+		Map4empty: map[int]*SimpleStruct{},
 	}
 
 	// ms2 is expected to be this.
@@ -554,11 +549,9 @@ func TestMarshalJSONMap(t *testing.T) {
 		Map3nil:   map[string]*SimpleStruct{},
 		Map3empty: map[string]*SimpleStruct{},
 
-		/*
-			Map4:      map[int]*SimpleStruct{123: &SimpleStruct{Foo: 1, Bar: []byte("bar")}},
-			Map4nil:   (map[int]*SimpleStruct)(nil),
-			Map4empty: map[int]*SimpleStruct{},
-		*/
+		Map4:      map[int]*SimpleStruct{123: {Foo: 1, Bar: []byte("bar")}},
+		Map4nil:   map[int]*SimpleStruct{},
+		Map4empty: map[int]*SimpleStruct{},
 	}
 
 	b, err := cdc.MarshalJSON(ms)
@@ -570,6 +563,91 @@ func TestMarshalJSONMap(t *testing.T) {
 	assert.Equal(t, ms3, ms2)
 }
 
+type textKey struct {
+	A, B string
+}
+
+func (tk textKey) MarshalText() ([]byte, error) {
+	return []byte(tk.A + "-" + tk.B), nil
+}
+
+func (tk *textKey) UnmarshalText(text []byte) error {
+	parts := strings.SplitN(string(text), "-", 2)
+	if len(parts) != 2 {
+		return fmt.Errorf("invalid textKey %q", text)
+	}
+	tk.A, tk.B = parts[0], parts[1]
+	return nil
+}
+
+// A string kind with text methods on the pointer.
+type upperKey string
+
+func (uk *upperKey) MarshalText() ([]byte, error) {
+	return []byte(strings.ToUpper(string(*uk))), nil
+}
+
+func (uk *upperKey) UnmarshalText(text []byte) error {
+	*uk = upperKey(strings.ToLower(string(text)))
+	return nil
+}
+
+func TestMarshalJSONMapKeys(t *testing.T) {
+	var cdc = amino.NewCodec()
+
+	type MapKeysStruct struct {
+		Strings map[string]int
+		Ints    map[int64]string
+		Uints   map[uint8]bool
+		Bools   map[bool]int8
+		Texts   map[textKey]int
+		Uppers  map[upperKey]int
+	}
+
+	mks := MapKeysStruct{
+		Strings: map[string]int{"b": 2, "a": 1, "c": 3, "": 0},
+		Ints:    map[int64]string{-10: "a", 2: "b", 10: "c"},
+		Uints:   map[uint8]bool{255: true, 0: false},
+		Bools:   map[bool]int8{true: 1, false: -1},
+		Texts:   map[textKey]int{{"x", "y"}: 1, {"a", "b"}: 2},
+		Uppers:  map[upperKey]int{"b": 1, "a": 2},
+	}
+	expected := `{"Strings":{"":"0","a":"1","b":"2","c":"3"},` +
+		`"Ints":{"-10":"a","10":"c","2":"b"},` +
+		`"Uints":{"0":false,"255":true},` +
+		`"Bools":{"false":-1,"true":1},` +
+		`"Texts":{"a-b":"2","x-y":"1"},` +
+		`"Uppers":{"A":"2","B":"1"}}`
+
+	// The output is sorted by key, hence deterministic.
+	for i := 0; i < 10; i++ {
+		bz, err := cdc.MarshalJSON(mks)
+		require.NoError(t, err)
+		assert.Equal(t, expected, string(bz))
+	}
+
+	var mks2 MapKeysStruct
+	err := cdc.UnmarshalJSON([]byte(expected), &mks2)
+	require.NoError(t, err)
+	assert.Equal(t, mks, mks2)
+
+	// Invalid keys result in an error.
+	err = cdc.UnmarshalJSON([]byte(`{"Uints":{"256":true}}`), &mks2)
+	assert.Error(t, err)
+	err = cdc.UnmarshalJSON([]byte(`{"Bools":{"yes":"1"}}`), &mks2)
+	assert.Error(t, err)
+	err = cdc.UnmarshalJSON([]byte(`{"Texts":{"xy":"1"}}`), &mks2)
+	assert.Error(t, err)
+
+	// Unsupported key types result in an error.
+	_, err = cdc.MarshalJSON(map[[2]int]int{{1, 2}: 3})
+	assert.Error(t, err)
+
+	// As do distinct keys with the same encoding.
+	_, err = cdc.MarshalJSON(map[upperKey]int{"a": 1, "A": 2})
+	assert.Error(t, err)
+}
+
 func TestMarshalJSONIndent(t *testing.T) {
 	var cdc = amino.NewCodec()
 	registerTransports(cdc)
@@ -584,3 +662,106 @@ func TestMarshalJSONIndent(t *testing.T) {
 	assert.Nil(t, err)
 	assert.Equal(t, expected, string(blob))
 }
+
+func TestUnmarshalJSONFieldOptions(t *testing.T) {
+	type Unsafe struct {
+		F float64 `amino:"unsafe"`
+	}
+	cdc := amino.NewCodec()
+
+	// The field's own options apply when decoding, like when encoding.
+	bz, err := cdc.MarshalJSON(Unsafe{F: 1.5})
+	require.NoError(t, err)
+	assert.Equal(t, `{"F":1.5}`, string(bz))
+	var u Unsafe
+	err = cdc.UnmarshalJSON(bz, &u)
+	require.NoError(t, err)
+	assert.Equal(t, Unsafe{F: 1.5}, u)
+}
+
+type VoteType byte
+
+const (
+	VoteTypePrevote   = VoteType(1)
+	VoteTypePrecommit = VoteType(2)
+)
+
+type Direction int64
+
+type Ballot struct {
+	Type  VoteType
+	Types []VoteType
+	Dir   Direction
+	Dirs  map[string]Direction
+	Ptr   *VoteType
+}
+
+func newEnumCodec(strict bool) *amino.Codec {
+	var cdc = amino.NewCodec()
+	cdc.RegisterEnum(VoteType(0), map[VoteType]string{
+		VoteTypePrevote:   "Prevote",
+		VoteTypePrecommit: "Precommit",
+	}, &amino.EnumOptions{Strict: strict})
+	cdc.RegisterEnum(Direction(0), map[Direction]string{-1: "Left", 1: "Right"}, nil)
+	return cdc
+}
+
+func TestJSONEnum(t *testing.T) {
+	var cdc = newEnumCodec(true)
+	var vt = VoteTypePrecommit
+	var b = Ballot{
+		Type:  VoteTypePrevote,
+		Types: []VoteType{VoteTypePrevote, VoteTypePrecommit},
+		Dir:   Direction(-1),
+		Dirs:  map[string]Direction{"a": 1, "b": 5},
+		Ptr:   &vt,
+	}
+
+	// Defined values are written as names, others as numbers.
+	bz, err := cdc.MarshalJSON(b)
+	require.NoError(t, err)
+	assert.Equal(t, `{"Type":"Prevote","Types":"AQI=","Dir":"Left","Dirs":{"a":"Right","b":"5"},"Ptr":"Precommit"}`,
+		string(bz))
+	var b2 Ballot
+	err = cdc.UnmarshalJSON(bz, &b2)
+	require.NoError(t, err)
+	assert.Equal(t, b, b2)
+
+	// Numbers are accepted too.
+	err = cdc.UnmarshalJSON([]byte(`{"Type":2,"Dir":"1"}`), &b2)
+	require.NoError(t, err)
+	assert.Equal(t, Ballot{Type: VoteTypePrecommit, Dir: Direction(1)}, b2)
+
+	// Undefined names never are, and undefined values aren't for strict enums.
+	for _, js := range []string{`{"Type":"Abstain"}`, `{"Dir":"Up"}`, `{"Type":3}`, `{"Types":"AQM="}`} {
+		err = cdc.UnmarshalJSON([]byte(js), &b2)
+		if assert.IsType(t, &amino.DecodeError{}, err, js) {
+			assert.Equal(t, amino.ErrKindUndefinedEnum, err.(*amino.DecodeError).Kind, js)
+		}
+	}
+	err = newEnumCodec(false).UnmarshalJSON([]byte(`{"Type":3}`), &b2)
+	assert.NoError(t, err)
+	assert.Equal(t, VoteType(3), b2.Type)
+
+	// Enums must be integers with valid, unique names.
+	assert.Panics(t, func() {
+		amino.NewCodec().RegisterEnum("", map[string]string{"a": "A"}, nil)
+	}, "not an integer")
+	assert.Panics(t, func() {
+		amino.NewCodec().RegisterEnum(VoteType(0), map[byte]string{1: "A"}, nil)
+	}, "wrong key type")
+	assert.Panics(t, func() {
+		amino.NewCodec().RegisterEnum(VoteType(0), map[VoteType]string{1: "1st"}, nil)
+	}, "invalid name")
+	assert.Panics(t, func() {
+		amino.NewCodec().RegisterEnum(VoteType(0), map[VoteType]string{1: "A", 2: "A"}, nil)
+	}, "duplicate name")
+
+	// Enums must be registered before they are used.
+	var used = amino.NewCodec()
+	used.MustMarshalJSON(b)
+	assert.PanicsWithValue(t, "cannot register amino_test.VoteType as an enum, it was already used or registered: "+
+		"RegisterEnum must be called before the type is used", func() {
+		used.RegisterEnum(VoteType(0), map[VoteType]string{1: "A"}, nil)
+	})
+}
@@ -0,0 +1,393 @@
+package amino
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+//----------------------------------------
+// Proto3 schema export
+
+// ExportProto3 writes a proto3 schema for all registered concrete types and
+// all struct types reachable from them, so that non-Go clients need not
+// maintain .proto files by hand.  Registered interfaces are listed in comments
+// along with their concrete implementations.
+//
+// Messages and fields are named after the Go types and fields, and field
+// numbers are those used by the Amino:binary encoding.  time.Time fields are
+// exported as google.protobuf.Timestamp.  Registered non-struct types (e.g.
+// `type IntDef int`) are exported as a message with a single field "Val",
+// matching MarshalBinaryBare.
+//
+// Enums (see RegisterEnum) are exported as proto3 enums if their values are
+// varints in the int32 range, like those of proto3 enums, and as integers
+// otherwise.
+//
+// Interface fields have no proto3 equivalent and are exported as bytes.  The
+// bytes hold the 4 prefix bytes of the registered concrete type (preceded by
+// 0x00 and the 3 disambiguation bytes if the prefix is ambiguous), followed by
+// the encoding of the concrete message.  The same holds for registered
+// concrete types encoded with MarshalBinaryBare.
+func (cdc *Codec) ExportProto3(w io.Writer, pkg string) error {
+	cdc.mtx.RLock()
+	var iinfos = append([]*TypeInfo(nil), cdc.interfaceInfos...)
+	var cinfos = append([]*TypeInfo(nil), cdc.concreteInfos...)
+	cdc.mtx.RUnlock()
+
+	var exp = &proto3Exporter{
+		cdc:   cdc,
+		names: make(map[string]reflect.Type),
+		lists: make(map[string]bool),
+		enums: make(map[string]string),
+	}
+	for _, iinfo := range iinfos {
+		exp.writeInterface(iinfo, cinfos)
+	}
+	for _, cinfo := range cinfos {
+		if err := exp.writeMessage(cinfo); err != nil {
+			return err
+		}
+	}
+	// Write messages for struct types reachable from the above.
+	for len(exp.queue) > 0 {
+		var info = exp.queue[0]
+		exp.queue = exp.queue[1:]
+		if err := exp.writeMessage(info); err != nil {
+			return err
+		}
+	}
+
+	var buf = new(bytes.Buffer)
+	fmt.Fprintf(buf, "syntax = \"proto3\";\npackage %v;\n", pkg)
+	if exp.timestamp {
+		fmt.Fprintf(buf, "\nimport \"google/protobuf/timestamp.proto\";\n")
+	}
+	buf.Write(exp.body.Bytes())
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+type proto3Exporter struct {
+	cdc       *Codec
+	body      bytes.Buffer
+	names     map[string]reflect.Type // Message name -> type, for conflicts.
+	lists     map[string]bool         // Names of messages that wrap lists.
+	enums     map[string]string       // Enum name -> scalar type, for map keys.
+	queue     []*TypeInfo             // Reachable messages yet to write.
+	timestamp bool                    // Whether Timestamp is used.
+}
+
+func (exp *proto3Exporter) writeInterface(iinfo *TypeInfo, cinfos []*TypeInfo) {
+	fmt.Fprintf(&exp.body, "\n// Interface %v, implemented by:\n", iinfo.Type)
+	for _, cinfo := range cinfos {
+		// Same as collectImplementersNolock.
+		if !cinfo.PtrToType.Implements(iinfo.Type) {
+			continue
+		}
+		fmt.Fprintf(&exp.body, "//   %v (%q, prefix 0x%X, disamb 0x%X)\n",
+			cinfo.Type.Name(), cinfo.Name, cinfo.Prefix.Bytes(), cinfo.Disamb.Bytes())
+	}
+}
+
+// Writes the message for info, if not already written.
+func (exp *proto3Exporter) writeMessage(info *TypeInfo) error {
+	var name = info.Type.Name()
+	if name == "" {
+		return fmt.Errorf("cannot export unnamed type %v to proto3", info.Type)
+	}
+	if exp.lists[name] {
+		return fmt.Errorf("conflicting proto3 message name %v for %v and a nested list", name, info.Type)
+	}
+	if _, ok := exp.enums[name]; ok {
+		return fmt.Errorf("conflicting proto3 message name %v for %v and an enum", name, info.Type)
+	}
+	if rt, ok := exp.names[name]; ok {
+		if rt != info.Type {
+			return fmt.Errorf("conflicting proto3 message name %v for %v and %v", name, rt, info.Type)
+		}
+		return nil
+	}
+	exp.names[name] = info.Type
+
+	// Amino(Un)Marshalers are encoded as their repr type.
+	var rinfo = info
+	if info.IsAminoMarshaler {
+		var err error
+		rinfo, err = exp.cdc.getTypeInfoWlock(info.AminoMarshalReprType)
+		if err != nil {
+			return err
+		}
+	}
+
+	var buf = new(bytes.Buffer)
+	fmt.Fprintf(buf, "\n")
+	if info.Registered {
+		fmt.Fprintf(buf, "// Registered as %q, prefix 0x%X, disamb 0x%X.\n",
+			info.Name, info.Prefix.Bytes(), info.Disamb.Bytes())
+	}
+	fmt.Fprintf(buf, "message %v {\n", name)
+	if rinfo.Type.Kind() == reflect.Struct && rinfo.Type != timeType {
+		for _, field := range rinfo.Fields {
+			if err := exp.writeField(buf, field.Name, field.BinFieldNum, field.Type, field.FieldOptions); err != nil {
+				return err
+			}
+		}
+		if len(rinfo.Reserved) > 0 {
+			var nums = make([]string, len(rinfo.Reserved))
+			for i, num := range rinfo.Reserved {
+				nums[i] = fmt.Sprintf("%v", num)
+			}
+			fmt.Fprintf(buf, "    reserved %v;\n", strings.Join(nums, ", "))
+		}
+	} else {
+		// Non-struct values are encoded as field 1, see MarshalBinaryBare.
+		if err := exp.writeField(buf, "Val", 1, rinfo.Type, FieldOptions{}); err != nil {
+			return err
+		}
+	}
+	fmt.Fprintf(buf, "}\n")
+	exp.body.Write(buf.Bytes())
+	return nil
+}
+
+// Returns the name of the message for lists of type rt that are elements of
+// lists, e.g. Int64List for [][]int64, and writes it if not already written.
+// The list is field 1 of the message, see encodeReflectBinaryWrapped.
+func (exp *proto3Exporter) listMessage(rt reflect.Type, fopts FieldOptions) (string, error) {
+	var typ, _, _, err = exp.fieldType(rt, fopts)
+	if err != nil {
+		return "", err
+	}
+	typ = typ[strings.LastIndex(typ, ".")+1:] // e.g. google.protobuf.Timestamp
+	var name = strings.ToUpper(typ[:1]) + typ[1:] + "List"
+	if exp.lists[name] {
+		return name, nil
+	}
+	if rt, ok := exp.names[name]; ok {
+		return "", fmt.Errorf("conflicting proto3 message name %v for %v and a nested list", name, rt)
+	}
+	var buf = new(bytes.Buffer)
+	if err = exp.writeField(buf, "Val", 1, rt, fopts); err != nil {
+		return "", err
+	}
+	exp.lists[name] = true
+	fmt.Fprintf(&exp.body, "\n// A list that is an element of a list.\nmessage %v {\n%v}\n", name, buf)
+	return name, nil
+}
+
+// Returns the name of the enum for type info, and writes it if not already
+// written, if the enum can be exported as such.  Proto3 enums are varints of
+// int32 values, so enums of other encodings or values are exported as
+// integers, as are enums without a Go name and registered concrete enums.
+func (exp *proto3Exporter) enumType(info *TypeInfo, fopts FieldOptions) (name string, ok bool, err error) {
+	var scalar string
+	switch info.Type.Kind() {
+	case reflect.Int32:
+		scalar = "int32"
+	case reflect.Int64, reflect.Int:
+		scalar = "int64"
+	case reflect.Uint32, reflect.Uint16, reflect.Uint8:
+		scalar = "uint32"
+	case reflect.Uint64, reflect.Uint:
+		scalar = "uint64"
+	}
+	if scalar == "" || fopts.BinFixed32 || fopts.BinFixed64 || info.Type.Name() == "" || info.Registered {
+		return "", false, nil
+	}
+	type value struct {
+		num  int64
+		name string
+	}
+	var values = make([]value, 0, len(info.EnumNames)+1)
+	var hasZero bool
+	for bits, vname := range info.EnumNames {
+		var num = int64(bits)
+		if strings.HasPrefix(scalar, "uint") && bits > math.MaxInt32 ||
+			num < math.MinInt32 || num > math.MaxInt32 {
+			return "", false, nil
+		}
+		hasZero = hasZero || num == 0
+		values = append(values, value{num, vname})
+	}
+
+	name = info.Type.Name()
+	if _, ok := exp.enums[name]; ok {
+		return name, true, nil
+	}
+	if rt, ok := exp.names[name]; ok || exp.lists[name] {
+		return "", false, fmt.Errorf("conflicting proto3 enum name %v for %v and %v", name, info.Type, rt)
+	}
+	exp.enums[name] = scalar
+
+	sort.Slice(values, func(i, j int) bool { return values[i].num < values[j].num })
+	var buf = new(bytes.Buffer)
+	fmt.Fprintf(buf, "\nenum %v {\n", name)
+	if !hasZero {
+		fmt.Fprintf(buf, "    %vUnspecified = 0; // Undefined, but proto3 requires a zero value.\n", name)
+	}
+	// Proto3 requires the zero value first.
+	for _, v := range values {
+		if v.num == 0 {
+			fmt.Fprintf(buf, "    %v = 0;\n", v.name)
+		}
+	}
+	for _, v := range values {
+		if v.num != 0 {
+			fmt.Fprintf(buf, "    %v = %v;\n", v.name, v.num)
+		}
+	}
+	fmt.Fprintf(buf, "}\n")
+	exp.body.Write(buf.Bytes())
+	return name, true, nil
+}
+
+func (exp *proto3Exporter) writeField(w io.Writer, name string, num uint32, rt reflect.Type, fopts FieldOptions) error {
+	var typ, repeated, comment, err = exp.fieldType(rt, fopts)
+	if err != nil {
+		return err
+	}
+	if repeated {
+		typ = "repeated " + typ
+	}
+	if comment != "" {
+		comment = " // " + comment
+	}
+	_, err = fmt.Fprintf(w, "    %v %v = %v;%v\n", typ, name, num, comment)
+	return err
+}
+
+// Returns the proto3 type for values of type rt.
+func (exp *proto3Exporter) fieldType(rt reflect.Type, fopts FieldOptions) (typ string, repeated bool, comment string, err error) {
+	var info *TypeInfo
+	info, err = exp.cdc.getTypeInfoWlock(rt)
+	if err != nil {
+		return
+	}
+	if info.IsAminoMarshaler {
+		info, err = exp.cdc.getTypeInfoWlock(info.AminoMarshalReprType)
+		if err != nil {
+			return
+		}
+	}
+	if info.IsEnum {
+		var ok bool
+		if typ, ok, err = exp.enumType(info, fopts); ok || err != nil {
+			return typ, false, "", err
+		}
+	}
+
+	switch info.Type.Kind() {
+
+	case reflect.Interface:
+		return "bytes", false, fmt.Sprintf("%v, prefix bytes + concrete message", info.Type), nil
+
+	case reflect.Array, reflect.Slice:
+		var ert = info.Type.Elem()
+		if ert.Kind() == reflect.Uint8 {
+			return "bytes", false, "", nil
+		}
+		for ert.Kind() == reflect.Ptr {
+			ert = ert.Elem()
+		}
+		switch ert.Kind() {
+		case reflect.Array, reflect.Slice:
+			if ert.Elem().Kind() != reflect.Uint8 {
+				typ, err = exp.listMessage(ert, fopts)
+				return typ, true, "", err
+			}
+		case reflect.Map:
+			return "bytes", true, "list of maps, each item is an encoded map", nil
+		case reflect.Interface:
+			return "bytes", true, fmt.Sprintf("%v, prefix bytes + concrete message", ert), nil
+		}
+		typ, _, comment, err = exp.fieldType(ert, fopts)
+		return typ, true, comment, err
+
+	case reflect.Map:
+		var ktyp, vtyp string
+		var vrepeated bool
+		ktyp, _, _, err = exp.fieldType(info.Type.Key(), FieldOptions{})
+		if err != nil {
+			return
+		}
+		if scalar, ok := exp.enums[ktyp]; ok {
+			ktyp = scalar // Map keys can't be enums.
+		}
+		vtyp, vrepeated, comment, err = exp.fieldType(info.Type.Elem(), fopts)
+		if err != nil {
+			return
+		}
+		if vrepeated || strings.HasPrefix(vtyp, "map<") {
+			err = fmt.Errorf("cannot export map of lists or maps %v to proto3", info.Type)
+			return
+		}
+		return fmt.Sprintf("map<%v, %v>", ktyp, vtyp), false, comment, nil
+
+	case reflect.Struct:
+		if info.Type == timeType {
+			exp.timestamp = true
+			return "google.protobuf.Timestamp", false, "", nil
+		}
+		if _, ok := exp.names[info.Type.Name()]; !ok {
+			exp.queue = append(exp.queue, info)
+		}
+		return info.Type.Name(), false, "", nil
+
+	case reflect.Int64:
+		if fopts.BinFixed64 {
+			return "sfixed64", false, "", nil
+		}
+		return "int64", false, "", nil
+
+	case reflect.Int32:
+		if fopts.BinFixed32 {
+			return "sfixed32", false, "", nil
+		}
+		return "int32", false, "", nil
+
+	case reflect.Int:
+		return "int64", false, "", nil
+
+	case reflect.Int16, reflect.Int8:
+		return "sint32", false, "", nil
+
+	case reflect.Uint64:
+		if fopts.BinFixed64 {
+			return "fixed64", false, "", nil
+		}
+		return "uint64", false, "", nil
+
+	case reflect.Uint32:
+		if fopts.BinFixed32 {
+			return "fixed32", false, "", nil
+		}
+		return "uint32", false, "", nil
+
+	case reflect.Uint:
+		return "uint64", false, "", nil
+
+	case reflect.Uint16, reflect.Uint8:
+		return "uint32", false, "", nil
+
+	case reflect.Bool:
+		return "bool", false, "", nil
+
+	case reflect.Float64:
+		return "double", false, "", nil
+
+	case reflect.Float32:
+		return "float", false, "", nil
+
+	case reflect.String:
+		return "string", false, "", nil
+
+	default:
+		err = fmt.Errorf("cannot export type %v to proto3", info.Type)
+		return
+	}
+}
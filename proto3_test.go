@@ -0,0 +1,132 @@
+package amino_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	amino "github.com/tendermint/go-amino"
+)
+
+type proto3Animal interface{}
+
+type Proto3Cat struct {
+	Name    string
+	Lives   int32 `binary:"fixed32"`
+	Born    time.Time
+	_       struct{}       `amino:"reserved=4"`
+	Friends []proto3Animal `amino:"field=5"`
+	Owner   *Proto3Owner
+}
+
+type Proto3Owner struct {
+	ID     uint64 `binary:"fixed64"`
+	Scores []int8
+	Pet    proto3Animal
+	Tags   map[string]uint32
+	Grid   [][]int64
+}
+
+type Proto3Weight uint16
+
+func TestExportProto3(t *testing.T) {
+	cdc := amino.NewCodec()
+	cdc.RegisterInterface((*proto3Animal)(nil), nil)
+	cdc.RegisterConcrete(&Proto3Cat{}, "animal/Cat", nil)
+	cdc.RegisterConcrete(Proto3Weight(0), "animal/Weight", nil)
+
+	buf := new(bytes.Buffer)
+	err := cdc.ExportProto3(buf, "animals")
+	require.NoError(t, err)
+	assert.Equal(t, `syntax = "proto3";
+package animals;
+
+import "google/protobuf/timestamp.proto";
+
+// Interface amino_test.proto3Animal, implemented by:
+//   Proto3Cat ("animal/Cat", prefix 0xFDF30F81, disamb 0x082171)
+//   Proto3Weight ("animal/Weight", prefix 0x8197AB9A, disamb 0xAB69C6)
+
+// Registered as "animal/Cat", prefix 0xFDF30F81, disamb 0x082171.
+message Proto3Cat {
+    string Name = 1;
+    sfixed32 Lives = 2;
+    google.protobuf.Timestamp Born = 3;
+    repeated bytes Friends = 5; // amino_test.proto3Animal, prefix bytes + concrete message
+    Proto3Owner Owner = 6;
+    reserved 4;
+}
+
+// Registered as "animal/Weight", prefix 0x8197AB9A, disamb 0xAB69C6.
+message Proto3Weight {
+    uint32 Val = 1;
+}
+
+// A list that is an element of a list.
+message Int64List {
+    repeated int64 Val = 1;
+}
+
+message Proto3Owner {
+    fixed64 ID = 1;
+    repeated sint32 Scores = 2;
+    bytes Pet = 3; // amino_test.proto3Animal, prefix bytes + concrete message
+    map<string, uint32> Tags = 4;
+    repeated Int64List Grid = 5;
+}
+`, buf.String())
+}
+
+func TestExportProto3Unsupported(t *testing.T) {
+	cdc := amino.NewCodec()
+	cdc.RegisterConcrete(struct{ A int }{}, "unnamed", nil)
+	err := cdc.ExportProto3(new(bytes.Buffer), "test")
+	assert.Error(t, err)
+}
+
+type Proto3Vote struct {
+	Type  VoteType
+	Dir   Direction
+	Dirs  map[Direction]VoteType
+	Fixed Direction `binary:"fixed64"`
+	Small int8Enum
+}
+
+type int8Enum int8
+
+func TestExportProto3Enum(t *testing.T) {
+	cdc := newEnumCodec(false)
+	cdc.RegisterEnum(int8Enum(0), map[int8Enum]string{1: "One"}, nil)
+	cdc.RegisterConcrete(Proto3Vote{}, "vote/Vote", nil)
+
+	buf := new(bytes.Buffer)
+	err := cdc.ExportProto3(buf, "votes")
+	require.NoError(t, err)
+	assert.Equal(t, `syntax = "proto3";
+package votes;
+
+enum VoteType {
+    VoteTypeUnspecified = 0; // Undefined, but proto3 requires a zero value.
+    Prevote = 1;
+    Precommit = 2;
+}
+
+enum Direction {
+    DirectionUnspecified = 0; // Undefined, but proto3 requires a zero value.
+    Left = -1;
+    Right = 1;
+}
+
+// Registered as "vote/Vote", prefix 0x3E45F832, disamb 0xE78087.
+message Proto3Vote {
+    VoteType Type = 1;
+    Direction Dir = 2;
+    map<int64, VoteType> Dirs = 3;
+    sfixed64 Fixed = 4;
+    sint32 Small = 5;
+}
+`, buf.String())
+}
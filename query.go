@@ -0,0 +1,542 @@
+package amino
+
+import (
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+//----------------------------------------
+// Queries
+
+// QueryBinary decodes the value at path in bz into out, which must be a
+// pointer, without decoding the rest of bz.  bz is as returned from
+// MarshalBinaryBare for a value of type rootType, which must be a struct or
+// an interface.
+//
+// The path is made of struct field names and list indices, e.g.
+// "Header.Height" or "Txs[2].Fee".  Interface values are followed into their
+// concrete types.  If a field along the path is not present, out is set to
+// the default value.  If the path ends at an interface value, out may also
+// point to its concrete type, in which case the concrete type must match.
+func (cdc *Codec) QueryBinary(bz []byte, rootType reflect.Type, path string, out interface{}) error {
+	rv := reflect.ValueOf(out)
+	if rv.Kind() != reflect.Ptr {
+		return ErrNoPointer
+	}
+	steps, err := parseQueryPath(path)
+	if err != nil {
+		return err
+	}
+	info, err := cdc.getTypeInfoWlock(rootType)
+	if err != nil {
+		return err
+	}
+	var ds = newDecodeState(cdc.getDecodeLimits())
+	ds.root, ds.input = info.Type, bz
+
+	// Find the value, starting with the root.
+	var c = binaryCursor{bz: bz, info: info, fopts: FieldOptions{BinFieldNum: 1}, bare: true}
+	switch {
+	case info.isTopLevelRegistered():
+		var n int
+		if n, err = ds.consumePrefix(bz, info); err != nil {
+			return err
+		}
+		c.bz = bz[n:]
+	case info.Type.Kind() == reflect.Interface:
+	case info.Type.Kind() == reflect.Struct && !info.IsAminoMarshaler:
+	default:
+		return errors.Errorf("cannot query %v, expected a struct or interface", info.Type)
+	}
+	for i, step := range steps {
+		if step.index < 0 {
+			c, err = cdc.queryBinaryField(c, step.name)
+		} else {
+			c, err = cdc.queryBinaryIndex(c, step.index)
+		}
+		if err != nil {
+			return errors.Wrapf(err, "querying %v", formatQueryPath(steps[:i+1]))
+		}
+	}
+
+	// Decode it.
+	return queryDecode(c.info, rv.Elem(), func(rv reflect.Value) error {
+		if c.bz == nil {
+			rv.Set(defaultValue(rv.Type()))
+			return nil
+		}
+		_, err := cdc.decodeReflectBinary(c.bz, c.info, rv, c.fopts, c.bare, ds)
+		return err
+	})
+}
+
+// A value in the binary encoding being queried.
+type binaryCursor struct {
+	bz       []byte // The encoding of the value, or nil if not present.
+	info     *TypeInfo
+	fopts    FieldOptions
+	bare     bool // Whether bz is bare, see decodeReflectBinary.
+	unpacked bool // Whether bz is an unpacked list, starting with a field key.
+}
+
+// Returns the contents of the composite value at c.
+func (c binaryCursor) contents() ([]byte, error) {
+	if c.bare {
+		return c.bz, nil
+	}
+	bz, _, err := DecodeByteSliceNoCopy(c.bz)
+	return bz, err
+}
+
+func (cdc *Codec) queryBinaryField(c binaryCursor, name string) (binaryCursor, error) {
+	var err error
+	if c.info.Type.Kind() == reflect.Interface {
+		if c, err = cdc.queryBinaryConcrete(c); err != nil {
+			return c, err
+		}
+	}
+	var info = c.info
+	if info.Type.Kind() != reflect.Struct || info.Type == timeType || info.IsAminoMarshaler {
+		return c, errors.Errorf("cannot query a field of %v", info.Type)
+	}
+	plan, err := cdc.getBinaryPlan(info)
+	if err != nil {
+		return c, err
+	}
+	var i = 0
+	for ; i < len(info.Fields) && info.Fields[i].Name != name; i++ {
+	}
+	if i == len(info.Fields) {
+		return c, errors.Errorf("%v has no amino field %v", info.Type, name)
+	}
+	var field = &info.Fields[i]
+	var fc = binaryCursor{info: plan.fields[i].info, fopts: field.FieldOptions}
+	if c.bz == nil {
+		return fc, nil
+	}
+
+	// Skip the fields before it.
+	bz, err := c.contents()
+	if err != nil {
+		return c, err
+	}
+	var fnum, typ, n = uint32(0), Typ3(0x00), int(0)
+	for {
+		if len(bz) == 0 {
+			return fc, nil
+		}
+		fnum, typ, n, err = decodeFieldNumberAndTyp3(bz)
+		if err != nil {
+			return c, err
+		}
+		if field.BinFieldNum <= fnum {
+			break
+		}
+		// Known fields may have a custom amino encoding.
+		for j := range info.Fields[:i] {
+			if info.Fields[j].BinFieldNum != fnum {
+				continue
+			}
+			var finfo = plan.fields[j].info
+			if info.Fields[j].UnpackedList && finfo.Type.Kind() == reflect.Map {
+				// Map entries are messages, see encodeReflectBinaryMap.
+				typ = Typ3ByteLength
+				continue
+			}
+			if info.Fields[j].UnpackedList {
+				var lplan *binaryPlan
+				if lplan, err = cdc.getBinaryPlan(finfo); err != nil {
+					return c, err
+				}
+				finfo = lplan.elemInfo
+			}
+			typ = cdc.valueTyp3(finfo, info.Fields[j].FieldOptions)
+		}
+		var _n int
+		if _n, err = consumeAny(typ, bz[n:]); err != nil {
+			return c, err
+		}
+		bz = bz[n+_n:]
+	}
+	switch {
+	case fnum != field.BinFieldNum:
+		// Not present.
+	case field.UnpackedList:
+		fc.bz, fc.bare, fc.unpacked = bz, true, true
+	default:
+		if typWanted := typeToTyp3(fc.info.Type, field.FieldOptions); typ != typWanted {
+			return c, kindErrorf(ErrKindWrongTyp3, "expected field type %v for # %v of %v, got %v",
+				typWanted, fnum, info.Type, typ)
+		}
+		fc.bz = bz[n:]
+	}
+	return fc, nil
+}
+
+func (cdc *Codec) queryBinaryIndex(c binaryCursor, index int) (binaryCursor, error) {
+	var info = c.info
+	if (info.Type.Kind() != reflect.Array && info.Type.Kind() != reflect.Slice) ||
+		info.Type.Elem().Kind() == reflect.Uint8 || info.IsAminoMarshaler {
+		return c, errors.Errorf("cannot index %v", info.Type)
+	}
+	plan, err := cdc.getBinaryPlan(info)
+	if err != nil {
+		return c, err
+	}
+	var ec = binaryCursor{info: plan.elemInfo, fopts: c.fopts}
+	var outOfRange = errors.Errorf("index %v out of range", index)
+	if c.bz == nil {
+		return c, outOfRange
+	}
+
+	if c.unpacked {
+		// Each element is a field.
+		var ert = info.Type.Elem()
+		var isErtStructPointer = ert.Kind() == reflect.Ptr && ec.info.Type.Kind() == reflect.Struct
+		var bz = c.bz
+		for i := 0; ; i++ {
+			if len(bz) == 0 {
+				return c, outOfRange
+			}
+			fnum, typ, n, err := decodeFieldNumberAndTyp3(bz)
+			if err != nil {
+				return c, err
+			}
+			if fnum != c.fopts.BinFieldNum {
+				return c, outOfRange
+			}
+			if typ != Typ3ByteLength {
+				return c, kindErrorf(ErrKindWrongTyp3, "expected repeated field type %v, got %v", Typ3ByteLength, typ)
+			}
+			bz = bz[n:]
+			if i == index {
+				// See decodeReflectBinaryList for empty elements.
+				if len(bz) > 0 && bz[0] != 0x00 || isErtStructPointer && c.fopts.EmptyElements {
+					ec.bz = bz
+				}
+				ec.fopts.BinFieldNum = 1
+				if ec.bz != nil && (plan.wrapElems || isUnpackedList(ec.info.Type, ec.fopts)) {
+					return cdc.queryBinaryNestedList(ec, plan.wrapElems)
+				}
+				return ec, nil
+			}
+			if n, err = consumeAny(cdc.valueTyp3(ec.info, c.fopts), bz); err != nil {
+				return c, err
+			}
+			bz = bz[n:]
+		}
+	}
+
+	// The elements are packed.
+	bz, err := c.contents()
+	if err != nil {
+		return c, err
+	}
+	var typ = cdc.valueTyp3(ec.info, c.fopts)
+	for i := 0; i < index; i++ {
+		n, err := consumeAny(typ, bz)
+		if err != nil {
+			return c, err
+		}
+		bz = bz[n:]
+	}
+	if len(bz) == 0 {
+		return c, outOfRange
+	}
+	ec.bz = bz
+	return ec, nil
+}
+
+// Returns the typ3 of the encoding of values of type info.  For types with a
+// custom amino encoding, this is that of the repr type, which can differ from
+// the typ3 of their field keys.
+func (cdc *Codec) valueTyp3(info *TypeInfo, fopts FieldOptions) Typ3 {
+	for info.IsAminoMarshaler {
+		plan, err := cdc.getBinaryPlan(info)
+		if err != nil {
+			break
+		}
+		info = plan.reprInfo
+	}
+	return typeToTyp3(info.Type, fopts)
+}
+
+// Returns the cursor for the list at c, an element of a list of lists, which
+// is written as field 1 of a message, see encodeReflectBinaryWrapped.
+func (cdc *Codec) queryBinaryNestedList(c binaryCursor, wrapped bool) (binaryCursor, error) {
+	bz, err := c.contents()
+	if err != nil {
+		return c, err
+	}
+	if !wrapped {
+		// The elements are already repeated field 1.
+		c.bz, c.bare, c.unpacked = bz, true, true
+		return c, nil
+	}
+	fnum, typ, n, err := decodeFieldNumberAndTyp3(bz)
+	if err != nil {
+		return c, err
+	}
+	if fnum != 1 || typ != Typ3ByteLength {
+		return c, kindErrorf(ErrKindFieldOrder, "expected field number 1 of nested list, got %v", fnum)
+	}
+	c.bz = bz[n:]
+	return c, nil
+}
+
+// Returns the value of the concrete type of the interface value at c.
+func (cdc *Codec) queryBinaryConcrete(c binaryCursor) (binaryCursor, error) {
+	if c.bz == nil {
+		return c, errors.Errorf("%v is nil", c.info.Type)
+	}
+	bz, err := c.contents()
+	if err != nil {
+		return c, err
+	}
+	if len(bz) == 0 {
+		return c, errors.Errorf("%v is nil", c.info.Type)
+	}
+	disamb, hasDisamb, prefix, hasPrefix, n, err := DecodeDisambPrefixBytes(bz)
+	if err != nil {
+		return c, err
+	}
+	var cinfo *TypeInfo
+	switch {
+	case hasDisamb:
+		cinfo, err = cdc.getTypeInfoFromDisfixRlock(toDisfix(disamb, prefix))
+	case hasPrefix:
+		cinfo, err = cdc.getTypeInfoFromPrefixRlock(c.info, prefix)
+	default:
+		err = kindErrorf(ErrKindUnknownPrefix, "expected disambiguation or prefix bytes")
+	}
+	if err != nil {
+		return c, err
+	}
+	if err = checkConcreteAllowed(c.info, cinfo, c.fopts); err != nil {
+		return c, err
+	}
+	return binaryCursor{bz: bz[n:], info: cinfo, fopts: FieldOptions{BinFieldNum: 1}, bare: true}, nil
+}
+
+// QueryJSON is like QueryBinary, but for bz as returned from MarshalJSON.
+// The path is still made of struct field names, not of JSON names.
+func (cdc *Codec) QueryJSON(bz []byte, rootType reflect.Type, path string, out interface{}) error {
+	rv := reflect.ValueOf(out)
+	if rv.Kind() != reflect.Ptr {
+		return ErrNoPointer
+	}
+	steps, err := parseQueryPath(path)
+	if err != nil {
+		return err
+	}
+	info, err := cdc.getTypeInfoWlock(rootType)
+	if err != nil {
+		return err
+	}
+	var ds = newDecodeState(cdc.getDecodeLimits())
+	ds.root = info.Type
+
+	// Find the value, starting with the root.
+	var c = jsonCursor{bz: bz, info: info}
+	switch {
+	case info.isTopLevelRegistered():
+		name, data, err := decodeInterfaceJSON(bz)
+		if err != nil {
+			return err
+		}
+		if name != info.Name {
+			return kindErrorf(ErrKindUnknownPrefix, "wanted to decode %v but found %v", info.Name, name)
+		}
+		c.bz = data
+	case info.Type.Kind() == reflect.Interface:
+	case info.Type.Kind() == reflect.Struct && !info.IsAminoMarshaler:
+	default:
+		return errors.Errorf("cannot query %v, expected a struct or interface", info.Type)
+	}
+	for i, step := range steps {
+		if step.index < 0 {
+			c, err = cdc.queryJSONField(c, step.name)
+		} else {
+			c, err = cdc.queryJSONIndex(c, step.index)
+		}
+		if err != nil {
+			return errors.Wrapf(err, "querying %v", formatQueryPath(steps[:i+1]))
+		}
+	}
+
+	// Decode it.
+	return queryDecode(c.info, rv.Elem(), func(rv reflect.Value) error {
+		if len(c.bz) == 0 {
+			rv.Set(reflect.Zero(rv.Type()))
+			return nil
+		}
+		return cdc.decodeReflectJSON(c.bz, c.info, rv, c.fopts, ds)
+	})
+}
+
+// A value in the JSON encoding being queried.
+type jsonCursor struct {
+	bz    []byte // The encoding of the value, or nil if not present.
+	info  *TypeInfo
+	fopts FieldOptions
+}
+
+func (cdc *Codec) queryJSONField(c jsonCursor, name string) (jsonCursor, error) {
+	var err error
+	if c.info.Type.Kind() == reflect.Interface {
+		if len(c.bz) == 0 || nullBytes(c.bz) {
+			return c, errors.Errorf("%v is nil", c.info.Type)
+		}
+		var cname string
+		if cname, c.bz, err = decodeInterfaceJSON(c.bz); err != nil {
+			return c, err
+		}
+		var cinfo *TypeInfo
+		if cinfo, err = cdc.getTypeInfoFromNameRlock(cname); err != nil {
+			return c, err
+		}
+		if err = checkConcreteAllowed(c.info, cinfo, c.fopts); err != nil {
+			return c, err
+		}
+		c.info = cinfo
+	}
+	var info = c.info
+	if info.Type.Kind() != reflect.Struct || info.Type == timeType || info.IsAminoMarshaler {
+		return c, errors.Errorf("cannot query a field of %v", info.Type)
+	}
+	var i = 0
+	for ; i < len(info.Fields) && info.Fields[i].Name != name; i++ {
+	}
+	if i == len(info.Fields) {
+		return c, errors.Errorf("%v has no amino field %v", info.Type, name)
+	}
+	var field = &info.Fields[i]
+	finfo, err := cdc.getTypeInfoWlock(field.Type)
+	if err != nil {
+		return c, err
+	}
+	var fc = jsonCursor{info: finfo, fopts: field.FieldOptions}
+	if len(c.bz) == 0 || nullBytes(c.bz) {
+		return fc, nil
+	}
+	var rawMap map[string]json.RawMessage
+	if err = json.Unmarshal(c.bz, &rawMap); err != nil {
+		return c, err
+	}
+	fc.bz = rawMap[field.JSONName]
+	return fc, nil
+}
+
+func (cdc *Codec) queryJSONIndex(c jsonCursor, index int) (jsonCursor, error) {
+	var info = c.info
+	if (info.Type.Kind() != reflect.Array && info.Type.Kind() != reflect.Slice) ||
+		info.Type.Elem().Kind() == reflect.Uint8 || info.IsAminoMarshaler {
+		return c, errors.Errorf("cannot index %v", info.Type)
+	}
+	einfo, err := cdc.getTypeInfoWlock(info.Type.Elem())
+	if err != nil {
+		return c, err
+	}
+	var rawSlice []json.RawMessage
+	if len(c.bz) > 0 {
+		if err = json.Unmarshal(c.bz, &rawSlice); err != nil {
+			return c, err
+		}
+	}
+	if index >= len(rawSlice) {
+		return c, errors.Errorf("index %v out of range", index)
+	}
+	return jsonCursor{bz: rawSlice[index], info: einfo, fopts: c.fopts}, nil
+}
+
+// Decodes the queried value of type info into rv, with decode, which is
+// passed rv, or an interface value if rv is of its concrete type.
+func queryDecode(info *TypeInfo, rv reflect.Value, decode func(rv reflect.Value) error) error {
+	if derefType(rv.Type()) == info.Type {
+		return decode(rv)
+	}
+	if info.Type.Kind() != reflect.Interface {
+		return errors.Errorf("cannot decode %v into %v", info.Type, rv.Type())
+	}
+	var irv = reflect.New(info.Type).Elem()
+	if err := decode(irv); err != nil {
+		return err
+	}
+	if irv.IsNil() {
+		return errors.Errorf("cannot decode nil %v into %v", info.Type, rv.Type())
+	}
+	var crv = irv.Elem()
+	for !crv.Type().AssignableTo(rv.Type()) && crv.Kind() == reflect.Ptr {
+		crv = crv.Elem()
+	}
+	if !crv.Type().AssignableTo(rv.Type()) {
+		return errors.Errorf("concrete type %v of %v is not %v", irv.Elem().Type(), info.Type, rv.Type())
+	}
+	rv.Set(crv)
+	return nil
+}
+
+//----------------------------------------
+// Query paths
+
+// A struct field name, or a list index if index >= 0.
+type queryStep struct {
+	name  string
+	index int
+}
+
+// Parses paths like "Header.Height" and "Txs[2].Fee".
+func parseQueryPath(path string) (steps []queryStep, err error) {
+	var rest = path
+	for len(rest) > 0 {
+		if rest[0] == '[' {
+			var end = strings.IndexByte(rest, ']')
+			if end < 0 {
+				return nil, errors.Errorf("invalid path %q: missing ]", path)
+			}
+			var index int
+			index, err = strconv.Atoi(rest[1:end])
+			if err != nil || index < 0 {
+				return nil, errors.Errorf("invalid path %q: invalid index %q", path, rest[1:end])
+			}
+			steps = append(steps, queryStep{index: index})
+			rest = rest[end+1:]
+			continue
+		}
+		if len(steps) > 0 {
+			if rest[0] != '.' {
+				return nil, errors.Errorf("invalid path %q: expected . or [ before %q", path, rest)
+			}
+			rest = rest[1:]
+		}
+		var end = strings.IndexAny(rest, ".[")
+		if end < 0 {
+			end = len(rest)
+		}
+		if end == 0 {
+			return nil, errors.Errorf("invalid path %q: missing field name", path)
+		}
+		steps = append(steps, queryStep{name: rest[:end], index: -1})
+		rest = rest[end:]
+	}
+	return steps, nil
+}
+
+func formatQueryPath(steps []queryStep) string {
+	var sb strings.Builder
+	for _, step := range steps {
+		if step.index >= 0 {
+			sb.WriteString("[" + strconv.Itoa(step.index) + "]")
+			continue
+		}
+		if sb.Len() > 0 {
+			sb.WriteByte('.')
+		}
+		sb.WriteString(step.name)
+	}
+	return sb.String()
+}
@@ -0,0 +1,169 @@
+package amino_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	amino "github.com/tendermint/go-amino"
+)
+
+type queryMsg interface{}
+
+type queryMsgA struct{ A string }
+
+type queryMsgB struct {
+	B []byte
+	N int64
+}
+
+type queryHeader struct {
+	ChainID string
+	Height  int64
+	Time    time.Time
+}
+
+type queryTx struct {
+	Fee  uint32
+	Memo string
+}
+
+type queryBlock struct {
+	Header  queryHeader
+	Txs     []queryTx
+	TxPtrs  []*queryTx
+	Heights []int64
+	Msg     queryMsg
+	Msgs    []queryMsg
+	Temp    Celsius
+	Temps   []Celsius
+	Data    []byte
+	Fees    map[string]int64
+	Tail    int64
+}
+
+func TestQuery(t *testing.T) {
+	cdc := amino.NewCodec()
+	cdc.RegisterInterface((*queryMsg)(nil), nil)
+	cdc.RegisterConcrete(queryMsgA{}, "amino_test/queryMsgA", nil)
+	cdc.RegisterConcrete(&queryMsgB{}, "amino_test/queryMsgB", nil)
+
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	block := queryBlock{
+		Header:  queryHeader{"chain", 42, now},
+		Txs:     []queryTx{{1, "a"}, {}, {3, "c"}},
+		TxPtrs:  []*queryTx{nil, {2, "b"}},
+		Heights: []int64{1, 0, -3},
+		Msg:     queryMsgA{"a"},
+		Msgs:    []queryMsg{&queryMsgB{[]byte("b"), 7}, nil, queryMsgA{}},
+		Temp:    Celsius{-40},
+		Temps:   []Celsius{{1}, {-2}, {3}},
+		Data:    []byte("data"),
+		Fees:    map[string]int64{"a": 1, "b": 2},
+		Tail:    9,
+	}
+	var rootType = reflect.TypeOf(queryBlock{})
+
+	cases := []struct {
+		path string
+		out  interface{}
+		want interface{}
+	}{
+		{"", new(queryBlock), block},
+		{"Header", new(queryHeader), block.Header},
+		{"Header.ChainID", new(string), "chain"},
+		{"Header.Height", new(int64), int64(42)},
+		{"Header.Time", new(time.Time), now},
+		{"Txs", new([]queryTx), block.Txs},
+		{"Txs[0]", new(queryTx), queryTx{1, "a"}},
+		{"Txs[1].Memo", new(string), ""},
+		{"Txs[2].Fee", new(uint32), uint32(3)},
+		{"TxPtrs[0]", new(*queryTx), (*queryTx)(nil)},
+		{"TxPtrs[1]", new(*queryTx), &queryTx{2, "b"}},
+		{"TxPtrs[1].Memo", new(string), "b"},
+		{"Heights", new([]int64), block.Heights},
+		{"Heights[1]", new(int64), int64(0)},
+		{"Heights[2]", new(int64), int64(-3)},
+		{"Msg", new(queryMsg), queryMsg(queryMsgA{"a"})},
+		{"Msg", new(queryMsgA), queryMsgA{"a"}},
+		{"Msg.A", new(string), "a"},
+		{"Msgs[0]", new(queryMsgB), queryMsgB{[]byte("b"), 7}},
+		{"Msgs[0]", new(*queryMsgB), &queryMsgB{[]byte("b"), 7}},
+		{"Msgs[0].N", new(int64), int64(7)},
+		{"Msgs[1]", new(queryMsg), nil},
+		{"Msgs[2].A", new(string), ""},
+		{"Temp", new(Celsius), Celsius{-40}},
+		{"Temps[2]", new(Celsius), Celsius{3}},
+		{"Data", new([]byte), []byte("data")},
+		{"Fees", new(map[string]int64), block.Fees},
+		{"Tail", new(int64), int64(9)},
+	}
+	errCases := []struct {
+		path string
+		out  interface{}
+	}{
+		{"Txs[3]", new(queryTx)},
+		{"Heights[3]", new(int64)},
+		{"Header.Nope", new(int64)},
+		{"Header.Height.X", new(int64)},
+		{"Header[0]", new(int64)},
+		{"Data[0]", new(byte)},
+		{"Msg.B", new([]byte)},
+		{"Msgs[1].A", new(string)},
+		{"Msg", new(queryMsgB)},
+		{"Header.Height", new(string)},
+		{"Temp.Degrees", new(int64)},
+		{"Header.", new(int64)},
+		{"Txs[-1]", new(queryTx)},
+		{"Txs[0", new(queryTx)},
+	}
+
+	bz, err := cdc.MarshalBinaryBare(block)
+	require.NoError(t, err)
+	jsonBz, err := cdc.MarshalJSON(block)
+	require.NoError(t, err)
+
+	for _, tc := range cases {
+		out := reflect.New(reflect.TypeOf(tc.out).Elem())
+		err := cdc.QueryBinary(bz, rootType, tc.path, out.Interface())
+		if assert.NoError(t, err, "binary %v", tc.path) {
+			assert.Equal(t, tc.want, out.Elem().Interface(), "binary %v", tc.path)
+		}
+
+		out = reflect.New(reflect.TypeOf(tc.out).Elem())
+		err = cdc.QueryJSON(jsonBz, rootType, tc.path, out.Interface())
+		if assert.NoError(t, err, "json %v", tc.path) {
+			assert.Equal(t, tc.want, out.Elem().Interface(), "json %v", tc.path)
+		}
+	}
+	for _, tc := range errCases {
+		assert.Error(t, cdc.QueryBinary(bz, rootType, tc.path, tc.out), "binary %v", tc.path)
+		assert.Error(t, cdc.QueryJSON(jsonBz, rootType, tc.path, tc.out), "json %v", tc.path)
+	}
+
+	// Fields that are not present have the default value.
+	bz, err = cdc.MarshalBinaryBare(queryBlock{})
+	require.NoError(t, err)
+	var height = int64(1)
+	assert.NoError(t, cdc.QueryBinary(bz, rootType, "Header.Height", &height))
+	assert.Equal(t, int64(0), height)
+	assert.Error(t, cdc.QueryBinary(bz, rootType, "Msg.A", new(string)))
+
+	// Interface roots.
+	bz, err = cdc.MarshalBinaryBare(&queryMsgB{N: 8})
+	require.NoError(t, err)
+	assert.NoError(t, cdc.QueryBinary(bz, reflect.TypeOf((*queryMsg)(nil)).Elem(), "N", &height))
+	assert.Equal(t, int64(8), height)
+	assert.NoError(t, cdc.QueryBinary(bz, reflect.TypeOf(queryMsgB{}), "N", &height))
+	assert.Equal(t, int64(8), height)
+
+	// Wrong prefix bytes.
+	bz[0] ^= 0xff
+	err = cdc.QueryBinary(bz, reflect.TypeOf(queryMsgB{}), "N", &height)
+	if assert.IsType(t, &amino.DecodeError{}, err) {
+		assert.Equal(t, amino.ErrKindUnknownPrefix, err.(*amino.DecodeError).Kind)
+		assert.Equal(t, 0, err.(*amino.DecodeError).Offset)
+	}
+}
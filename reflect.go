@@ -1,6 +1,7 @@
 package amino
 
 import (
+	"encoding"
 	"encoding/json"
 	"fmt"
 	"reflect"
@@ -16,7 +17,17 @@ var (
 	timeType            = reflect.TypeOf(time.Time{})
 	jsonMarshalerType   = reflect.TypeOf(new(json.Marshaler)).Elem()
 	jsonUnmarshalerType = reflect.TypeOf(new(json.Unmarshaler)).Elem()
+	textMarshalerType   = reflect.TypeOf(new(encoding.TextMarshaler)).Elem()
+	textUnmarshalerType = reflect.TypeOf(new(encoding.TextUnmarshaler)).Elem()
 	errorType           = reflect.TypeOf(new(error)).Elem()
+	unknownFieldsType   = reflect.TypeOf(UnknownFields(nil))
+	unknownConcreteType = reflect.TypeOf(UnknownConcrete{})
+	rawType             = reflect.TypeOf(Raw(nil))
+	emptyInterfaceType  = reflect.TypeOf((*interface{})(nil)).Elem()
+	byteType            = reflect.TypeOf(byte(0))
+
+	aminoBinaryMarshalerType   = reflect.TypeOf(new(AminoBinaryMarshaler)).Elem()
+	aminoBinaryUnmarshalerType = reflect.TypeOf(new(AminoBinaryUnmarshaler)).Elem()
 )
 
 //----------------------------------------
@@ -117,6 +128,10 @@ func isDefaultValue(rv reflect.Value) (erv reflect.Value, isDefaultValue bool) {
 	case reflect.String:
 		return rv, rv.Len() == 0
 	case reflect.Chan, reflect.Map, reflect.Slice:
+		if rv.Type() == rawType {
+			// An empty Raw was decoded from an empty field, see Raw.
+			return rv, rv.IsNil()
+		}
 		return rv, rv.IsNil() || rv.Len() == 0
 	case reflect.Func, reflect.Interface:
 		return rv, rv.IsNil()
@@ -240,3 +255,24 @@ func toReprObject(rv reflect.Value) (rrv reflect.Value, err error) {
 	rrv = mwouts[0]
 	return
 }
+
+// reuseConcreteType is like constructConcreteType, but keeps the pointer held
+// by the interface rv when it points to a value of the concrete type, so that
+// decoding into a pre-populated interface keeps its pointer.  crv is always a
+// new zero value, so that fields absent from the encoding are reset, and once
+// decoded it must be copied to reused (if valid), so that the old value is
+// left as is on errors.
+func reuseConcreteType(rv reflect.Value, cinfo *TypeInfo) (crv, irvSet, reused reflect.Value) {
+	if !rv.IsNil() {
+		var erv = rv.Elem()
+		switch {
+		case erv.Kind() == reflect.Ptr && erv.Type().Elem() == cinfo.Type && !erv.IsNil():
+			return reflect.New(cinfo.Type).Elem(), erv, erv.Elem()
+		case erv.Type() == cinfo.Type:
+			crv = reflect.New(cinfo.Type).Elem()
+			return crv, crv, reflect.Value{}
+		}
+	}
+	crv, irvSet = constructConcreteType(cinfo)
+	return
+}
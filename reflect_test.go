@@ -93,6 +93,13 @@ func _testCodec(t *testing.T, rt reflect.Type, codecType string) {
 			"failed to marshal %v to bytes: %v\n",
 			spw(ptr), err)
 
+		if codecType == "binary" {
+			var size int
+			size, err = cdc.SizeBinaryBare(ptr)
+			require.NoError(t, err)
+			require.Equal(t, len(bz), size, "wrong size of %v", spw(ptr))
+		}
+
 		switch codecType {
 		case "binary":
 			err = cdc.UnmarshalBinaryBare(bz, ptr2)
@@ -227,6 +234,72 @@ func TestCodecRegisterAndMarshalMultipleConcrete(t *testing.T) {
 	}
 }
 
+func TestCodecSealedSnapshot(t *testing.T) {
+	type Bar struct{ B int64 }
+	cdc := NewCodec()
+	cdc.RegisterInterface((*tests.Interface1)(nil), nil)
+	cdc.RegisterConcrete((*tests.Concrete1)(nil), "Concrete1", nil)
+	cdc.Seal()
+	snap := cdc.getSnapshot()
+
+	// Types discovered once sealed are added without copying the state as
+	// of Seal.
+	bz, err := cdc.MarshalBinaryBare(Bar{1})
+	require.NoError(t, err)
+	var bar Bar
+	require.NoError(t, cdc.UnmarshalBinaryBare(bz, &bar))
+	assert.Equal(t, Bar{1}, bar)
+	snap2 := cdc.getSnapshot()
+	assert.Equal(t, reflect.ValueOf(snap.typeInfos).Pointer(), reflect.ValueOf(snap2.typeInfos).Pointer())
+	assert.Contains(t, snap2.newTypeInfos, reflect.TypeOf(Bar{}))
+	info, ok := snap2.getTypeInfo(reflect.TypeOf(Bar{}))
+	assert.True(t, ok)
+	assert.Equal(t, reflect.TypeOf(Bar{}), info.Type)
+
+	// As are settings.
+	cdc.SetDecodeLimits(DecodeLimits{MaxDepth: 5})
+	snap3 := cdc.getSnapshot()
+	assert.Equal(t, reflect.ValueOf(snap.typeInfos).Pointer(), reflect.ValueOf(snap3.typeInfos).Pointer())
+	assert.Equal(t, DecodeLimits{MaxDepth: 5}, snap3.decodeLimits)
+	assert.Equal(t, snap2.newTypeInfos, snap3.newTypeInfos)
+}
+
+func TestCodecRegisterConcreteEnum(t *testing.T) {
+	type Enum int8
+	type Holder struct{ E Enum }
+	cdc := NewCodec()
+	cdc.RegisterEnum(Enum(0), map[Enum]string{1: "One"}, nil)
+
+	// The plan of Holder refers to the type info of Enum, which is then
+	// registered in place.
+	_, err := cdc.MarshalBinaryBare(Holder{1})
+	require.NoError(t, err)
+	info := cdc.typeInfos[reflect.TypeOf(Enum(0))]
+	hinfo := cdc.typeInfos[reflect.TypeOf(Holder{})]
+	cdc.RegisterConcrete(Enum(0), "amino/Enum", nil)
+	assert.True(t, cdc.typeInfos[reflect.TypeOf(Enum(0))] == info)
+	assert.True(t, info.Registered)
+	assert.True(t, info.IsEnum)
+	plan, err := cdc.getBinaryPlan(hinfo)
+	require.NoError(t, err)
+	assert.True(t, plan.fields[0].info == info)
+
+	bz, err := cdc.MarshalJSON(struct{ Any interface{} }{Enum(1)})
+	require.NoError(t, err)
+	assert.Equal(t, `{"Any":{"type":"amino/Enum","value":"One"}}`, string(bz))
+
+	// A failed registration leaves it as is.
+	type Enum2 uint8
+	cdc.RegisterEnum(Enum2(0), map[Enum2]string{1: "One"}, nil)
+	info2 := cdc.typeInfos[reflect.TypeOf(Enum2(0))]
+	assert.Panics(t, func() {
+		cdc.RegisterConcrete(Enum2(0), "amino/Enum", nil)
+	}, "duplicate name")
+	assert.True(t, cdc.typeInfos[reflect.TypeOf(Enum2(0))] == info2)
+	assert.False(t, info2.Registered)
+	assert.True(t, info2.IsEnum)
+}
+
 // Serialize and deserialize a non-nil interface value.
 func TestCodecRoundtripNonNilRegisteredTypeDef(t *testing.T) {
 	cdc := NewCodec()
@@ -63,6 +63,10 @@ func TestMarshalAminoBinary(t *testing.T) {
 
 	t.Logf("bz %#v", bz)
 
+	size, err := cdc.SizeBinaryLengthPrefixed(f)
+	assert.NoError(t, err)
+	assert.Equal(t, len(bz), size)
+
 	var f2 Foo
 	err = cdc.UnmarshalBinaryLengthPrefixed(bz, &f2)
 	assert.NoError(t, err)
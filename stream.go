@@ -0,0 +1,315 @@
+package amino
+
+import (
+	"bufio"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+//----------------------------------------
+// Streams of length-prefixed messages
+
+// DecoderOptions configures a Decoder.  A zero value for any of the limits
+// means that it is not enforced.
+type DecoderOptions struct {
+	// Max length of a single message, excluding its length prefix.
+	MaxMsgSize int64
+	// Max total number of bytes read from the stream, including the length
+	// prefixes.
+	MaxTotalSize int64
+	// Limits for decoding each message.  If nil, those of the codec are used.
+	Limits *DecodeLimits
+}
+
+// Decoder reads a stream of length-prefixed messages, as written by Encoder
+// or MarshalBinaryLengthPrefixedWriter.
+type Decoder struct {
+	r      *bufio.Reader
+	cdc    *Codec
+	opts   DecoderOptions
+	buf    []byte // Reused for each message.
+	offset int64  // Bytes read so far.
+	err    error  // Sticky read error.
+}
+
+// NewDecoder returns a Decoder that reads from r.  The Decoder buffers its
+// reads, so it may read past the last message it decodes.
+func NewDecoder(r io.Reader, cdc *Codec, opts DecoderOptions) *Decoder {
+	return &Decoder{
+		r:    bufio.NewReader(r),
+		cdc:  cdc,
+		opts: opts,
+	}
+}
+
+// More reports whether there is another message to decode, i.e. whether the
+// stream did not end cleanly.  A read error also counts as more, so that
+// Decode returns it.
+func (d *Decoder) More() bool {
+	if d.err != nil {
+		return d.err != io.EOF
+	}
+	_, err := d.r.Peek(1)
+	return err != io.EOF
+}
+
+// Decode reads the next message and decodes it into ptr, like
+// UnmarshalBinaryLengthPrefixed.  It returns io.EOF if the stream ends before
+// the message, and io.ErrUnexpectedEOF if it ends within it.  If the message
+// can't be decoded, the error is returned but the message is consumed, so
+// that the next one can still be decoded.  Read errors, including exceeding
+// the limits of DecoderOptions, are returned by all later calls.
+func (d *Decoder) Decode(ptr interface{}) error {
+	if d.err != nil {
+		return d.err
+	}
+	var limits DecodeLimits
+	if d.opts.Limits != nil {
+		limits = *d.opts.Limits
+	} else {
+		limits = d.cdc.getDecodeLimits()
+	}
+	var ds = newDecodeState(limits)
+	var bz, err = d.readMsg(ds)
+	if err != nil {
+		d.err = err
+		return err
+	}
+
+	// Offsets of DecodeErrors are relative to the start of the stream.
+	ds.input, ds.base = bz, int(d.offset-int64(len(bz)))
+	return d.cdc.unmarshalBinaryBare(bz, ptr, ds)
+}
+
+// InputOffset returns the number of bytes read so far, i.e. the offset of
+// the next message in the stream.
+func (d *Decoder) InputOffset() int64 {
+	return d.offset
+}
+
+// Reads the length prefix and the message, and returns the message.  The
+// message is accounted for in ds.
+func (d *Decoder) readMsg(ds *decodeState) ([]byte, error) {
+	// Read byte-length prefix.
+	var u64 uint64
+	var n int64
+	for shift := uint(0); ; shift += 7 {
+		b, err := d.r.ReadByte()
+		if err != nil {
+			if err == io.EOF && n > 0 {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+		n++
+		if n > 10 || n == 10 && b > 1 {
+			return nil, errors.New("Error reading msg byte-length prefix: uvarint overflows a 64-bit integer")
+		}
+		u64 |= uint64(b&0x7f) << shift
+		if b&0x80 == 0 {
+			break
+		}
+	}
+	d.offset += n
+
+	// Check the limits.
+	if max := d.opts.MaxMsgSize; max > 0 && u64 > uint64(max) {
+		return nil, LimitExceededErr{"MaxMsgSize", max}
+	}
+	if max := d.opts.MaxTotalSize; max > 0 && (d.offset > max || u64 > uint64(max-d.offset)) {
+		return nil, LimitExceededErr{"MaxTotalSize", max}
+	}
+	if int64(u64) < 0 || int(u64) < 0 {
+		return nil, errors.Errorf("read overflow, message of %v bytes is too long", u64)
+	}
+	if err := ds.allocate(int64(u64)); err != nil {
+		return nil, err
+	}
+
+	// Read the message, reusing the buffer.
+	var size = int(u64)
+	if cap(d.buf) < size {
+		d.buf = make([]byte, size)
+	}
+	var bz = d.buf[:size]
+	if _, err := io.ReadFull(d.r, bz); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	d.offset += int64(size)
+	return bz, nil
+}
+
+// Encoder writes a stream of length-prefixed messages, which can be read
+// with Decoder.
+type Encoder struct {
+	w   io.Writer
+	cdc *Codec
+	buf []byte // Reused for each message.
+}
+
+// NewEncoder returns an Encoder that writes to w.
+func NewEncoder(w io.Writer, cdc *Codec) *Encoder {
+	return &Encoder{w: w, cdc: cdc}
+}
+
+// Encode writes o to the stream, as MarshalBinaryLengthPrefixed would encode
+// it.  Each message is written with a single call to w.Write.
+func (e *Encoder) Encode(o interface{}) (err error) {
+	e.buf, err = e.cdc.AppendBinaryLengthPrefixed(e.buf[:0], o)
+	if err != nil {
+		return
+	}
+	_, err = e.w.Write(e.buf)
+	if cap(e.buf) > maxPooledBufferSize {
+		e.buf = nil // Don't hold on to large buffers.
+	}
+	return
+}
+
+// PushDecoder decodes a stream of length-prefixed messages that arrives in
+// chunks of any size, e.g. from the network.  Unlike Decoder, it doesn't read
+// the stream itself; the chunks are passed to Write as they arrive, and each
+// message is decoded and handled as soon as all of its bytes are written.
+type PushDecoder struct {
+	cdc    *Codec
+	opts   DecoderOptions
+	newMsg func() interface{}
+	onMsg  func(msg interface{}) error
+	buf    []byte // The incomplete message, if any, with its length prefix.
+	offset int64  // Bytes of complete messages so far.
+	err    error  // Sticky framing error.
+}
+
+// NewPushDecoder returns a PushDecoder that decodes each message into
+// newMsg(), which must return a pointer, and passes it on to onMsg.
+func NewPushDecoder(cdc *Codec, opts DecoderOptions, newMsg func() interface{}, onMsg func(msg interface{}) error) *PushDecoder {
+	return &PushDecoder{
+		cdc:    cdc,
+		opts:   opts,
+		newMsg: newMsg,
+		onMsg:  onMsg,
+	}
+}
+
+// Write decodes the messages completed by chunk, and keeps any remaining
+// bytes until the next call.  If a message can't be decoded, or onMsg fails,
+// the error is returned along with the number of bytes of chunk consumed up
+// to the end of that message, so the rest of chunk can be written again.
+// Framing errors, including exceeding the limits of DecoderOptions, are
+// returned by all later calls.  Write never keeps a reference to chunk.
+func (d *PushDecoder) Write(chunk []byte) (n int, err error) {
+	if d.err != nil {
+		return 0, d.err
+	}
+	for len(chunk) > 0 {
+		var pl, size int
+		var msg []byte
+		if len(d.buf) == 0 {
+			// Decode straight from chunk if it holds the whole message.
+			pl, size, err = d.frame(chunk)
+			if err != nil {
+				return
+			}
+			if pl == 0 || len(chunk) < pl+size {
+				d.buf = append(d.buf, chunk...)
+				n += len(chunk)
+				return
+			}
+			msg = chunk[pl : pl+size]
+			chunk = chunk[pl+size:]
+			n += pl + size
+		} else {
+			// Only take what is needed for the message.
+			var take = d.Needed()
+			if take > len(chunk) {
+				take = len(chunk)
+			}
+			d.buf = append(d.buf, chunk[:take]...)
+			chunk = chunk[take:]
+			n += take
+			pl, size, err = d.frame(d.buf)
+			if err != nil {
+				return
+			}
+			if pl == 0 || len(d.buf) < pl+size {
+				continue
+			}
+			msg = d.buf[pl:]
+			if cap(d.buf) > maxPooledBufferSize {
+				d.buf = nil // Don't hold on to large buffers.
+			} else {
+				d.buf = d.buf[:0]
+			}
+		}
+		if err = d.decode(pl, msg); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// Needed returns the number of bytes needed to complete the next message, or
+// 1 if its length prefix is not complete yet.
+func (d *PushDecoder) Needed() int {
+	var pl, size, err = d.frame(d.buf)
+	if err != nil || pl == 0 {
+		return 1
+	}
+	return pl + size - len(d.buf)
+}
+
+// Buffered returns the number of bytes written of the next message.
+func (d *PushDecoder) Buffered() int {
+	return len(d.buf)
+}
+
+// Returns the length of the length prefix at the start of bz and the length
+// of the message, or zeros if the prefix is not complete.
+func (d *PushDecoder) frame(bz []byte) (pl int, size int, err error) {
+	var u64 uint64
+	u64, pl, err = DecodeUvarint(bz)
+	if pl == 0 {
+		return 0, 0, nil
+	}
+	if err != nil {
+		err = errors.Wrap(err, "Error reading msg byte-length prefix")
+	} else if max := d.opts.MaxMsgSize; max > 0 && u64 > uint64(max) {
+		err = LimitExceededErr{"MaxMsgSize", max}
+	} else if max := d.opts.MaxTotalSize; max > 0 && (d.offset+int64(pl) > max || u64 > uint64(max-d.offset-int64(pl))) {
+		err = LimitExceededErr{"MaxTotalSize", max}
+	} else if int64(u64) < 0 || int(u64) < 0 || int(u64)+pl < 0 {
+		err = errors.Errorf("read overflow, message of %v bytes is too long", u64)
+	}
+	if err != nil {
+		d.err = err
+		return 0, 0, err
+	}
+	return pl, int(u64), nil
+}
+
+// Decodes and handles the message msg, which had a length prefix of pl bytes.
+// The message is consumed either way.
+func (d *PushDecoder) decode(pl int, msg []byte) error {
+	var limits DecodeLimits
+	if d.opts.Limits != nil {
+		limits = *d.opts.Limits
+	} else {
+		limits = d.cdc.getDecodeLimits()
+	}
+	var ds = newDecodeState(limits)
+	d.offset += int64(pl + len(msg))
+	// Offsets of DecodeErrors are relative to the start of the stream.
+	ds.input, ds.base = msg, int(d.offset-int64(len(msg)))
+	if err := ds.allocate(int64(len(msg))); err != nil {
+		return err
+	}
+	var ptr = d.newMsg()
+	if err := d.cdc.unmarshalBinaryBare(msg, ptr, ds); err != nil {
+		return err
+	}
+	return d.onMsg(ptr)
+}
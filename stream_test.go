@@ -0,0 +1,219 @@
+package amino_test
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	amino "github.com/tendermint/go-amino"
+)
+
+func TestEncoderDecoder(t *testing.T) {
+	var cdc = amino.NewCodec()
+
+	type Entry struct {
+		Height int64
+		Data   []byte
+	}
+	var entries = []Entry{{1, []byte("foo")}, {2, nil}, {3, bytes.Repeat([]byte("x"), 1000)}}
+
+	var buf = new(bytes.Buffer)
+	var enc = amino.NewEncoder(buf, cdc)
+	for _, e := range entries {
+		assert.Nil(t, enc.Encode(e))
+	}
+	var want []byte
+	for _, e := range entries {
+		want = append(want, cdc.MustMarshalBinaryLengthPrefixed(e)...)
+	}
+	assert.Equal(t, want, buf.Bytes())
+
+	var dec = amino.NewDecoder(bytes.NewReader(want), cdc, amino.DecoderOptions{})
+	var got []Entry
+	for dec.More() {
+		var e Entry
+		assert.Nil(t, dec.Decode(&e))
+		got = append(got, e)
+	}
+	assert.Equal(t, entries, got)
+	assert.Equal(t, int64(len(want)), dec.InputOffset())
+	assert.Equal(t, io.EOF, dec.Decode(new(Entry)))
+
+	// The stream ends within a message.
+	for _, l := range []int{len(want) - 1, len(want) - 1001} {
+		dec = amino.NewDecoder(bytes.NewReader(want[:l]), cdc, amino.DecoderOptions{})
+		var err error
+		for err == nil {
+			err = dec.Decode(new(Entry))
+		}
+		assert.Equal(t, io.ErrUnexpectedEOF, err, "length %v", l)
+		assert.True(t, dec.More())
+	}
+
+	// A message that can't be decoded is skipped.
+	var bad = append([]byte{0x01, 0xFF}, want...)
+	dec = amino.NewDecoder(bytes.NewReader(bad), cdc, amino.DecoderOptions{})
+	assert.NotNil(t, dec.Decode(new(Entry)))
+	got = nil
+	for dec.More() {
+		var e Entry
+		assert.Nil(t, dec.Decode(&e))
+		got = append(got, e)
+	}
+	assert.Equal(t, entries, got)
+}
+
+func TestDecoderLimits(t *testing.T) {
+	var cdc = amino.NewCodec()
+
+	var buf = new(bytes.Buffer)
+	var enc = amino.NewEncoder(buf, cdc)
+	for _, s := range []string{"foo", "barbaz", "qux"} {
+		assert.Nil(t, enc.Encode(stringWrapper{s}))
+	}
+	var bz = buf.Bytes() // Messages of 5, 8 and 5 bytes.
+
+	cases := []struct {
+		opts  amino.DecoderOptions
+		count int
+		limit string
+	}{
+		{amino.DecoderOptions{}, 3, ""},
+		{amino.DecoderOptions{MaxMsgSize: 8}, 3, ""},
+		{amino.DecoderOptions{MaxMsgSize: 7}, 1, "MaxMsgSize"},
+		{amino.DecoderOptions{MaxTotalSize: int64(len(bz))}, 3, ""},
+		{amino.DecoderOptions{MaxTotalSize: int64(len(bz) - 1)}, 2, "MaxTotalSize"},
+		{amino.DecoderOptions{Limits: &amino.DecodeLimits{MaxStringLen: 3}}, 1, "MaxStringLen"},
+	}
+	for i, tc := range cases {
+		var dec = amino.NewDecoder(bytes.NewReader(bz), cdc, tc.opts)
+		var count int
+		var err error
+		for dec.More() {
+			if err = dec.Decode(new(stringWrapper)); err != nil {
+				break
+			}
+			count++
+		}
+		assert.Equal(t, tc.count, count, "case %v", i)
+		if tc.limit == "" {
+			assert.Nil(t, err, "case %v", i)
+		} else if assert.IsType(t, amino.LimitExceededErr{}, err, "case %v", i) {
+			assert.Equal(t, tc.limit, err.(amino.LimitExceededErr).Limit, "case %v", i)
+		}
+	}
+
+	// The first two messages take 15 bytes with their length prefixes, so
+	// the limit is exceeded by the prefix of the third, and none of the
+	// following messages are read.
+	var dec = amino.NewDecoder(bytes.NewReader(bytes.Repeat(bz, 3)), cdc,
+		amino.DecoderOptions{MaxTotalSize: 15})
+	var count int
+	var err error
+	for dec.More() && count < 10 {
+		if err = dec.Decode(new(stringWrapper)); err != nil {
+			break
+		}
+		count++
+	}
+	assert.Equal(t, 2, count)
+	assert.Equal(t, amino.LimitExceededErr{Limit: "MaxTotalSize", Max: 15}, err)
+}
+
+func TestPushDecoder(t *testing.T) {
+	var cdc = amino.NewCodec()
+
+	var msgs = []string{"foo", "", "barbaz", string(bytes.Repeat([]byte("x"), 200))}
+	var bz []byte
+	for _, s := range msgs {
+		bz = append(bz, cdc.MustMarshalBinaryLengthPrefixed(stringWrapper{s})...)
+	}
+
+	// Write the stream in chunks of every size.
+	for chunkSize := 1; chunkSize <= len(bz); chunkSize++ {
+		var got []string
+		var dec = amino.NewPushDecoder(cdc, amino.DecoderOptions{},
+			func() interface{} { return new(stringWrapper) },
+			func(msg interface{}) error {
+				got = append(got, msg.(*stringWrapper).S)
+				return nil
+			})
+		for i := 0; i < len(bz); i += chunkSize {
+			var end = i + chunkSize
+			if end > len(bz) {
+				end = len(bz)
+			}
+			n, err := dec.Write(bz[i:end])
+			assert.Nil(t, err)
+			assert.Equal(t, end-i, n)
+		}
+		assert.Equal(t, msgs, got, "chunk size %v", chunkSize)
+		assert.Equal(t, 0, dec.Buffered())
+		assert.Equal(t, 1, dec.Needed())
+	}
+
+	// Needed counts down to the end of the message.
+	var dec = amino.NewPushDecoder(cdc, amino.DecoderOptions{},
+		func() interface{} { return new(stringWrapper) },
+		func(msg interface{}) error { return nil })
+	var first = len(cdc.MustMarshalBinaryLengthPrefixed(stringWrapper{"foo"}))
+	for i := 0; i < first; i++ {
+		if i > 0 {
+			assert.Equal(t, first-i, dec.Needed(), "after %v bytes", i)
+		}
+		_, err := dec.Write(bz[i : i+1])
+		assert.Nil(t, err)
+	}
+	assert.Equal(t, 0, dec.Buffered())
+}
+
+func TestPushDecoderErrors(t *testing.T) {
+	var cdc = amino.NewCodec()
+	var newMsg = func() interface{} { return new(stringWrapper) }
+
+	var foo = cdc.MustMarshalBinaryLengthPrefixed(stringWrapper{"foo"})
+	var bad = []byte{0x01, 0xFF}
+	var bz = append(append(append([]byte(nil), foo...), bad...), foo...)
+
+	// A message that can't be decoded is skipped.
+	var count int
+	var dec = amino.NewPushDecoder(cdc, amino.DecoderOptions{}, newMsg,
+		func(msg interface{}) error { count++; return nil })
+	n, err := dec.Write(bz)
+	assert.NotNil(t, err)
+	assert.Equal(t, len(foo)+len(bad), n)
+	n, err = dec.Write(bz[n:])
+	assert.Nil(t, err)
+	assert.Equal(t, len(foo), n)
+	assert.Equal(t, 2, count)
+
+	// Errors of onMsg are returned.
+	dec = amino.NewPushDecoder(cdc, amino.DecoderOptions{}, newMsg,
+		func(msg interface{}) error { return io.ErrShortWrite })
+	n, err = dec.Write(foo[:2])
+	assert.Nil(t, err)
+	assert.Equal(t, 2, n)
+	n, err = dec.Write(foo[2:])
+	assert.Equal(t, io.ErrShortWrite, err)
+	assert.Equal(t, len(foo)-2, n)
+
+	// Messages that are too long are rejected before they are buffered.
+	dec = amino.NewPushDecoder(cdc, amino.DecoderOptions{MaxMsgSize: 4}, newMsg,
+		func(msg interface{}) error { return nil })
+	_, err = dec.Write(foo[:1])
+	assert.Equal(t, amino.LimitExceededErr{"MaxMsgSize", 4}, err)
+	_, err = dec.Write(foo[1:])
+	assert.Equal(t, amino.LimitExceededErr{"MaxMsgSize", 4}, err)
+	assert.Equal(t, 0, dec.Buffered())
+
+	// Two messages fit in MaxTotalSize, and the length prefix of the third
+	// exceeds it, so none of the following messages are decoded.
+	count = 0
+	dec = amino.NewPushDecoder(cdc, amino.DecoderOptions{MaxTotalSize: int64(2 * len(foo))}, newMsg,
+		func(msg interface{}) error { count++; return nil })
+	n, err = dec.Write(bytes.Repeat(foo, 5))
+	assert.Equal(t, amino.LimitExceededErr{"MaxTotalSize", int64(2 * len(foo))}, err)
+	assert.Equal(t, 2*len(foo), n)
+	assert.Equal(t, 2, count)
+}
@@ -0,0 +1,956 @@
+// Code generated by aminogen. DO NOT EDIT.
+
+package gen
+
+import (
+	"bytes"
+	"fmt"
+
+	amino "github.com/tendermint/go-amino"
+)
+
+// MarshalAminoBinary implements amino.AminoBinaryMarshaler.
+func (x Coin) MarshalAminoBinary(cdc *amino.Codec) ([]byte, error) {
+	var buf = new(bytes.Buffer)
+	var err error
+	// Field 1: Denom
+	if len(x.Denom) != 0 {
+		if err = amino.EncodeFieldKey(buf, 1, amino.Typ3ByteLength); err != nil {
+			return nil, err
+		}
+		if err = amino.EncodeString(buf, string(x.Denom)); err != nil {
+			return nil, err
+		}
+	}
+	// Field 2: Amount
+	if x.Amount != 0 {
+		if err = amino.EncodeFieldKey(buf, 2, amino.Typ3Varint); err != nil {
+			return nil, err
+		}
+		if err = amino.EncodeUvarint(buf, uint64(x.Amount)); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// AminoSize implements amino.AminoBinaryMarshaler.
+func (x Coin) AminoSize(cdc *amino.Codec) (int, error) {
+	var n int
+	// Field 1: Denom
+	if len(x.Denom) != 0 {
+		n += 1 + amino.UvarintSize(uint64(len(x.Denom))) + len(x.Denom)
+	}
+	// Field 2: Amount
+	if x.Amount != 0 {
+		n += 1 + amino.UvarintSize(uint64(x.Amount))
+	}
+	return n, nil
+}
+
+// UnmarshalAminoBinary implements amino.AminoBinaryUnmarshaler.
+func (x *Coin) UnmarshalAminoBinary(cdc *amino.Codec, bz []byte) (err error) {
+	var lastFieldNum uint32
+	var _n int
+	var found bool
+	// Field 1: Denom
+	if found, _n, err = amino.DecodeFieldKey(bz, 1, amino.Typ3ByteLength, &lastFieldNum); err != nil {
+		return
+	}
+	bz = bz[_n:]
+	if found {
+		var v []byte
+		if v, _n, err = amino.DecodeByteSliceNoCopy(bz); err != nil {
+			return
+		}
+		x.Denom = string(v)
+		bz = bz[_n:]
+	} else {
+		x.Denom = ""
+	}
+	// Field 2: Amount
+	if found, _n, err = amino.DecodeFieldKey(bz, 2, amino.Typ3Varint, &lastFieldNum); err != nil {
+		return
+	}
+	bz = bz[_n:]
+	if found {
+		var v uint64
+		if v, _n, err = amino.DecodeUvarint(bz); err != nil {
+			return
+		}
+		x.Amount = int64(v)
+		bz = bz[_n:]
+	} else {
+		x.Amount = 0
+	}
+	_, err = amino.SkipUnknownFields(bz, &lastFieldNum)
+	return
+}
+
+// MarshalAminoBinary implements amino.AminoBinaryMarshaler.
+func (x Header) MarshalAminoBinary(cdc *amino.Codec) ([]byte, error) {
+	var buf = new(bytes.Buffer)
+	var err error
+	// Field 1: ChainID
+	if len(x.ChainID) != 0 {
+		if err = amino.EncodeFieldKey(buf, 1, amino.Typ3ByteLength); err != nil {
+			return nil, err
+		}
+		if err = amino.EncodeString(buf, string(x.ChainID)); err != nil {
+			return nil, err
+		}
+	}
+	// Field 2: Height
+	if x.Height != 0 {
+		if err = amino.EncodeFieldKey(buf, 2, amino.Typ3Varint); err != nil {
+			return nil, err
+		}
+		if err = amino.EncodeUvarint(buf, uint64(x.Height)); err != nil {
+			return nil, err
+		}
+	}
+	// Field 3: Time
+	if err = cdc.EncodeBinaryField(buf, &x, 2); err != nil {
+		return nil, err
+	}
+	// Field 4: Round
+	if x.Round != 0 {
+		if err = amino.EncodeFieldKey(buf, 4, amino.Typ3_4Byte); err != nil {
+			return nil, err
+		}
+		if err = amino.EncodeInt32(buf, int32(x.Round)); err != nil {
+			return nil, err
+		}
+	}
+	// Field 5: Nonce
+	if x.Nonce != 0 {
+		if err = amino.EncodeFieldKey(buf, 5, amino.Typ38Byte); err != nil {
+			return nil, err
+		}
+		if err = amino.EncodeUint64(buf, uint64(x.Nonce)); err != nil {
+			return nil, err
+		}
+	}
+	// Field 6: Small
+	if x.Small != 0 {
+		if err = amino.EncodeFieldKey(buf, 6, amino.Typ3Varint); err != nil {
+			return nil, err
+		}
+		if err = amino.EncodeInt8(buf, int8(x.Small)); err != nil {
+			return nil, err
+		}
+	}
+	// Field 7: Kind
+	if x.Kind != 0 {
+		if err = amino.EncodeFieldKey(buf, 7, amino.Typ3Varint); err != nil {
+			return nil, err
+		}
+		if err = amino.EncodeInt16(buf, int16(x.Kind)); err != nil {
+			return nil, err
+		}
+	}
+	// Field 8: Count
+	if x.Count != 0 {
+		if err = amino.EncodeFieldKey(buf, 8, amino.Typ3Varint); err != nil {
+			return nil, err
+		}
+		if err = amino.EncodeUvarint(buf, uint64(x.Count)); err != nil {
+			return nil, err
+		}
+	}
+	// Field 9: U8
+	if x.U8 != 0 {
+		if err = amino.EncodeFieldKey(buf, 9, amino.Typ3Varint); err != nil {
+			return nil, err
+		}
+		if err = amino.EncodeUint8(buf, uint8(x.U8)); err != nil {
+			return nil, err
+		}
+	}
+	// Field 10: U16
+	if x.U16 != 0 {
+		if err = amino.EncodeFieldKey(buf, 10, amino.Typ3Varint); err != nil {
+			return nil, err
+		}
+		if err = amino.EncodeUint16(buf, uint16(x.U16)); err != nil {
+			return nil, err
+		}
+	}
+	// Field 11: U32
+	if x.U32 != 0 {
+		if err = amino.EncodeFieldKey(buf, 11, amino.Typ3Varint); err != nil {
+			return nil, err
+		}
+		if err = amino.EncodeUvarint(buf, uint64(x.U32)); err != nil {
+			return nil, err
+		}
+	}
+	// Field 12: U
+	if x.U != 0 {
+		if err = amino.EncodeFieldKey(buf, 12, amino.Typ3Varint); err != nil {
+			return nil, err
+		}
+		if err = amino.EncodeUvarint(buf, uint64(x.U)); err != nil {
+			return nil, err
+		}
+	}
+	// Field 13: Flag
+	if x.Flag {
+		if err = amino.EncodeFieldKey(buf, 13, amino.Typ3Varint); err != nil {
+			return nil, err
+		}
+		if err = amino.EncodeBool(buf, bool(x.Flag)); err != nil {
+			return nil, err
+		}
+	}
+	// Field 14: Hash
+	if err = amino.EncodeFieldKey(buf, 14, amino.Typ3ByteLength); err != nil {
+		return nil, err
+	}
+	if err = amino.EncodeByteSlice(buf, x.Hash[:]); err != nil {
+		return nil, err
+	}
+	// Field 15: Data
+	if len(x.Data) != 0 {
+		if err = amino.EncodeFieldKey(buf, 15, amino.Typ3ByteLength); err != nil {
+			return nil, err
+		}
+		if err = amino.EncodeByteSlice(buf, []byte(x.Data)); err != nil {
+			return nil, err
+		}
+	}
+	// Field 16: Ratio
+	if err = amino.EncodeFieldKey(buf, 16, amino.Typ38Byte); err != nil {
+		return nil, err
+	}
+	if err = amino.EncodeFloat64(buf, float64(x.Ratio)); err != nil {
+		return nil, err
+	}
+	// Field 17: Weight
+	if err = amino.EncodeFieldKey(buf, 17, amino.Typ3_4Byte); err != nil {
+		return nil, err
+	}
+	if err = amino.EncodeFloat32(buf, float32(x.Weight)); err != nil {
+		return nil, err
+	}
+	// Field 18: Nothing
+	// Field 19: Empty
+	{
+		var bz []byte
+		if bz, err = x.Empty.MarshalAminoBinary(cdc); err != nil {
+			return nil, err
+		}
+		if len(bz) != 0 {
+			if err = amino.EncodeFieldKey(buf, 19, amino.Typ3ByteLength); err != nil {
+				return nil, err
+			}
+			if err = amino.EncodeByteSlice(buf, bz); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// AminoSize implements amino.AminoBinaryMarshaler.
+func (x Header) AminoSize(cdc *amino.Codec) (int, error) {
+	var n int
+	// Field 1: ChainID
+	if len(x.ChainID) != 0 {
+		n += 1 + amino.UvarintSize(uint64(len(x.ChainID))) + len(x.ChainID)
+	}
+	// Field 2: Height
+	if x.Height != 0 {
+		n += 1 + amino.UvarintSize(uint64(x.Height))
+	}
+	// Field 3: Time
+	var size int
+	var err error
+	if size, err = cdc.SizeBinaryField(&x, 2); err != nil {
+		return 0, err
+	}
+	n += size
+	// Field 4: Round
+	if x.Round != 0 {
+		n += 1 + 4
+	}
+	// Field 5: Nonce
+	if x.Nonce != 0 {
+		n += 1 + 8
+	}
+	// Field 6: Small
+	if x.Small != 0 {
+		n += 1 + amino.VarintSize(int64(x.Small))
+	}
+	// Field 7: Kind
+	if x.Kind != 0 {
+		n += 1 + amino.VarintSize(int64(x.Kind))
+	}
+	// Field 8: Count
+	if x.Count != 0 {
+		n += 1 + amino.UvarintSize(uint64(x.Count))
+	}
+	// Field 9: U8
+	if x.U8 != 0 {
+		n += 1 + amino.UvarintSize(uint64(x.U8))
+	}
+	// Field 10: U16
+	if x.U16 != 0 {
+		n += 1 + amino.UvarintSize(uint64(x.U16))
+	}
+	// Field 11: U32
+	if x.U32 != 0 {
+		n += 1 + amino.UvarintSize(uint64(x.U32))
+	}
+	// Field 12: U
+	if x.U != 0 {
+		n += 1 + amino.UvarintSize(uint64(x.U))
+	}
+	// Field 13: Flag
+	if x.Flag {
+		n += 1 + 1
+	}
+	// Field 14: Hash
+	n += 1 + 9
+	// Field 15: Data
+	if len(x.Data) != 0 {
+		n += 1 + amino.ByteSliceSize([]byte(x.Data))
+	}
+	// Field 16: Ratio
+	n += 2 + 8
+	// Field 17: Weight
+	n += 2 + 4
+	// Field 18: Nothing
+	// Field 19: Empty
+	if size, err := x.Empty.AminoSize(cdc); err != nil {
+		return 0, err
+	} else if size != 0 {
+		n += 2 + amino.UvarintSize(uint64(size)) + size
+	}
+	return n, nil
+}
+
+// UnmarshalAminoBinary implements amino.AminoBinaryUnmarshaler.
+func (x *Header) UnmarshalAminoBinary(cdc *amino.Codec, bz []byte) (err error) {
+	var lastFieldNum uint32
+	var _n int
+	var found bool
+	// Field 1: ChainID
+	if found, _n, err = amino.DecodeFieldKey(bz, 1, amino.Typ3ByteLength, &lastFieldNum); err != nil {
+		return
+	}
+	bz = bz[_n:]
+	if found {
+		var v []byte
+		if v, _n, err = amino.DecodeByteSliceNoCopy(bz); err != nil {
+			return
+		}
+		x.ChainID = string(v)
+		bz = bz[_n:]
+	} else {
+		x.ChainID = ""
+	}
+	// Field 2: Height
+	if found, _n, err = amino.DecodeFieldKey(bz, 2, amino.Typ3Varint, &lastFieldNum); err != nil {
+		return
+	}
+	bz = bz[_n:]
+	if found {
+		var v uint64
+		if v, _n, err = amino.DecodeUvarint(bz); err != nil {
+			return
+		}
+		x.Height = int64(v)
+		bz = bz[_n:]
+	} else {
+		x.Height = 0
+	}
+	// Field 3: Time
+	if _n, err = cdc.DecodeBinaryField(bz, x, 2, &lastFieldNum); err != nil {
+		return
+	}
+	bz = bz[_n:]
+	// Field 4: Round
+	if found, _n, err = amino.DecodeFieldKey(bz, 4, amino.Typ3_4Byte, &lastFieldNum); err != nil {
+		return
+	}
+	bz = bz[_n:]
+	if found {
+		var v int32
+		if v, _n, err = amino.DecodeInt32(bz); err != nil {
+			return
+		}
+		x.Round = int32(v)
+		bz = bz[_n:]
+	} else {
+		x.Round = 0
+	}
+	// Field 5: Nonce
+	if found, _n, err = amino.DecodeFieldKey(bz, 5, amino.Typ38Byte, &lastFieldNum); err != nil {
+		return
+	}
+	bz = bz[_n:]
+	if found {
+		var v uint64
+		if v, _n, err = amino.DecodeUint64(bz); err != nil {
+			return
+		}
+		x.Nonce = uint64(v)
+		bz = bz[_n:]
+	} else {
+		x.Nonce = 0
+	}
+	// Field 6: Small
+	if found, _n, err = amino.DecodeFieldKey(bz, 6, amino.Typ3Varint, &lastFieldNum); err != nil {
+		return
+	}
+	bz = bz[_n:]
+	if found {
+		var v int8
+		if v, _n, err = amino.DecodeInt8(bz); err != nil {
+			return
+		}
+		x.Small = int8(v)
+		bz = bz[_n:]
+	} else {
+		x.Small = 0
+	}
+	// Field 7: Kind
+	if found, _n, err = amino.DecodeFieldKey(bz, 7, amino.Typ3Varint, &lastFieldNum); err != nil {
+		return
+	}
+	bz = bz[_n:]
+	if found {
+		var v int16
+		if v, _n, err = amino.DecodeInt16(bz); err != nil {
+			return
+		}
+		x.Kind = Kind(v)
+		bz = bz[_n:]
+	} else {
+		x.Kind = 0
+	}
+	// Field 8: Count
+	if found, _n, err = amino.DecodeFieldKey(bz, 8, amino.Typ3Varint, &lastFieldNum); err != nil {
+		return
+	}
+	bz = bz[_n:]
+	if found {
+		var v uint64
+		if v, _n, err = amino.DecodeUvarint(bz); err != nil {
+			return
+		}
+		if int64(v) != int64(int(v)) {
+			err = amino.ErrOverflowInt
+			return
+		}
+		x.Count = int(v)
+		bz = bz[_n:]
+	} else {
+		x.Count = 0
+	}
+	// Field 9: U8
+	if found, _n, err = amino.DecodeFieldKey(bz, 9, amino.Typ3Varint, &lastFieldNum); err != nil {
+		return
+	}
+	bz = bz[_n:]
+	if found {
+		var v uint8
+		if v, _n, err = amino.DecodeUint8(bz); err != nil {
+			return
+		}
+		x.U8 = uint8(v)
+		bz = bz[_n:]
+	} else {
+		x.U8 = 0
+	}
+	// Field 10: U16
+	if found, _n, err = amino.DecodeFieldKey(bz, 10, amino.Typ3Varint, &lastFieldNum); err != nil {
+		return
+	}
+	bz = bz[_n:]
+	if found {
+		var v uint16
+		if v, _n, err = amino.DecodeUint16(bz); err != nil {
+			return
+		}
+		x.U16 = uint16(v)
+		bz = bz[_n:]
+	} else {
+		x.U16 = 0
+	}
+	// Field 11: U32
+	if found, _n, err = amino.DecodeFieldKey(bz, 11, amino.Typ3Varint, &lastFieldNum); err != nil {
+		return
+	}
+	bz = bz[_n:]
+	if found {
+		var v uint64
+		if v, _n, err = amino.DecodeUvarint(bz); err != nil {
+			return
+		}
+		if v != uint64(uint32(v)) {
+			err = amino.ErrOverflowUint
+			return
+		}
+		x.U32 = uint32(v)
+		bz = bz[_n:]
+	} else {
+		x.U32 = 0
+	}
+	// Field 12: U
+	if found, _n, err = amino.DecodeFieldKey(bz, 12, amino.Typ3Varint, &lastFieldNum); err != nil {
+		return
+	}
+	bz = bz[_n:]
+	if found {
+		var v uint64
+		if v, _n, err = amino.DecodeUvarint(bz); err != nil {
+			return
+		}
+		if v != uint64(uint(v)) {
+			err = amino.ErrOverflowUint
+			return
+		}
+		x.U = uint(v)
+		bz = bz[_n:]
+	} else {
+		x.U = 0
+	}
+	// Field 13: Flag
+	if found, _n, err = amino.DecodeFieldKey(bz, 13, amino.Typ3Varint, &lastFieldNum); err != nil {
+		return
+	}
+	bz = bz[_n:]
+	if found {
+		var v bool
+		if v, _n, err = amino.DecodeBool(bz); err != nil {
+			return
+		}
+		x.Flag = bool(v)
+		bz = bz[_n:]
+	} else {
+		x.Flag = false
+	}
+	// Field 14: Hash
+	if found, _n, err = amino.DecodeFieldKey(bz, 14, amino.Typ3ByteLength, &lastFieldNum); err != nil {
+		return
+	}
+	bz = bz[_n:]
+	if found {
+		var v []byte
+		if v, _n, err = amino.DecodeByteSliceNoCopy(bz); err != nil {
+			return
+		}
+		if len(v) != 8 {
+			err = fmt.Errorf("Mismatched byte array length: Expected 8, got %v", len(v))
+			return
+		}
+		copy(x.Hash[:], v)
+		bz = bz[_n:]
+	} else {
+		x.Hash = [8]byte{}
+	}
+	// Field 15: Data
+	if found, _n, err = amino.DecodeFieldKey(bz, 15, amino.Typ3ByteLength, &lastFieldNum); err != nil {
+		return
+	}
+	bz = bz[_n:]
+	if found {
+		var v []byte
+		if v, _n, err = amino.DecodeByteSlice(bz); err != nil {
+			return
+		}
+		if len(v) == 0 {
+			v = nil
+		}
+		x.Data = []byte(v)
+		bz = bz[_n:]
+	} else {
+		x.Data = nil
+	}
+	// Field 16: Ratio
+	if found, _n, err = amino.DecodeFieldKey(bz, 16, amino.Typ38Byte, &lastFieldNum); err != nil {
+		return
+	}
+	bz = bz[_n:]
+	if found {
+		var v float64
+		if v, _n, err = amino.DecodeFloat64(bz); err != nil {
+			return
+		}
+		x.Ratio = float64(v)
+		bz = bz[_n:]
+	} else {
+		x.Ratio = 0
+	}
+	// Field 17: Weight
+	if found, _n, err = amino.DecodeFieldKey(bz, 17, amino.Typ3_4Byte, &lastFieldNum); err != nil {
+		return
+	}
+	bz = bz[_n:]
+	if found {
+		var v float32
+		if v, _n, err = amino.DecodeFloat32(bz); err != nil {
+			return
+		}
+		x.Weight = float32(v)
+		bz = bz[_n:]
+	} else {
+		x.Weight = 0
+	}
+	// Field 18: Nothing
+	if found, _n, err = amino.DecodeFieldKey(bz, 18, amino.Typ3ByteLength, &lastFieldNum); err != nil {
+		return
+	}
+	bz = bz[_n:]
+	if found {
+		var v []byte
+		if v, _n, err = amino.DecodeByteSliceNoCopy(bz); err != nil {
+			return
+		}
+		if len(v) != 0 {
+			err = fmt.Errorf("Mismatched byte array length: Expected 0, got %v", len(v))
+			return
+		}
+		copy(x.Nothing[:], v)
+		bz = bz[_n:]
+	} else {
+		x.Nothing = [0]byte{}
+	}
+	// Field 19: Empty
+	if found, _n, err = amino.DecodeFieldKey(bz, 19, amino.Typ3ByteLength, &lastFieldNum); err != nil {
+		return
+	}
+	bz = bz[_n:]
+	if found {
+		var v []byte
+		if v, _n, err = amino.DecodeByteSliceNoCopy(bz); err != nil {
+			return
+		}
+		if err = x.Empty.UnmarshalAminoBinary(cdc, v); err != nil {
+			return
+		}
+		bz = bz[_n:]
+	} else {
+		x.Empty = Empty{}
+	}
+	_, err = amino.SkipUnknownFields(bz, &lastFieldNum)
+	return
+}
+
+// MarshalAminoBinary implements amino.AminoBinaryMarshaler.
+func (x MsgSend) MarshalAminoBinary(cdc *amino.Codec) ([]byte, error) {
+	var buf = new(bytes.Buffer)
+	var err error
+	// Field 1: From
+	if len(x.From) != 0 {
+		if err = amino.EncodeFieldKey(buf, 1, amino.Typ3ByteLength); err != nil {
+			return nil, err
+		}
+		if err = amino.EncodeByteSlice(buf, []byte(x.From)); err != nil {
+			return nil, err
+		}
+	}
+	// Field 2: To
+	if len(x.To) != 0 {
+		if err = amino.EncodeFieldKey(buf, 2, amino.Typ3ByteLength); err != nil {
+			return nil, err
+		}
+		if err = amino.EncodeByteSlice(buf, []byte(x.To)); err != nil {
+			return nil, err
+		}
+	}
+	// Field 3: Amount
+	{
+		var bz []byte
+		if bz, err = x.Amount.MarshalAminoBinary(cdc); err != nil {
+			return nil, err
+		}
+		if len(bz) != 0 {
+			if err = amino.EncodeFieldKey(buf, 3, amino.Typ3ByteLength); err != nil {
+				return nil, err
+			}
+			if err = amino.EncodeByteSlice(buf, bz); err != nil {
+				return nil, err
+			}
+		}
+	}
+	// Field 4: Fee
+	if err = cdc.EncodeBinaryField(buf, &x, 3); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// AminoSize implements amino.AminoBinaryMarshaler.
+func (x MsgSend) AminoSize(cdc *amino.Codec) (int, error) {
+	var n int
+	// Field 1: From
+	if len(x.From) != 0 {
+		n += 1 + amino.ByteSliceSize([]byte(x.From))
+	}
+	// Field 2: To
+	if len(x.To) != 0 {
+		n += 1 + amino.ByteSliceSize([]byte(x.To))
+	}
+	// Field 3: Amount
+	if size, err := x.Amount.AminoSize(cdc); err != nil {
+		return 0, err
+	} else if size != 0 {
+		n += 1 + amino.UvarintSize(uint64(size)) + size
+	}
+	// Field 4: Fee
+	var size int
+	var err error
+	if size, err = cdc.SizeBinaryField(&x, 3); err != nil {
+		return 0, err
+	}
+	n += size
+	return n, nil
+}
+
+// UnmarshalAminoBinary implements amino.AminoBinaryUnmarshaler.
+func (x *MsgSend) UnmarshalAminoBinary(cdc *amino.Codec, bz []byte) (err error) {
+	var lastFieldNum uint32
+	var _n int
+	var found bool
+	// Field 1: From
+	if found, _n, err = amino.DecodeFieldKey(bz, 1, amino.Typ3ByteLength, &lastFieldNum); err != nil {
+		return
+	}
+	bz = bz[_n:]
+	if found {
+		var v []byte
+		if v, _n, err = amino.DecodeByteSlice(bz); err != nil {
+			return
+		}
+		if len(v) == 0 {
+			v = nil
+		}
+		x.From = []byte(v)
+		bz = bz[_n:]
+	} else {
+		x.From = nil
+	}
+	// Field 2: To
+	if found, _n, err = amino.DecodeFieldKey(bz, 2, amino.Typ3ByteLength, &lastFieldNum); err != nil {
+		return
+	}
+	bz = bz[_n:]
+	if found {
+		var v []byte
+		if v, _n, err = amino.DecodeByteSlice(bz); err != nil {
+			return
+		}
+		if len(v) == 0 {
+			v = nil
+		}
+		x.To = []byte(v)
+		bz = bz[_n:]
+	} else {
+		x.To = nil
+	}
+	// Field 3: Amount
+	if found, _n, err = amino.DecodeFieldKey(bz, 3, amino.Typ3ByteLength, &lastFieldNum); err != nil {
+		return
+	}
+	bz = bz[_n:]
+	if found {
+		var v []byte
+		if v, _n, err = amino.DecodeByteSliceNoCopy(bz); err != nil {
+			return
+		}
+		if err = x.Amount.UnmarshalAminoBinary(cdc, v); err != nil {
+			return
+		}
+		bz = bz[_n:]
+	} else {
+		x.Amount = Coin{}
+	}
+	// Field 4: Fee
+	if _n, err = cdc.DecodeBinaryField(bz, x, 3, &lastFieldNum); err != nil {
+		return
+	}
+	bz = bz[_n:]
+	_, err = amino.SkipUnknownFields(bz, &lastFieldNum)
+	return
+}
+
+// MarshalAminoBinary implements amino.AminoBinaryMarshaler.
+func (x Tx) MarshalAminoBinary(cdc *amino.Codec) ([]byte, error) {
+	var buf = new(bytes.Buffer)
+	var err error
+	// Field 1: Msgs
+	if err = cdc.EncodeBinaryField(buf, &x, 0); err != nil {
+		return nil, err
+	}
+	// Field 3: Memo
+	if len(x.Memo) != 0 {
+		if err = amino.EncodeFieldKey(buf, 3, amino.Typ3ByteLength); err != nil {
+			return nil, err
+		}
+		if err = amino.EncodeString(buf, string(x.Memo)); err != nil {
+			return nil, err
+		}
+	}
+	// Field 4: Fee
+	if err = cdc.EncodeBinaryField(buf, &x, 2); err != nil {
+		return nil, err
+	}
+	// Field 5: Coins
+	if err = cdc.EncodeBinaryField(buf, &x, 3); err != nil {
+		return nil, err
+	}
+	// Field 6: Sigs
+	if err = cdc.EncodeBinaryField(buf, &x, 4); err != nil {
+		return nil, err
+	}
+	// Field 7: Labels
+	if err = cdc.EncodeBinaryField(buf, &x, 5); err != nil {
+		return nil, err
+	}
+	// Field 8: Header
+	{
+		var bz []byte
+		if bz, err = x.Header.MarshalAminoBinary(cdc); err != nil {
+			return nil, err
+		}
+		if len(bz) != 0 {
+			if err = amino.EncodeFieldKey(buf, 8, amino.Typ3ByteLength); err != nil {
+				return nil, err
+			}
+			if err = amino.EncodeByteSlice(buf, bz); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// AminoSize implements amino.AminoBinaryMarshaler.
+func (x Tx) AminoSize(cdc *amino.Codec) (int, error) {
+	var n int
+	// Field 1: Msgs
+	var size int
+	var err error
+	if size, err = cdc.SizeBinaryField(&x, 0); err != nil {
+		return 0, err
+	}
+	n += size
+	// Field 3: Memo
+	if len(x.Memo) != 0 {
+		n += 1 + amino.UvarintSize(uint64(len(x.Memo))) + len(x.Memo)
+	}
+	// Field 4: Fee
+	if size, err = cdc.SizeBinaryField(&x, 2); err != nil {
+		return 0, err
+	}
+	n += size
+	// Field 5: Coins
+	if size, err = cdc.SizeBinaryField(&x, 3); err != nil {
+		return 0, err
+	}
+	n += size
+	// Field 6: Sigs
+	if size, err = cdc.SizeBinaryField(&x, 4); err != nil {
+		return 0, err
+	}
+	n += size
+	// Field 7: Labels
+	if size, err = cdc.SizeBinaryField(&x, 5); err != nil {
+		return 0, err
+	}
+	n += size
+	// Field 8: Header
+	if size, err := x.Header.AminoSize(cdc); err != nil {
+		return 0, err
+	} else if size != 0 {
+		n += 1 + amino.UvarintSize(uint64(size)) + size
+	}
+	return n, nil
+}
+
+// UnmarshalAminoBinary implements amino.AminoBinaryUnmarshaler.
+func (x *Tx) UnmarshalAminoBinary(cdc *amino.Codec, bz []byte) (err error) {
+	var lastFieldNum uint32
+	var _n int
+	var found bool
+	// Field 1: Msgs
+	if _n, err = cdc.DecodeBinaryField(bz, x, 0, &lastFieldNum); err != nil {
+		return
+	}
+	bz = bz[_n:]
+	// Field 3: Memo
+	if found, _n, err = amino.DecodeFieldKey(bz, 3, amino.Typ3ByteLength, &lastFieldNum); err != nil {
+		return
+	}
+	bz = bz[_n:]
+	if found {
+		var v []byte
+		if v, _n, err = amino.DecodeByteSliceNoCopy(bz); err != nil {
+			return
+		}
+		x.Memo = string(v)
+		bz = bz[_n:]
+	} else {
+		x.Memo = ""
+	}
+	// Field 4: Fee
+	if _n, err = cdc.DecodeBinaryField(bz, x, 2, &lastFieldNum); err != nil {
+		return
+	}
+	bz = bz[_n:]
+	// Field 5: Coins
+	if _n, err = cdc.DecodeBinaryField(bz, x, 3, &lastFieldNum); err != nil {
+		return
+	}
+	bz = bz[_n:]
+	// Field 6: Sigs
+	if _n, err = cdc.DecodeBinaryField(bz, x, 4, &lastFieldNum); err != nil {
+		return
+	}
+	bz = bz[_n:]
+	// Field 7: Labels
+	if _n, err = cdc.DecodeBinaryField(bz, x, 5, &lastFieldNum); err != nil {
+		return
+	}
+	bz = bz[_n:]
+	// Field 8: Header
+	if found, _n, err = amino.DecodeFieldKey(bz, 8, amino.Typ3ByteLength, &lastFieldNum); err != nil {
+		return
+	}
+	bz = bz[_n:]
+	if found {
+		var v []byte
+		if v, _n, err = amino.DecodeByteSliceNoCopy(bz); err != nil {
+			return
+		}
+		if err = x.Header.UnmarshalAminoBinary(cdc, v); err != nil {
+			return
+		}
+		bz = bz[_n:]
+	} else {
+		x.Header = Header{}
+	}
+	_, err = amino.SkipUnknownFields(bz, &lastFieldNum)
+	return
+}
+
+// MarshalAminoBinary implements amino.AminoBinaryMarshaler.
+func (x Empty) MarshalAminoBinary(cdc *amino.Codec) ([]byte, error) {
+	return nil, nil
+}
+
+// AminoSize implements amino.AminoBinaryMarshaler.
+func (x Empty) AminoSize(cdc *amino.Codec) (int, error) {
+	var n int
+	return n, nil
+}
+
+// UnmarshalAminoBinary implements amino.AminoBinaryUnmarshaler.
+func (x *Empty) UnmarshalAminoBinary(cdc *amino.Codec, bz []byte) (err error) {
+	var lastFieldNum uint32
+	_, err = amino.SkipUnknownFields(bz, &lastFieldNum)
+	return
+}
@@ -0,0 +1,56 @@
+// Package gen has types with code generated by aminogen, for tests.
+package gen
+
+import "time"
+
+//go:generate go run github.com/tendermint/go-amino/cmd/aminogen -type=Coin,Header,MsgSend,Tx,Empty
+
+type Kind int16
+
+type Coin struct {
+	Denom  string `json:"denom"`
+	Amount int64  `json:"amount"`
+}
+
+type Header struct {
+	ChainID string
+	Height  int64
+	Time    time.Time
+	Round   int32  `binary:"fixed32"`
+	Nonce   uint64 `binary:"fixed64"`
+	Small   int8
+	Kind    Kind
+	Count   int
+	U8      uint8
+	U16     uint16
+	U32     uint32
+	U       uint
+	Flag    bool
+	Hash    [8]byte
+	Data    []byte
+	Ratio   float64 `amino:"unsafe"`
+	Weight  float32 `amino:"unsafe"`
+	Nothing [0]byte
+	Empty   Empty
+}
+
+type Empty struct{}
+
+type Msg interface{}
+
+type MsgSend struct {
+	From   []byte `json:"from"`
+	To     []byte `json:"to"`
+	Amount Coin   `json:"amount"`
+	Fee    Coin   `json:"fee" amino:"write_empty"`
+}
+
+type Tx struct {
+	Msgs   []Msg             `json:"msgs"`
+	Memo   string            `json:"memo" amino:"field=3"`
+	Fee    *Coin             `json:"fee"`
+	Coins  []Coin            `json:"coins"`
+	Sigs   [][]byte          `json:"sigs"`
+	Labels map[string]string `json:"labels"`
+	Header Header            `json:"header"`
+}
@@ -1,3 +1,4 @@
+//go:build extensive_tests
 // +build extensive_tests
 
 // only built if manually enforced (via the build tag above)
@@ -383,3 +384,191 @@ func TestTypeDefCompatibility(t *testing.T) {
 		assert.Equal(t, pb, ab, "Amino and protobuf encoding do not match %v", i)
 	}
 }
+
+// equivalent go struct to p3.PrimitivesStruct, using explicit field numbers:
+type goAminoExplicitFieldNums struct {
+	_      struct{} `amino:"reserved=1|2"`
+	Int32  int32    `amino:"field=3"`
+	Int64  int64
+	Varint int64
+	String string `amino:"field=14"`
+	Bytes  []byte
+	Time   time.Time
+}
+
+func TestProto3CompatExplicitFieldNums(t *testing.T) {
+	pNow := ptypes.TimestampNow()
+	now, err := ptypes.Timestamp(pNow)
+	require.NoError(t, err)
+
+	as := goAminoExplicitFieldNums{Int32: 1, Int64: -1, Varint: 2, String: "protobuf3", Bytes: []byte("got some bytes"), Time: now}
+	ps := p3.PrimitivesStruct{Int32: 1, Int64: -1, Varint: 2, String_: "protobuf3", Bytes: []byte("got some bytes"), Time: pNow}
+
+	ab, err := cdc.MarshalBinaryBare(as)
+	require.NoError(t, err)
+	pb, err := proto.Marshal(&ps)
+	require.NoError(t, err)
+	assert.Equal(t, pb, ab, "Amino and protobuf encoding do not match")
+
+	var amToP3 p3.PrimitivesStruct
+	err = proto.Unmarshal(ab, &amToP3)
+	require.NoError(t, err)
+	assert.True(t, proto.Equal(&ps, &amToP3))
+
+	var p3ToAm goAminoExplicitFieldNums
+	err = cdc.UnmarshalBinaryBare(pb, &p3ToAm)
+	require.NoError(t, err)
+	assert.Equal(t, as, p3ToAm)
+}
+
+// Hand-written equivalent of the protoc generated code for:
+//
+//	message ProtoMaps {
+//	    map<string, int64> StrInt = 1;
+//	    map<uint32, EmbeddedStruct> UintStruct = 2;
+//	}
+type protoMaps struct {
+	StrInt     map[string]int64              `protobuf:"bytes,1,rep,name=StrInt,proto3" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"varint,2,opt,name=value,proto3"`
+	UintStruct map[uint32]*p3.EmbeddedStruct `protobuf:"bytes,2,rep,name=UintStruct,proto3" protobuf_key:"varint,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+}
+
+func (m *protoMaps) Reset()         { *m = protoMaps{} }
+func (m *protoMaps) String() string { return proto.CompactTextString(m) }
+func (*protoMaps) ProtoMessage()    {}
+
+type goAminoEmbeddedStruct struct {
+	SomethingFixedLen int64 `binary:"fixed64"`
+}
+
+type goAminoMaps struct {
+	StrInt     map[string]int64
+	UintStruct map[uint32]goAminoEmbeddedStruct
+}
+
+func TestProto3CompatMaps(t *testing.T) {
+	// NOTE: Amino sorts entries by the encoded key bytes, while protobuf
+	// sorts by key value.  These keys sort the same either way.
+	am := goAminoMaps{
+		StrInt:     map[string]int64{"a": -1, "b": 1, "c": 100},
+		UintStruct: map[uint32]goAminoEmbeddedStruct{1: {SomethingFixedLen: 1}, 2: {SomethingFixedLen: 2}},
+	}
+	pm := protoMaps{
+		StrInt:     map[string]int64{"a": -1, "b": 1, "c": 100},
+		UintStruct: map[uint32]*p3.EmbeddedStruct{1: {SomethingFixedLen: 1}, 2: {SomethingFixedLen: 2}},
+	}
+
+	ab, err := cdc.MarshalBinaryBare(am)
+	require.NoError(t, err)
+	pbuf := proto.NewBuffer(nil)
+	pbuf.SetDeterministic(true)
+	err = pbuf.Marshal(&pm)
+	require.NoError(t, err)
+	assert.Equal(t, pbuf.Bytes(), ab, "Amino and protobuf encoding do not match")
+
+	var amToP3 protoMaps
+	err = proto.Unmarshal(ab, &amToP3)
+	require.NoError(t, err)
+	assert.True(t, proto.Equal(&pm, &amToP3))
+
+	var p3ToAm goAminoMaps
+	err = cdc.UnmarshalBinaryBare(pbuf.Bytes(), &p3ToAm)
+	require.NoError(t, err)
+	assert.Equal(t, am, p3ToAm)
+
+	// Protobuf always writes keys and values, even if they are zero.
+	pbuf.Reset()
+	err = pbuf.Marshal(&protoMaps{StrInt: map[string]int64{"": 0}})
+	require.NoError(t, err)
+	p3ToAm = goAminoMaps{}
+	err = cdc.UnmarshalBinaryBare(pbuf.Bytes(), &p3ToAm)
+	require.NoError(t, err)
+	assert.Equal(t, goAminoMaps{StrInt: map[string]int64{"": 0}}, p3ToAm)
+}
+
+// Hand-written equivalents of the protoc output for:
+//
+//	message Int64List {
+//	    repeated int64 Val = 1;
+//	}
+//	message StringList {
+//	    repeated string Val = 1;
+//	}
+//	message EmbeddedStructList {
+//	    repeated EmbeddedStruct Val = 1;
+//	}
+//	message ProtoNestedLists {
+//	    repeated Int64List Ints = 1;
+//	    repeated StringList Strs = 2;
+//	    repeated EmbeddedStructList Structs = 3;
+//	}
+type protoInt64List struct {
+	Val []int64 `protobuf:"varint,1,rep,packed,name=Val,proto3"`
+}
+
+func (m *protoInt64List) Reset()         { *m = protoInt64List{} }
+func (m *protoInt64List) String() string { return proto.CompactTextString(m) }
+func (*protoInt64List) ProtoMessage()    {}
+
+type protoStringList struct {
+	Val []string `protobuf:"bytes,1,rep,name=Val,proto3"`
+}
+
+func (m *protoStringList) Reset()         { *m = protoStringList{} }
+func (m *protoStringList) String() string { return proto.CompactTextString(m) }
+func (*protoStringList) ProtoMessage()    {}
+
+type protoEmbeddedStructList struct {
+	Val []*p3.EmbeddedStruct `protobuf:"bytes,1,rep,name=Val,proto3"`
+}
+
+func (m *protoEmbeddedStructList) Reset()         { *m = protoEmbeddedStructList{} }
+func (m *protoEmbeddedStructList) String() string { return proto.CompactTextString(m) }
+func (*protoEmbeddedStructList) ProtoMessage()    {}
+
+type protoNestedLists struct {
+	Ints    []*protoInt64List          `protobuf:"bytes,1,rep,name=Ints,proto3"`
+	Strs    []*protoStringList         `protobuf:"bytes,2,rep,name=Strs,proto3"`
+	Structs []*protoEmbeddedStructList `protobuf:"bytes,3,rep,name=Structs,proto3"`
+}
+
+func (m *protoNestedLists) Reset()         { *m = protoNestedLists{} }
+func (m *protoNestedLists) String() string { return proto.CompactTextString(m) }
+func (*protoNestedLists) ProtoMessage()    {}
+
+type goAminoNestedLists struct {
+	Ints    [][]int64
+	Strs    [][]string
+	Structs [][]goAminoEmbeddedStruct
+}
+
+func TestProto3CompatNestedLists(t *testing.T) {
+	am := goAminoNestedLists{
+		Ints:    [][]int64{{1, -2}, {3}},
+		Strs:    [][]string{{"a", "b"}, {"c"}},
+		Structs: [][]goAminoEmbeddedStruct{{{1}, {2}}, {{3}}},
+	}
+	pm := protoNestedLists{
+		Ints: []*protoInt64List{{Val: []int64{1, -2}}, {Val: []int64{3}}},
+		Strs: []*protoStringList{{Val: []string{"a", "b"}}, {Val: []string{"c"}}},
+		Structs: []*protoEmbeddedStructList{
+			{Val: []*p3.EmbeddedStruct{{SomethingFixedLen: 1}, {SomethingFixedLen: 2}}},
+			{Val: []*p3.EmbeddedStruct{{SomethingFixedLen: 3}}},
+		},
+	}
+
+	ab, err := cdc.MarshalBinaryBare(am)
+	require.NoError(t, err)
+	pb, err := proto.Marshal(&pm)
+	require.NoError(t, err)
+	assert.Equal(t, pb, ab, "Amino and protobuf encoding do not match")
+
+	var amToP3 protoNestedLists
+	err = proto.Unmarshal(ab, &amToP3)
+	require.NoError(t, err)
+	assert.True(t, proto.Equal(&pm, &amToP3))
+
+	var p3ToAm goAminoNestedLists
+	err = cdc.UnmarshalBinaryBare(pb, &p3ToAm)
+	require.NoError(t, err)
+	assert.Equal(t, am, p3ToAm)
+}